@@ -0,0 +1,119 @@
+package server
+
+import (
+	"config-manager/configs"
+	"config-manager/internal/migration"
+	"config-manager/internal/repository"
+	"config-manager/internal/usecase"
+	"config-manager/pkg/shared/utils"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var BackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Write a portable snapshot of the Controller database",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("out")
+		if out == "" {
+			out = usecase.BackupFileName(time.Now())
+		}
+
+		backupUsecase, err := newBackupUsecase()
+		if err != nil {
+			return err
+		}
+
+		if out == "-" {
+			return backupUsecase.Export(cmd.OutOrStdout())
+		}
+
+		// Write next to the destination and rename, so an interrupted backup
+		// never leaves a truncated archive under the final name.
+		tmp, err := os.CreateTemp(filepath.Dir(out), ".backup-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+
+		if err := backupUsecase.Export(tmp); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), out); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "backup written to %s\n", out)
+		return nil
+	},
+}
+
+var RestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore a snapshot created by the backup command",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		in, _ := cmd.Flags().GetString("in")
+		force, _ := cmd.Flags().GetBool("force")
+
+		var r io.Reader = cmd.InOrStdin()
+		if in != "-" {
+			f, err := os.Open(in)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		backupUsecase, err := newBackupUsecase()
+		if err != nil {
+			return err
+		}
+
+		archive, err := backupUsecase.Import(r, force)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "restored %d agent(s) and %d config version(s) from backup taken at %s\n",
+			len(archive.Agents), len(archive.Configs), archive.CreatedAt.Format(time.RFC3339))
+		return nil
+	},
+}
+
+func init() {
+	BackupCmd.Flags().String("out", "", `archive path, "-" for stdout (default config-manager-<timestamp>.json.gz)`)
+
+	RestoreCmd.Flags().String("in", "", `archive path, "-" for stdin`)
+	RestoreCmd.Flags().Bool("force", false, "replace existing data in the target database")
+	_ = RestoreCmd.MarkFlagRequired("in")
+}
+
+// newBackupUsecase opens the configured database and refuses to continue unless
+// its schema matches this binary, so archives always reflect a known layout.
+func newBackupUsecase() (usecase.BackupUsecase, error) {
+	cfg := configs.LoadConfig()
+	db, err := utils.InitDB(cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migration.NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Check(); err != nil {
+		return nil, err
+	}
+
+	return usecase.NewBackupUsecase(repository.NewBackupRepository(db)), nil
+}
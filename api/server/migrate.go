@@ -0,0 +1,107 @@
+package server
+
+import (
+	"config-manager/configs"
+	"config-manager/internal/migration"
+	"config-manager/pkg/shared/utils"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var MigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the Controller database schema",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := newMigrator()
+		if err != nil {
+			return err
+		}
+
+		count, err := m.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "applied %d migration(s), schema at version %d\n", count, m.LatestVersion())
+		return nil
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Roll back the most recent migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps, _ := cmd.Flags().GetInt("steps")
+
+		m, err := newMigrator()
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < steps; i++ {
+			mig, err := m.Down()
+			if err != nil {
+				return err
+			}
+			if mig == nil {
+				fmt.Fprintln(cmd.OutOrStdout(), "no migrations to roll back")
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "rolled back %04d_%s\n", mig.Version, mig.Name)
+		}
+		return nil
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := newMigrator()
+		if err != nil {
+			return err
+		}
+
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+
+		if err := m.Check(); err != nil {
+			fmt.Fprintln(cmd.OutOrStdout(), err.Error())
+		}
+		return nil
+	},
+}
+
+func init() {
+	migrateDownCmd.Flags().Int("steps", 1, "number of migrations to roll back")
+
+	MigrateCmd.AddCommand(migrateUpCmd)
+	MigrateCmd.AddCommand(migrateDownCmd)
+	MigrateCmd.AddCommand(migrateStatusCmd)
+}
+
+func newMigrator() (*migration.Migrator, error) {
+	cfg := configs.LoadConfig()
+	db, err := utils.InitDB(cfg.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	return migration.NewMigrator(db)
+}
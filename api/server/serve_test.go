@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	e.GET("/slow", func(c echo.Context) error {
+		close(entered)
+		<-release
+		return c.String(http.StatusOK, "done")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, e, "127.0.0.1:0", 5*time.Second)
+	}()
+
+	var addr string
+	assert.Eventually(t, func() bool {
+		if a := e.ListenerAddr(); a != nil {
+			addr = a.String()
+			return true
+		}
+		return false
+	}, time.Second, time.Millisecond)
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{body: string(body), err: err}
+	}()
+	<-entered
+
+	// Shutdown begins while the request is still being handled
+	shuttingDown := make(chan struct{})
+	e.Server.RegisterOnShutdown(func() { close(shuttingDown) })
+	cancel()
+	<-shuttingDown
+	close(release)
+
+	res := <-responses
+	assert.NoError(t, res.err)
+	assert.Equal(t, "done", res.body)
+	assert.NoError(t, <-served)
+}
+
+func TestServe_StartError(t *testing.T) {
+	e := echo.New()
+	e.HideBanner = true
+
+	err := serve(context.Background(), e, "invalid-address", time.Second)
+	assert.Error(t, err)
+}
@@ -4,6 +4,15 @@ import (
 	"config-manager/configs"
 	"config-manager/di"
 	_ "config-manager/docs" // Swagger docs
+	"config-manager/pkg/shared/metrics"
+	"config-manager/pkg/shared/tracing"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -25,14 +34,25 @@ func startController() {
 
 	// Middleware
 	e.Use(middleware.RequestID())
+	defer setupTracing(e, cfg, "controller")()
+	reg := metrics.NewRegistry()
+	e.Use(metrics.Middleware(reg))
+	metrics.Register(e, reg)
 
 	// Swagger
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Init DI
-	di.InitializeControllerV1(e, cfg)
+	cleanup := di.InitializeControllerV1(ctx, e, cfg, reg)
 
-	e.Logger.Fatal(e.Start(":" + cfg.ControllerPort))
+	if err := serve(ctx, e, ":"+cfg.ControllerPort, shutdownTimeout(cfg)); err != nil {
+		e.Logger.Fatal(err)
+	}
+	// Stop background jobs and close the database once requests are drained
+	cleanup()
 }
 
 var AgentCmd = &cobra.Command{
@@ -45,7 +65,25 @@ var AgentCmd = &cobra.Command{
 
 func startAgent() {
 	cfg := configs.LoadConfig()
-	di.InitializeAgent(cfg)
+	e := echo.New()
+
+	// Middleware
+	e.Use(middleware.RequestID())
+	defer setupTracing(e, cfg, "agent")()
+	reg := metrics.NewRegistry()
+	e.Use(metrics.Middleware(reg))
+	metrics.Register(e, reg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Init DI, the poller runs in the background
+	pollerStopped := di.InitializeAgent(ctx, e, cfg, reg)
+
+	if err := serve(ctx, e, ":"+cfg.AgentPort, shutdownTimeout(cfg)); err != nil {
+		e.Logger.Fatal(err)
+	}
+	<-pollerStopped
 }
 
 var WorkerCmd = &cobra.Command{
@@ -62,8 +100,74 @@ func startWorker() {
 
 	// Middleware
 	e.Use(middleware.RequestID())
+	defer setupTracing(e, cfg, "worker")()
+	reg := metrics.NewRegistry()
+	e.Use(metrics.Middleware(reg))
+	metrics.Register(e, reg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	deregistered := di.InitializeWorker(ctx, e, cfg, reg)
 
-	di.InitializeWorker(e, cfg)
+	if err := serve(ctx, e, ":"+cfg.WorkerPort, shutdownTimeout(cfg)); err != nil {
+		e.Logger.Fatal(err)
+	}
+	<-deregistered
+}
+
+// serve runs e on address until ctx is cancelled, then stops accepting
+// connections and waits up to timeout for in-flight requests to finish.
+func serve(ctx context.Context, e *echo.Echo, address string, timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- e.Start(address)
+	}()
+
+	select {
+	case err := <-errCh:
+		// The server failed before a shutdown was requested, e.g. the port is taken
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// setupTracing installs the tracer provider of role and traces every request.
+// The returned function flushes pending spans.
+func setupTracing(e *echo.Echo, cfg *configs.Config, role string) func() {
+	service := "config-manager-" + role
+	shutdown, err := tracing.Setup(context.Background(), service, tracing.Config{
+		Exporter:     cfg.TracingExporter,
+		File:         cfg.TracingFile,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		SampleRatio:  cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	e.Use(tracing.Middleware(service))
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout(cfg))
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			e.Logger.Error(err)
+		}
+	}
+}
 
-	e.Logger.Fatal(e.Start(":" + cfg.WorkerPort))
+func shutdownTimeout(cfg *configs.Config) time.Duration {
+	if cfg.ShutdownTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(cfg.ShutdownTimeout) * time.Second
 }
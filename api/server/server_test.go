@@ -1,10 +1,13 @@
 package server
 
 import (
+	"bytes"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -28,12 +31,14 @@ func TestStartServerFunctions(t *testing.T) {
 	// Set ports that are unlikely to conflict
 	os.Setenv("CONTROLLER_PORT", "0") // 0 usually means random available port, but echo might still bind
 	os.Setenv("WORKER_PORT", "0")
+	os.Setenv("AGENT_PORT", "0")
 	// For SQLite, use memory to avoid file locks
 	os.Setenv("DB_PATH", "file::memory:?cache=shared")
 
 	defer func() {
 		os.Unsetenv("CONTROLLER_PORT")
 		os.Unsetenv("WORKER_PORT")
+		os.Unsetenv("AGENT_PORT")
 		os.Unsetenv("DB_PATH")
 	}()
 
@@ -59,3 +64,60 @@ func TestStartServerFunctions(t *testing.T) {
 	// Give them a moment to run their initialization code before the test exits
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestMigrateCommands(t *testing.T) {
+	os.Setenv("DB_PATH", filepath.Join(t.TempDir(), "migrate.db"))
+	defer os.Unsetenv("DB_PATH")
+
+	run := func(args ...string) string {
+		var out bytes.Buffer
+		MigrateCmd.SetOut(&out)
+		MigrateCmd.SetArgs(args)
+		assert.NoError(t, MigrateCmd.Execute())
+		return out.String()
+	}
+
+	assert.Contains(t, run("status"), "pending")
+	assert.Contains(t, run("up"), "applied")
+	assert.Contains(t, run("status"), "applied")
+	assert.Contains(t, run("down"), "rolled back")
+	assert.Contains(t, run("down", "--steps", "100"), "no migrations to roll back")
+}
+
+func TestBackupAndRestoreCommands(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "backup.json.gz")
+
+	run := func(cmd *cobra.Command, args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	os.Setenv("DB_PATH", filepath.Join(dir, "source.db"))
+	defer os.Unsetenv("DB_PATH")
+
+	// Backups require a migrated schema
+	_, err := run(BackupCmd, "--out", archive)
+	assert.Error(t, err)
+
+	_, err = run(MigrateCmd, "up")
+	assert.NoError(t, err)
+
+	out, err := run(BackupCmd, "--out", archive)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "backup written")
+	assert.FileExists(t, archive)
+
+	// Restore into a different, freshly migrated database
+	os.Setenv("DB_PATH", filepath.Join(dir, "target.db"))
+	_, err = run(MigrateCmd, "up")
+	assert.NoError(t, err)
+
+	out, err = run(RestoreCmd, "--in", archive)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "restored 0 agent(s) and 0 config version(s)")
+}
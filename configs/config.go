@@ -9,15 +9,75 @@ import (
 // Config holds all configuration values.
 // Environment variables can override the default values.
 type Config struct {
-	ControllerPort string `envconfig:"CONTROLLER_PORT" default:"8080"`
-	AgentPort      string `envconfig:"AGENT_PORT" default:"8081"`
-	WorkerPort     string `envconfig:"WORKER_PORT" default:"8082"`
-	DBPath         string `envconfig:"DB_PATH" default:"controller.db"`
-	ControllerURL  string `envconfig:"CONTROLLER_URL" default:"http://localhost:8080"`
-	WorkerURL      string `envconfig:"WORKER_URL" default:"http://localhost:8082"`
-	PollInterval   int    `envconfig:"POLL_INTERVAL" default:"30"`
-	AgentAuthToken string `envconfig:"AGENT_AUTH_TOKEN" default:"agent-secret"`
-	PollURL        string `envconfig:"POLL_URL" default:"/v1/config"`
+	ControllerPort  string `envconfig:"CONTROLLER_PORT" default:"8080"`
+	AgentPort       string `envconfig:"AGENT_PORT" default:"8081"`
+	WorkerPort      string `envconfig:"WORKER_PORT" default:"8082"`
+	DBPath          string `envconfig:"DB_PATH" default:"controller.db"`
+	ControllerURL   string `envconfig:"CONTROLLER_URL" default:"http://localhost:8080"`
+	WorkerURL       string `envconfig:"WORKER_URL" default:"http://localhost:8082"`
+	PollInterval    int    `envconfig:"POLL_INTERVAL" default:"30"`
+	AgentAuthToken  string `envconfig:"AGENT_AUTH_TOKEN" default:"agent-secret"`
+	AdminAuthToken  string `envconfig:"ADMIN_AUTH_TOKEN" default:"admin-secret"`
+	PollURL         string `envconfig:"POLL_URL" default:"/v1/config"`
+	ShutdownTimeout int    `envconfig:"SHUTDOWN_TIMEOUT" default:"10"`                 // seconds to drain in-flight requests on SIGINT/SIGTERM
+	ConfigCacheTTL  int    `envconfig:"CONFIG_CACHE_TTL" default:"5"`                  // seconds, 0 disables the latest config cache
+	WorkerStateFile string `envconfig:"WORKER_STATE_FILE" default:"worker-state.json"` // empty keeps worker config in memory only
+	AgentCacheFile  string `envconfig:"AGENT_CACHE_FILE" default:"agent-cache.json"`   // empty disables the agent's offline fallback
+
+	// Where the controller reaches the agent's admin API to validate configs on its
+	// workers, empty keeps the agent out of validation
+	AgentAdvertiseURL string `envconfig:"AGENT_ADVERTISE_URL" default:"http://localhost:8081"`
+
+	// Agent to worker push retries, backoff in seconds
+	PushRetryInitial int `envconfig:"PUSH_RETRY_INITIAL" default:"1"`
+	PushRetryMax     int `envconfig:"PUSH_RETRY_MAX" default:"60"`
+	PushMaxAttempts  int `envconfig:"PUSH_MAX_ATTEMPTS" default:"10"`
+	PushConcurrency  int `envconfig:"PUSH_CONCURRENCY" default:"4"` // max pushes in flight across workers
+
+	// Workers managed by the agent: static (WORKER_URLS, or WORKER_URL when empty), dns, srv, file
+	// or registry. Workers that register themselves are managed in every mode.
+	WorkerDiscovery   string   `envconfig:"WORKER_DISCOVERY" default:"static"`
+	WorkerURLs        []string `envconfig:"WORKER_URLS"`
+	WorkerDNSName     string   `envconfig:"WORKER_DNS_NAME"`
+	WorkerDNSPort     int      `envconfig:"WORKER_DNS_PORT" default:"8082"` // A/AAAA lookups only
+	WorkerDNSScheme   string   `envconfig:"WORKER_DNS_SCHEME" default:"http"`
+	WorkerTargetsFile string   `envconfig:"WORKER_TARGETS_FILE"`
+
+	// Upstreams the worker's actions may call, whatever the pushed config says. Private,
+	// loopback and link-local addresses are refused unless listed in WORKER_EGRESS_CIDRS.
+	WorkerEgressSchemes []string `envconfig:"WORKER_EGRESS_SCHEMES" default:"http,https"`
+	WorkerEgressHosts   []string `envconfig:"WORKER_EGRESS_HOSTS"` // empty allows any host, "*.example.com" its subdomains
+	WorkerEgressCIDRs   []string `envconfig:"WORKER_EGRESS_CIDRS"`
+
+	// Proxies in front of the worker whose X-Forwarded-For is trusted to identify
+	// /hit clients; empty uses the peer address
+	WorkerTrustedProxies []string `envconfig:"WORKER_TRUSTED_PROXIES"`
+
+	// Worker self-registration with its local agent, empty AGENT_URL disables it
+	AgentURL              string   `envconfig:"AGENT_URL"`
+	WorkerAdvertiseURL    string   `envconfig:"WORKER_ADVERTISE_URL" default:"http://localhost:8082"`
+	WorkerNamespaces      []string `envconfig:"WORKER_NAMESPACES"`
+	WorkerCapabilities    []string `envconfig:"WORKER_CAPABILITIES"`
+	WorkerRegistrationTTL int      `envconfig:"WORKER_REGISTRATION_TTL" default:"30"` // seconds, workers renew every TTL/3
+
+	// Config version retention on the controller, intervals are in seconds
+	RetentionKeepLast   int  `envconfig:"RETENTION_KEEP_LAST" default:"100"`
+	RetentionKeepDays   int  `envconfig:"RETENTION_KEEP_DAYS" default:"0"`
+	RetentionKeepTagged bool `envconfig:"RETENTION_KEEP_TAGGED" default:"true"`
+	RetentionInterval   int  `envconfig:"RETENTION_INTERVAL" default:"3600"`
+
+	// High availability: replicas elect a leader through a lease in the shared database
+	HAEnabled              bool   `envconfig:"HA_ENABLED" default:"false"`
+	ControllerID           string `envconfig:"CONTROLLER_ID"` // generated when empty
+	ControllerAdvertiseURL string `envconfig:"CONTROLLER_ADVERTISE_URL" default:"http://localhost:8080"`
+	LeaderLeaseTTL         int    `envconfig:"LEADER_LEASE_TTL" default:"15"`
+
+	// Tracing: none, stdout, file (TRACING_FILE) or otlp (TRACING_OTLP_ENDPOINT, or the
+	// standard OTEL_EXPORTER_OTLP_* variables when empty)
+	TracingExporter     string  `envconfig:"TRACING_EXPORTER" default:"none"`
+	TracingFile         string  `envconfig:"TRACING_FILE" default:"traces.jsonl"`
+	TracingOTLPEndpoint string  `envconfig:"TRACING_OTLP_ENDPOINT"`
+	TracingSampleRatio  float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
 }
 
 // LoadConfig returns a Config populated by envconfig.
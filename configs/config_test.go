@@ -54,4 +54,25 @@ func TestLoadConfig_Defaults(t *testing.T) {
 	assert.Equal(t, "http://localhost:8080", cfg.ControllerURL)
 	assert.Equal(t, "http://localhost:8082", cfg.WorkerURL)
 	assert.Equal(t, 30, cfg.PollInterval)
+	assert.Equal(t, "admin-secret", cfg.AdminAuthToken)
+	assert.Equal(t, 5, cfg.ConfigCacheTTL)
+	assert.Equal(t, "worker-state.json", cfg.WorkerStateFile)
+	assert.Equal(t, "agent-cache.json", cfg.AgentCacheFile)
+	assert.Equal(t, "http://localhost:8081", cfg.AgentAdvertiseURL)
+	assert.Equal(t, 1, cfg.PushRetryInitial)
+	assert.Equal(t, 60, cfg.PushRetryMax)
+	assert.Equal(t, 10, cfg.PushMaxAttempts)
+	assert.Equal(t, 4, cfg.PushConcurrency)
+	assert.Equal(t, "static", cfg.WorkerDiscovery)
+	assert.Empty(t, cfg.WorkerURLs)
+	assert.Equal(t, 8082, cfg.WorkerDNSPort)
+	assert.Empty(t, cfg.AgentURL)
+	assert.Equal(t, "http://localhost:8082", cfg.WorkerAdvertiseURL)
+	assert.Equal(t, 30, cfg.WorkerRegistrationTTL)
+	assert.Equal(t, []string{"http", "https"}, cfg.WorkerEgressSchemes)
+	assert.Empty(t, cfg.WorkerEgressCIDRs)
+	assert.Empty(t, cfg.WorkerTrustedProxies)
+	assert.Equal(t, 10, cfg.ShutdownTimeout)
+	assert.Equal(t, "none", cfg.TracingExporter)
+	assert.Equal(t, 1.0, cfg.TracingSampleRatio)
 }
@@ -5,13 +5,106 @@ import (
 	"config-manager/internal/handler"
 	"config-manager/internal/logger"
 	"config-manager/internal/usecase"
+	"config-manager/pkg/shared/health"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-func InitializeAgent(cfg *configs.Config) {
+// InitializeAgent wires the agent's API and polls the controller until ctx is
+// cancelled. The returned channel is closed once the poller has stopped.
+func InitializeAgent(ctx context.Context, e *echo.Echo, cfg *configs.Config, reg prometheus.Registerer) <-chan struct{} {
 	agentManager := usecase.NewAgentManager(cfg)
 	log := logger.NewLogger()
-	poller := handler.NewControllerPoller(cfg, agentManager, log)
 
-	// Block and run
-	poller.Start()
+	var cache usecase.AgentCache
+	if cfg.AgentCacheFile != "" {
+		cache = usecase.NewFileAgentCache(cfg.AgentCacheFile, cfg.AgentAuthToken)
+	}
+
+	// Self-registered workers are managed on top of the configured ones
+	registry := usecase.NewWorkerRegistry(time.Duration(cfg.WorkerRegistrationTTL) * time.Second)
+	discovery := usecase.WorkerDiscovery(registry)
+	if configured := newWorkerDiscovery(cfg); configured != nil {
+		discovery = usecase.NewMergedDiscovery(configured, registry)
+	}
+	poller := handler.NewControllerPoller(cfg, agentManager, cache, discovery, handler.NewAgentMetrics(reg), log)
+
+	handler.NewWorkerRegistryHandler(e, registry, poller.RefreshWorkers, log, cfg.AgentAuthToken)
+	handler.NewAgentAdminHandler(e, poller, log, cfg.AdminAuthToken)
+
+	healthClient := &http.Client{Timeout: health.CheckTimeout}
+	health.Register(e, nil, []health.Check{
+		{Name: "registration", Run: func(ctx context.Context) error {
+			if !poller.Status().Registered {
+				return errors.New("not registered with the controller")
+			}
+			return nil
+		}},
+		{Name: "controller", Run: health.HTTPCheck(healthClient, cfg.ControllerURL+"/healthz")},
+		{Name: "workers", Run: workersReachable(poller, healthClient)},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		poller.Start(ctx)
+	}()
+	return done
+}
+
+// workersReachable passes when the agent manages at least one worker and every
+// managed worker answers its health endpoint.
+func workersReachable(runtime handler.AgentRuntime, client *http.Client) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		workers := runtime.Status().Workers
+		if len(workers) == 0 {
+			return errors.New("no workers managed")
+		}
+
+		var errs []error
+		for _, w := range workers {
+			if err := health.HTTPCheck(client, w.URL+"/healthz")(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", w.URL, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// newWorkerDiscovery returns the configured discovery, or nil when only
+// self-registered workers are managed.
+func newWorkerDiscovery(cfg *configs.Config) usecase.WorkerDiscovery {
+	switch cfg.WorkerDiscovery {
+	case "", "static":
+		if len(cfg.WorkerURLs) > 0 {
+			return usecase.NewStaticDiscovery(cfg.WorkerURLs)
+		}
+		return usecase.NewStaticDiscovery([]string{cfg.WorkerURL})
+	case "dns", "srv":
+		if cfg.WorkerDNSName == "" {
+			panic("Failed to configure worker discovery: WORKER_DNS_NAME is required")
+		}
+		return usecase.NewDNSDiscovery(net.DefaultResolver, usecase.DNSDiscoveryConfig{
+			Name:   cfg.WorkerDNSName,
+			SRV:    cfg.WorkerDiscovery == "srv",
+			Scheme: cfg.WorkerDNSScheme,
+			Port:   cfg.WorkerDNSPort,
+		})
+	case "file":
+		if cfg.WorkerTargetsFile == "" {
+			panic("Failed to configure worker discovery: WORKER_TARGETS_FILE is required")
+		}
+		return usecase.NewFileDiscovery(cfg.WorkerTargetsFile)
+	case "registry":
+		return nil
+	default:
+		panic("Failed to configure worker discovery: unknown WORKER_DISCOVERY " + cfg.WorkerDiscovery)
+	}
 }
@@ -2,9 +2,16 @@ package di
 
 import (
 	"config-manager/configs"
+	"config-manager/internal/dto"
+	"config-manager/internal/handler"
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,14 +21,90 @@ func TestInitializeAgent(t *testing.T) {
 		WorkerPort:    "8082",
 	}
 
-	// InitializeAgent starts the poller which runs indefinitely if not mocked.
-	// But actually, if Controller is unreachable, it will just keep retrying.
-	// We'll run it in a goroutine and give it a small time to initialize.
-
+	// InitializeAgent starts the poller in the background; with the controller
+	// unreachable it just keeps retrying.
+	e := echo.New()
 	assert.NotPanics(t, func() {
-		go InitializeAgent(cfg)
+		InitializeAgent(t.Context(), e, cfg, prometheus.NewRegistry())
 	})
 
+	routes := map[string]bool{}
+	for _, r := range e.Routes() {
+		routes[r.Method+" "+r.Path] = true
+	}
+	assert.True(t, routes["POST /v1/workers"])
+	assert.True(t, routes["DELETE /v1/workers"])
+	assert.True(t, routes["GET /v1/workers"])
+	assert.True(t, routes["GET /v1/admin/status"])
+	assert.True(t, routes["POST /v1/admin/resync"])
+	assert.True(t, routes["POST /v1/admin/cache/reload"])
+	assert.True(t, routes["GET /healthz"])
+	assert.True(t, routes["GET /readyz"])
+
 	// Just sleep slightly to let any immediate initialization happen and verify no panic
 	time.Sleep(100 * time.Millisecond)
+
+	// Not ready while the controller is unreachable
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "not registered with the controller")
+}
+
+func TestNewWorkerDiscovery(t *testing.T) {
+	t.Run("Falls Back To WORKER_URL", func(t *testing.T) {
+		urls, err := newWorkerDiscovery(&configs.Config{WorkerURL: "http://worker:8082"}).Workers(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"http://worker:8082"}, urls)
+	})
+
+	t.Run("WORKER_URLS", func(t *testing.T) {
+		cfg := &configs.Config{WorkerDiscovery: "static", WorkerURL: "http://worker:8082", WorkerURLs: []string{"http://w1:8082", "http://w2:8082"}}
+		urls, err := newWorkerDiscovery(cfg).Workers(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"http://w1:8082", "http://w2:8082"}, urls)
+	})
+
+	t.Run("Registry Only", func(t *testing.T) {
+		assert.Nil(t, newWorkerDiscovery(&configs.Config{WorkerDiscovery: "registry"}))
+	})
+
+	t.Run("Missing Settings", func(t *testing.T) {
+		assert.Panics(t, func() { newWorkerDiscovery(&configs.Config{WorkerDiscovery: "dns"}) })
+		assert.Panics(t, func() { newWorkerDiscovery(&configs.Config{WorkerDiscovery: "file"}) })
+		assert.Panics(t, func() { newWorkerDiscovery(&configs.Config{WorkerDiscovery: "consul"}) })
+	})
+}
+
+type fakeAgentRuntime struct {
+	status dto.AgentStatus
+}
+
+func (f *fakeAgentRuntime) Status() dto.AgentStatus { return f.status }
+func (f *fakeAgentRuntime) Resync()                 {}
+func (f *fakeAgentRuntime) ReloadCache() (*dto.AgentCacheEntry, error) {
+	return nil, handler.ErrAgentCacheDisabled
+}
+func (f *fakeAgentRuntime) ValidateConfig(ctx context.Context, req dto.ConfigRequest) []dto.WorkerConfigValidation {
+	return nil
+}
+func (f *fakeAgentRuntime) ValidateToken() string { return "" }
+
+func TestWorkersReachable(t *testing.T) {
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/healthz", r.URL.Path)
+	}))
+	defer worker.Close()
+
+	runtime := &fakeAgentRuntime{}
+	check := workersReachable(runtime, worker.Client())
+	assert.EqualError(t, check(t.Context()), "no workers managed")
+
+	runtime.status.Workers = []dto.AgentWorkerStatus{{URL: worker.URL}}
+	assert.NoError(t, check(t.Context()))
+
+	runtime.status.Workers = append(runtime.status.Workers, dto.AgentWorkerStatus{URL: "http://127.0.0.1:1"})
+	err := check(t.Context())
+	assert.ErrorContains(t, err, "http://127.0.0.1:1: ")
+	assert.NotContains(t, err.Error(), worker.URL)
 }
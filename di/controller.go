@@ -2,33 +2,95 @@ package di
 
 import (
 	"config-manager/configs"
-	"config-manager/internal/domain"
 	"config-manager/internal/handler"
 	"config-manager/internal/logger"
+	"config-manager/internal/migration"
 	"config-manager/internal/repository"
 	"config-manager/internal/usecase"
+	"config-manager/pkg/shared/health"
+	"config-manager/pkg/shared/middleware"
 	"config-manager/pkg/shared/utils"
+	"context"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-func InitializeControllerV1(e *echo.Echo, cfg *configs.Config) {
+// InitializeControllerV1 wires the controller's API, registering its metrics with
+// reg, and runs its background jobs until ctx is cancelled. The returned function
+// waits for the jobs to stop and closes the database.
+func InitializeControllerV1(ctx context.Context, e *echo.Echo, cfg *configs.Config, reg prometheus.Registerer) func() {
 	// Init DB
 	db, err := utils.InitDB(cfg.DBPath)
 	if err != nil {
 		panic("Failed to connect to database: " + err.Error())
 	}
 
-	// Migrate
-	db.AutoMigrate(&domain.Agent{}, &domain.GlobalConfig{})
+	// Refuse to run against a schema this binary was not built for
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		panic("Failed to load migrations: " + err.Error())
+	}
+	if err := migrator.Check(); err != nil {
+		panic("Database schema mismatch, run `config-manager migrate up` with a matching binary: " + err.Error())
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic("Failed to connect to database: " + err.Error())
+	}
+	health.Register(e, nil, []health.Check{
+		{Name: "database", Run: sqlDB.PingContext},
+		{Name: "migrations", Run: func(ctx context.Context) error { return migrator.Check() }},
+	})
 
 	// Repositories
 	agentRepo := repository.NewAgentRepository(db)
 	configRepo := repository.NewConfigRepository(db)
+	backupRepo := repository.NewBackupRepository(db)
+	leaseRepo := repository.NewLeaseRepository(db)
 
 	// Usecases
 	agentUsecase := usecase.NewAgentUsecase(agentRepo, cfg.PollURL, cfg.PollInterval)
 	configUsecase := usecase.NewConfigUsecase(configRepo)
+	if cfg.ConfigCacheTTL > 0 {
+		configUsecase = usecase.NewCachedConfigUsecase(configUsecase, time.Duration(cfg.ConfigCacheTTL)*time.Second)
+	}
+	configValidator := usecase.NewConfigValidator(agentRepo, usecase.ConfigValidatorConfig{
+		ActiveWithin: 3 * time.Duration(cfg.PollInterval) * time.Second,
+		Timeout:      10 * time.Second,
+	})
+	backupUsecase := usecase.NewBackupUsecase(backupRepo)
+	retentionUsecase := usecase.NewRetentionUsecase(configRepo, agentRepo, usecase.RetentionPolicy{
+		KeepLast:     cfg.RetentionKeepLast,
+		KeepFor:      time.Duration(cfg.RetentionKeepDays) * 24 * time.Hour,
+		KeepTagged:   cfg.RetentionKeepTagged,
+		ActiveWithin: 3 * time.Duration(cfg.PollInterval) * time.Second,
+	})
+
+	// Leader election, a single replica is always the leader
+	elector := usecase.NewStaticElector(cfg.ControllerAdvertiseURL)
+	if cfg.HAEnabled {
+		controllerID := cfg.ControllerID
+		if controllerID == "" {
+			controllerID = uuid.New().String()
+		}
+		elector = usecase.NewLeaseElector(leaseRepo, usecase.LeaseElectorConfig{
+			Name: "controller",
+			ID:   controllerID,
+			URL:  cfg.ControllerAdvertiseURL,
+			TTL:  time.Duration(cfg.LeaderLeaseTTL) * time.Second,
+		})
+	}
+	var jobs sync.WaitGroup
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		elector.Run(ctx)
+	}()
 
 	// Group V1
 	v1 := e.Group("/v1")
@@ -38,5 +100,21 @@ func InitializeControllerV1(e *echo.Echo, cfg *configs.Config) {
 
 	// Handlers
 	handler.NewAgentHandler(v1, agentUsecase, log, cfg.AgentAuthToken)
-	handler.NewConfigHandler(v1, configUsecase, log)
+	handler.NewConfigHandler(v1, configUsecase, configValidator, handler.NewControllerMetrics(reg, agentUsecase), log, cfg.AdminAuthToken, middleware.ForwardToLeader(elector))
+	handler.NewAdminHandler(v1, backupUsecase, log, cfg.AdminAuthToken)
+
+	// Background jobs
+	retentionJob := handler.NewRetentionJob(retentionUsecase, elector, time.Duration(cfg.RetentionInterval)*time.Second, log)
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		retentionJob.Start(ctx)
+	}()
+
+	return func() {
+		jobs.Wait()
+		if err := sqlDB.Close(); err != nil {
+			log.Error("Failed to close database", "error", err)
+		}
+	}
 }
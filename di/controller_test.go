@@ -2,9 +2,16 @@ package di
 
 import (
 	"config-manager/configs"
+	"config-manager/internal/migration"
+	"config-manager/pkg/shared/utils"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"testing"
 
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,9 +22,22 @@ func TestInitializeControllerV1(t *testing.T) {
 		PollInterval: 30,
 	}
 
+	// The controller refuses to start until the schema is migrated
+	assert.Panics(t, func() {
+		InitializeControllerV1(t.Context(), echo.New(), cfg, prometheus.NewRegistry())
+	})
+
+	// Keep a connection open so the shared in-memory database survives
+	db, err := utils.InitDB(cfg.DBPath)
+	assert.NoError(t, err)
+	migrator, err := migration.NewMigrator(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
 	// Given a valid config and echo instance, InitializeControllerV1 should not panic
 	assert.NotPanics(t, func() {
-		InitializeControllerV1(e, cfg)
+		InitializeControllerV1(t.Context(), e, cfg, prometheus.NewRegistry())
 	})
 
 	// Check if routes are registered
@@ -37,3 +57,76 @@ func TestInitializeControllerV1(t *testing.T) {
 	assert.True(t, hasRegister)
 	assert.True(t, hasConfig)
 }
+
+func TestInitializeControllerV1_HA(t *testing.T) {
+	cfg := &configs.Config{
+		DBPath:                 "file::memory:?cache=shared",
+		PollInterval:           30,
+		HAEnabled:              true,
+		ControllerAdvertiseURL: "http://controller-1:8080",
+		LeaderLeaseTTL:         15,
+	}
+
+	db, err := utils.InitDB(cfg.DBPath)
+	assert.NoError(t, err)
+	migrator, err := migration.NewMigrator(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	assert.NotPanics(t, func() {
+		InitializeControllerV1(t.Context(), echo.New(), cfg, prometheus.NewRegistry())
+	})
+}
+
+func TestInitializeControllerV1_Cleanup(t *testing.T) {
+	cfg := &configs.Config{DBPath: filepath.Join(t.TempDir(), "controller.db")}
+	db, err := utils.InitDB(cfg.DBPath)
+	assert.NoError(t, err)
+	migrator, err := migration.NewMigrator(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	e := echo.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cleanup := InitializeControllerV1(ctx, e, cfg, prometheus.NewRegistry())
+
+	// Background jobs stop with ctx, so cleanup returns and closes the database
+	cancel()
+	cleanup()
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/config", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "database is closed")
+
+	// Readiness reports the closed database
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"database","status":"fail"`)
+}
+
+func TestInitializeControllerV1_Readiness(t *testing.T) {
+	cfg := &configs.Config{DBPath: filepath.Join(t.TempDir(), "controller.db")}
+	db, err := utils.InitDB(cfg.DBPath)
+	assert.NoError(t, err)
+	migrator, err := migration.NewMigrator(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	e := echo.New()
+	InitializeControllerV1(t.Context(), e, cfg, prometheus.NewRegistry())
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Contains(t, rec.Body.String(), `"name":"migrations","status":"ok"`)
+}
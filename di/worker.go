@@ -2,16 +2,75 @@ package di
 
 import (
 	"config-manager/configs"
+	"config-manager/internal/dto"
 	"config-manager/internal/handler"
 	"config-manager/internal/logger"
 	"config-manager/internal/usecase"
+	"config-manager/pkg/shared/health"
+	"context"
+	"errors"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-func InitializeWorker(e *echo.Echo, cfg *configs.Config) {
-	configManager := usecase.NewConfigManager()
+// InitializeWorker wires the worker routes and, when AGENT_URL is set, keeps the
+// worker registered with its agent until ctx is cancelled. The returned channel
+// is closed once the worker has deregistered.
+func InitializeWorker(ctx context.Context, e *echo.Echo, cfg *configs.Config, reg prometheus.Registerer) <-chan struct{} {
 	log := logger.NewLogger()
 
-	handler.NewWorkerHandler(e, configManager, log)
+	egress, err := usecase.ParseEgressPolicy(cfg.WorkerEgressSchemes, cfg.WorkerEgressHosts, cfg.WorkerEgressCIDRs)
+	if err != nil {
+		panic("Failed to parse worker egress policy: " + err.Error())
+	}
+
+	e.IPExtractor, err = handler.ClientIPExtractor(cfg.WorkerTrustedProxies)
+	if err != nil {
+		panic("Failed to parse worker trusted proxies: " + err.Error())
+	}
+
+	configManager := usecase.NewConfigManager(egress)
+	if cfg.WorkerStateFile != "" {
+		configManager, err = usecase.NewPersistentConfigManager(cfg.WorkerStateFile, egress)
+		if err != nil {
+			panic("Failed to restore worker state: " + err.Error())
+		}
+		if state := configManager.GetConfig(); state.Version != "" {
+			log.Info("Restored worker config from state file", "version", state.Version, "path", cfg.WorkerStateFile)
+		}
+	}
+
+	handler.NewWorkerHandler(e, configManager, handler.NewWorkerMetrics(reg, configManager), log)
+	health.Register(e, nil, []health.Check{
+		{Name: "config", Run: func(ctx context.Context) error {
+			if configManager.GetConfig().Version == "" {
+				return errors.New("no config loaded")
+			}
+			return nil
+		}},
+	})
+
+	done := make(chan struct{})
+	if cfg.AgentURL == "" {
+		close(done)
+		return done
+	}
+
+	registrar := usecase.NewWorkerRegistrar(cfg.AgentURL, cfg.AgentAuthToken, dto.WorkerRegistrationRequest{
+		URL:          cfg.WorkerAdvertiseURL,
+		Namespaces:   cfg.WorkerNamespaces,
+		Capabilities: cfg.WorkerCapabilities,
+	})
+	interval := time.Duration(cfg.WorkerRegistrationTTL) * time.Second / 3
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	registrationJob := handler.NewRegistrationJob(registrar, interval, log)
+	go func() {
+		defer close(done)
+		registrationJob.Start(ctx)
+	}()
+	return done
 }
@@ -2,9 +2,18 @@ package di
 
 import (
 	"config-manager/configs"
+	"config-manager/internal/dto"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,22 +22,135 @@ func TestInitializeWorker(t *testing.T) {
 	cfg := &configs.Config{}
 
 	assert.NotPanics(t, func() {
-		InitializeWorker(e, cfg)
+		InitializeWorker(context.Background(), e, cfg, prometheus.NewRegistry())
 	})
 
 	routes := e.Routes()
 	assert.Greater(t, len(routes), 0)
 
 	var hasConfig bool
+	var hasGetConfig bool
 	var hasHit bool
 	for _, r := range routes {
 		if r.Path == "/v1/config" && r.Method == "POST" {
 			hasConfig = true
 		}
+		if r.Path == "/v1/config" && r.Method == "GET" {
+			hasGetConfig = true
+		}
 		if r.Path == "/hit" && r.Method == "GET" {
 			hasHit = true
 		}
 	}
 	assert.True(t, hasConfig)
+	assert.True(t, hasGetConfig)
 	assert.True(t, hasHit)
 }
+
+func TestInitializeWorker_Readiness(t *testing.T) {
+	e := echo.New()
+	InitializeWorker(context.Background(), e, &configs.Config{}, prometheus.NewRegistry())
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Not ready until the agent pushes a config
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "no config loaded")
+}
+
+func TestInitializeWorker_StateFile(t *testing.T) {
+	t.Run("Restores State", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "worker-state.json")
+		state := `{"version":"v1","config":{"url":"http://example.com"},"updated_at":"2024-01-02T03:04:05Z"}`
+		assert.NoError(t, os.WriteFile(path, []byte(state), 0o600))
+
+		e := echo.New()
+		InitializeWorker(context.Background(), e, &configs.Config{WorkerStateFile: path}, prometheus.NewRegistry())
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/config", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"version":"v1"`)
+
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Invalid State Panics", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "worker-state.json")
+		assert.NoError(t, os.WriteFile(path, []byte("invalid json"), 0o600))
+
+		assert.Panics(t, func() {
+			InitializeWorker(context.Background(), echo.New(), &configs.Config{WorkerStateFile: path}, prometheus.NewRegistry())
+		})
+	})
+}
+
+func TestInitializeWorker_ClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/hit", nil)
+	req.RemoteAddr = "10.0.0.5:4321"
+	req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.1")
+
+	e := echo.New()
+	InitializeWorker(context.Background(), e, &configs.Config{}, prometheus.NewRegistry())
+	assert.Equal(t, "10.0.0.5", e.IPExtractor(req), "X-Forwarded-For is ignored by default")
+
+	e = echo.New()
+	InitializeWorker(context.Background(), e, &configs.Config{WorkerTrustedProxies: []string{"10.0.0.0/8"}}, prometheus.NewRegistry())
+	assert.Equal(t, "198.51.100.1", e.IPExtractor(req))
+
+	assert.Panics(t, func() {
+		InitializeWorker(context.Background(), echo.New(), &configs.Config{WorkerTrustedProxies: []string{"proxy"}}, prometheus.NewRegistry())
+	})
+}
+
+func TestInitializeWorker_Registration(t *testing.T) {
+	requests := make(chan string, 10)
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "agent-secret", r.Header.Get("Authorization"))
+		if r.Method == http.MethodPost {
+			var req dto.WorkerRegistrationRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "http://worker-a:8082", req.URL)
+			assert.Equal(t, []string{"edge"}, req.Namespaces)
+		} else {
+			assert.Equal(t, "http://worker-a:8082", r.URL.Query().Get("url"))
+		}
+		requests <- r.Method
+	}))
+	defer agent.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := InitializeWorker(ctx, echo.New(), &configs.Config{
+		AgentURL:              agent.URL,
+		AgentAuthToken:        "agent-secret",
+		WorkerAdvertiseURL:    "http://worker-a:8082",
+		WorkerNamespaces:      []string{"edge"},
+		WorkerRegistrationTTL: 3600,
+	}, prometheus.NewRegistry())
+
+	assert.Equal(t, http.MethodPost, <-requests)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not deregister")
+	}
+	assert.Equal(t, http.MethodDelete, <-requests)
+}
+
+func TestInitializeWorker_RegistrationDisabled(t *testing.T) {
+	done := InitializeWorker(context.Background(), echo.New(), &configs.Config{}, prometheus.NewRegistry())
+
+	select {
+	case <-done:
+	default:
+		t.Fatal("done should be closed without AGENT_URL")
+	}
+}
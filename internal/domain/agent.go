@@ -5,7 +5,11 @@ import (
 )
 
 type Agent struct {
-	ID        string `gorm:"primaryKey"`
-	Name      string
-	CreatedAt time.Time
+	ID             string `gorm:"primaryKey"`
+	Name           string
+	URL            string // where the controller reaches the agent's admin API, empty when not advertised
+	ValidateToken  string // issued to the agent with URL, the controller's only credential for it
+	AppliedVersion string // last config version the agent reported as pushed to its worker
+	LastSeenAt     *time.Time
+	CreatedAt      time.Time
 }
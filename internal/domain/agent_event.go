@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"time"
+)
+
+// AgentEventDrift is reported when an agent finds its worker running a config
+// other than the desired one and re-pushes it.
+const AgentEventDrift = "drift"
+
+// AgentEventPushDeadLetter is reported when an agent gives up pushing a config
+// version to its worker after exhausting its retries.
+const AgentEventPushDeadLetter = "push_dead_letter"
+
+type AgentEvent struct {
+	ID             uint `gorm:"primaryKey"`
+	AgentID        string
+	WorkerURL      string // set for events about a single worker
+	Type           string
+	DesiredVersion string
+	ActualVersion  string
+	Message        string
+	CreatedAt      time.Time
+}
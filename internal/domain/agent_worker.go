@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"time"
+)
+
+// AgentWorker is the last push result an agent reported for one of its workers.
+type AgentWorker struct {
+	AgentID        string `gorm:"primaryKey"`
+	URL            string `gorm:"primaryKey"`
+	AppliedVersion string
+	PendingVersion string
+	LastError      string
+	UpdatedAt      time.Time
+}
@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"time"
+)
+
+// Snapshot is a consistent, point-in-time copy of the controller data.
+type Snapshot struct {
+	SchemaVersion int
+	TakenAt       time.Time
+	Agents        []Agent
+	Workers       []AgentWorker
+	Configs       []GlobalConfig
+	Events        []AgentEvent
+}
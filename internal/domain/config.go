@@ -8,5 +8,7 @@ type GlobalConfig struct {
 	ID        uint   `gorm:"primaryKey"`
 	Config    string `gorm:"type:text"` // JSON format
 	Version   string
+	Tag       string // optional label, tagged versions can be exempt from retention
+	Pinned    bool   // pinned versions are never removed by retention
 	CreatedAt time.Time
 }
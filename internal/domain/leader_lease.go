@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"time"
+)
+
+// LeaderLease records which controller replica currently holds a named leadership lease.
+type LeaderLease struct {
+	Name      string `gorm:"primaryKey"`
+	HolderID  string
+	HolderURL string
+	ExpiresAt time.Time
+	UpdatedAt time.Time
+}
@@ -1,13 +1,79 @@
 package dto
 
+import "time"
+
 type AgentRegisterRequest struct {
 	Name string `json:"name"`
+	URL  string `json:"url,omitempty"` // where the controller reaches the agent's admin API
 }
 
 type AgentRegisterResponse struct {
 	AgentID             string `json:"agent_id"`
 	PollURL             string `json:"poll_url"`
 	PollIntervalSeconds int    `json:"poll_interval_seconds"`
+	ValidateToken       string `json:"validate_token,omitempty"`
 	Code                int    `json:"code"`
 	RequestID           string `json:"request_id"`
 }
+
+type AgentHeartbeatRequest struct {
+	AgentID        string `json:"agent_id"`
+	AppliedVersion string `json:"applied_version"` // version applied to every worker
+	// Workers lists per-worker push results; omitted by agents that predate
+	// multi-worker support, in which case the stored results are left as they are.
+	Workers []WorkerHeartbeat `json:"workers,omitempty"`
+}
+
+type WorkerHeartbeat struct {
+	URL            string `json:"url"`
+	AppliedVersion string `json:"applied_version"`
+	PendingVersion string `json:"pending_version,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+}
+
+type AgentWorker struct {
+	URL            string    `json:"url"`
+	AppliedVersion string    `json:"applied_version"`
+	PendingVersion string    `json:"pending_version,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type AgentWorkersResponse struct {
+	Workers   []AgentWorker `json:"workers"`
+	Code      int           `json:"code"`
+	RequestID string        `json:"request_id"`
+}
+
+type AgentEventRequest struct {
+	AgentID        string `json:"agent_id"`
+	WorkerURL      string `json:"worker_url,omitempty"`
+	Type           string `json:"type"`
+	DesiredVersion string `json:"desired_version,omitempty"`
+	ActualVersion  string `json:"actual_version,omitempty"`
+	Message        string `json:"message,omitempty"`
+}
+
+type AgentEvent struct {
+	ID             uint      `json:"id"`
+	AgentID        string    `json:"agent_id"`
+	WorkerURL      string    `json:"worker_url,omitempty"`
+	Type           string    `json:"type"`
+	DesiredVersion string    `json:"desired_version,omitempty"`
+	ActualVersion  string    `json:"actual_version,omitempty"`
+	Message        string    `json:"message,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type AgentEventsResponse struct {
+	Events    []AgentEvent `json:"events"`
+	Code      int          `json:"code"`
+	RequestID string       `json:"request_id"`
+}
+
+// AgentConfigValidateResponse lists how each worker of an agent checked a config.
+type AgentConfigValidateResponse struct {
+	Workers   []WorkerConfigValidation `json:"workers"`
+	Code      int                      `json:"code"`
+	RequestID string                   `json:"request_id"`
+}
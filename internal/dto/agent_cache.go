@@ -0,0 +1,59 @@
+package dto
+
+import "time"
+
+// AgentCacheEntry is the last config an agent fetched from the controller, as
+// stored in its local cache file.
+type AgentCacheEntry struct {
+	Version   string                 `json:"version"`
+	Config    map[string]interface{} `json:"config"`
+	Signature string                 `json:"signature"`
+	FetchedAt time.Time              `json:"fetched_at"`
+}
+
+// AgentStatus describes what an agent is currently serving to its workers.
+type AgentStatus struct {
+	AgentID        string              `json:"agent_id,omitempty"`
+	Registered     bool                `json:"registered"`
+	Version        string              `json:"version"`         // desired version
+	AppliedVersion string              `json:"applied_version"` // version applied to every worker
+	Stale          bool                `json:"stale"`           // true until the controller confirms the version, and while it is unreachable
+	Source         string              `json:"source"`          // "cache" or "controller"
+	LastSyncAt     *time.Time          `json:"last_sync_at,omitempty"`
+	Poll           PollStatus          `json:"poll"`
+	Workers        []AgentWorkerStatus `json:"workers"`
+}
+
+// PollStatus describes the agent's polling of the controller.
+type PollStatus struct {
+	IntervalSeconds     int        `json:"interval_seconds"`
+	LastPollAt          *time.Time `json:"last_poll_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"` // reset by the next successful poll
+	NextPollAt          *time.Time `json:"next_poll_at,omitempty"`
+}
+
+// AgentWorkerStatus describes one worker managed by an agent.
+type AgentWorkerStatus struct {
+	URL            string     `json:"url"`
+	AppliedVersion string     `json:"applied_version"`
+	Push           PushStatus `json:"push"`
+}
+
+// PushDeadLetter records a config version the agent gave up pushing to its worker.
+type PushDeadLetter struct {
+	Version   string    `json:"version"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	Rejected  bool      `json:"rejected"` // the worker refused the config, retrying cannot help
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// PushStatus describes the agent's in-flight and failed pushes to its worker.
+type PushStatus struct {
+	PendingVersion string           `json:"pending_version,omitempty"`
+	Attempts       int              `json:"attempts,omitempty"`
+	LastError      string           `json:"last_error,omitempty"`
+	NextRetryAt    *time.Time       `json:"next_retry_at,omitempty"`
+	DeadLetters    []PushDeadLetter `json:"dead_letters"`
+}
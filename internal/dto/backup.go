@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BackupFormat identifies a config-manager backup archive.
+const BackupFormat = "config-manager-backup"
+
+// BackupFormatVersion is bumped whenever the archive layout changes incompatibly.
+const BackupFormatVersion = 3
+
+// BackupArchive is the driver-independent document stored (gzip compressed) in a backup file.
+type BackupArchive struct {
+	Format        string         `json:"format"`
+	FormatVersion int            `json:"format_version"`
+	SchemaVersion int            `json:"schema_version"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Agents        []BackupAgent  `json:"agents"`
+	Workers       []BackupWorker `json:"workers,omitempty"`
+	Configs       []BackupConfig `json:"configs"`
+	Events        []BackupEvent  `json:"events,omitempty"`
+}
+
+type BackupAgent struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	URL            string     `json:"url,omitempty"`
+	ValidateToken  string     `json:"validate_token,omitempty"`
+	AppliedVersion string     `json:"applied_version,omitempty"`
+	LastSeenAt     *time.Time `json:"last_seen_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type BackupWorker struct {
+	AgentID        string    `json:"agent_id"`
+	URL            string    `json:"url"`
+	AppliedVersion string    `json:"applied_version,omitempty"`
+	PendingVersion string    `json:"pending_version,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type BackupConfig struct {
+	ID        uint            `json:"id"`
+	Version   string          `json:"version"`
+	Config    json.RawMessage `json:"config"`
+	Tag       string          `json:"tag,omitempty"`
+	Pinned    bool            `json:"pinned,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type BackupEvent struct {
+	ID             uint      `json:"id"`
+	AgentID        string    `json:"agent_id"`
+	WorkerURL      string    `json:"worker_url,omitempty"`
+	Type           string    `json:"type"`
+	DesiredVersion string    `json:"desired_version,omitempty"`
+	ActualVersion  string    `json:"actual_version,omitempty"`
+	Message        string    `json:"message,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -1,7 +1,13 @@
 package dto
 
 type ConfigRequest struct {
-	Config map[string]interface{} `json:"config"`
+	Config  map[string]interface{} `json:"config"`
+	Tag     string                 `json:"tag,omitempty"`
+	Version string                 `json:"version,omitempty"` // set by agents when pushing to a worker, ignored by the controller
+}
+
+type ConfigPinRequest struct {
+	Pinned bool `json:"pinned"`
 }
 
 type ConfigResponse struct {
@@ -10,3 +16,52 @@ type ConfigResponse struct {
 	Code      int                    `json:"code"`
 	RequestID string                 `json:"request_id"`
 }
+
+// ConfigValidateRequest is a candidate config to check without saving it.
+type ConfigValidateRequest struct {
+	Config map[string]interface{} `json:"config"`
+	Agents int                    `json:"agents,omitempty"` // agents asked to check it on their workers, 0 runs the controller's checks only
+}
+
+// ConfigIssue is a problem found in a config, in the named action when Action
+// is set.
+type ConfigIssue struct {
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error"`
+}
+
+// ConfigValidation is the result of checking a config, on the controller or a
+// single worker.
+type ConfigValidation struct {
+	Valid  bool          `json:"valid"`
+	Issues []ConfigIssue `json:"issues"`
+}
+
+// WorkerConfigValidation is how a worker checked a config, or why it could not
+// be asked.
+type WorkerConfigValidation struct {
+	URL string `json:"url"`
+	ConfigValidation
+	Error string `json:"error,omitempty"`
+}
+
+// AgentConfigValidation is how the workers of an agent checked a config, or why
+// the agent could not be asked.
+type AgentConfigValidation struct {
+	AgentID string                   `json:"agent_id"`
+	URL     string                   `json:"url"`
+	Workers []WorkerConfigValidation `json:"workers"`
+	Error   string                   `json:"error,omitempty"`
+}
+
+// ConfigValidateResponse combines the controller's checks with those of the
+// sampled agents' workers. Complete is false when a check asked for did not
+// run, e.g. no agent or worker could be asked; Valid is false then too, or when
+// any check found an issue.
+type ConfigValidateResponse struct {
+	ConfigValidation
+	Complete  bool                    `json:"complete"`
+	Agents    []AgentConfigValidation `json:"agents,omitempty"`
+	Code      int                     `json:"code"`
+	RequestID string                  `json:"request_id"`
+}
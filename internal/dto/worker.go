@@ -0,0 +1,97 @@
+package dto
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// WorkerState is the last config accepted by a worker, as persisted in its state file.
+type WorkerState struct {
+	Version   string                 `json:"version"`
+	Config    map[string]interface{} `json:"config"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+type WorkerConfigResponse struct {
+	Version   string     `json:"version"`
+	Digest    string     `json:"digest"` // sha256 of the config, see usecase.ConfigDigest
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Code      int        `json:"code"`
+	RequestID string     `json:"request_id"`
+}
+
+// HitRequest is a call to a worker's /hit proxy; its query parameters and body
+// can be used by the action's body template.
+type HitRequest struct {
+	Action   string // empty runs the default action, configured at the top level
+	Query    map[string]string
+	Body     string
+	ClientIP string      // for per-client rate limits
+	Headers  http.Header // for per-client rate limits keyed by a header
+}
+
+// HitResult is the upstream response fetched by a worker's /hit proxy.
+type HitResult struct {
+	StatusCode int
+	Headers    http.Header
+	Body       string
+	Stream     io.ReadCloser // replaces Body for passthrough actions, the caller must close it
+	Cache      string        // HIT, MISS or STALE when the action caches responses
+}
+
+// HitActionInfo describes an action a worker serves. Headers and the body are
+// left out as they often carry credentials.
+type HitActionInfo struct {
+	Name   string `json:"name"` // empty for the default action
+	Path   string `json:"path"`
+	Method string `json:"method,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Error  string `json:"error,omitempty"` // why the action cannot run, if it is invalid
+	// Circuit is the state of the action's circuit breaker: closed, open or half_open
+	Circuit   string            `json:"circuit,omitempty"`
+	Upstreams []HitUpstreamInfo `json:"upstreams,omitempty"` // when the action balances several
+}
+
+// HitUpstreamInfo is the load and health of an upstream of an action.
+type HitUpstreamInfo struct {
+	URL         string `json:"url"`
+	Weight      int    `json:"weight"`
+	Outstanding int    `json:"outstanding"` // requests in flight
+	Ejected     bool   `json:"ejected"`     // out of rotation after failing repeatedly
+}
+
+type HitActionsResponse struct {
+	Actions   []HitActionInfo `json:"actions"`
+	Code      int             `json:"code"`
+	RequestID string          `json:"request_id"`
+}
+
+// WorkerRegistrationRequest announces a worker to its local agent.
+type WorkerRegistrationRequest struct {
+	URL          string   `json:"url"`
+	Namespaces   []string `json:"namespaces,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// RegisteredWorker is a worker in the agent's registry.
+type RegisteredWorker struct {
+	URL          string    `json:"url"`
+	Namespaces   []string  `json:"namespaces,omitempty"`
+	Capabilities []string  `json:"capabilities,omitempty"`
+	RegisteredAt time.Time `json:"registered_at"`
+	LastSeenAt   time.Time `json:"last_seen_at"`
+}
+
+type RegisteredWorkersResponse struct {
+	Workers   []RegisteredWorker `json:"workers"`
+	Code      int                `json:"code"`
+	RequestID string             `json:"request_id"`
+}
+
+// WorkerConfigValidateResponse is a worker's check of a config it did not apply.
+type WorkerConfigValidateResponse struct {
+	ConfigValidation
+	Code      int    `json:"code"`
+	RequestID string `json:"request_id"`
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"bytes"
+	"config-manager/internal/usecase"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"config-manager/pkg/shared/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AdminHandler struct {
+	backupUsecase usecase.BackupUsecase
+	logger        *slog.Logger
+}
+
+func NewAdminHandler(e *echo.Group, backupUsecase usecase.BackupUsecase, logger *slog.Logger, authToken string) {
+	handler := &AdminHandler{
+		backupUsecase: backupUsecase,
+		logger:        logger,
+	}
+
+	admin := e.Group("/admin", middleware.StaticTokenAuth("Authorization", authToken))
+	admin.GET("/backup", handler.Backup)
+}
+
+// Backup godoc
+// @Summary Download a backup archive
+// @Description Stream a consistent, gzip-compressed JSON snapshot of agents and all config versions
+// @Tags Admin
+// @Produce application/gzip
+// @Success 200 {file} file
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/backup [get]
+func (h *AdminHandler) Backup(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	// Buffer the archive so a failed snapshot can still be reported as JSON
+	// instead of a truncated download.
+	var buf bytes.Buffer
+	if err := h.backupUsecase.Export(&buf); err != nil {
+		h.logger.Error("failed to export backup", "error", err.Error(), "request_id", reqID)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       http.StatusInternalServerError,
+			"request_id": reqID,
+		})
+	}
+
+	h.logger.Info("backup exported", "bytes", buf.Len(), "request_id", reqID)
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+usecase.BackupFileName(time.Now())+`"`)
+	return c.Blob(http.StatusOK, "application/gzip", buf.Bytes())
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"config-manager/internal/dto"
+	"config-manager/internal/logger"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockBackupUsecase is a mock for the BackupUsecase interface
+type MockBackupUsecase struct {
+	mock.Mock
+}
+
+func (m *MockBackupUsecase) Export(w io.Writer) error {
+	args := m.Called(w)
+	return args.Error(0)
+}
+
+func (m *MockBackupUsecase) Import(r io.Reader, overwrite bool) (*dto.BackupArchive, error) {
+	args := m.Called(r, overwrite)
+	if args.Get(0) != nil {
+		return args.Get(0).(*dto.BackupArchive), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestAdminHandler_Backup(t *testing.T) {
+	e := echo.New()
+	log := logger.NewLogger()
+	mockUsecase := new(MockBackupUsecase)
+	h := &AdminHandler{backupUsecase: mockUsecase, logger: log}
+
+	t.Run("Success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/backup", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockUsecase.On("Export", mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(0).(io.Writer).Write([]byte("archive"))
+		}).Return(nil).Once()
+
+		err := h.Backup(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/gzip", rec.Header().Get(echo.HeaderContentType))
+		assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "attachment")
+		assert.Equal(t, "archive", rec.Body.String())
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Export Error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/backup", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockUsecase.On("Export", mock.Anything).Return(errors.New("db error")).Once()
+
+		err := h.Backup(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Requires Admin Token", func(t *testing.T) {
+		e := echo.New()
+		NewAdminHandler(e.Group("/v1"), mockUsecase, log, "admin-token")
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/backup", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"config-manager/internal/dto"
+	"config-manager/internal/usecase"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"config-manager/pkg/shared/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AgentRuntime is the part of the agent exposed through its admin API,
+// implemented by *ControllerPoller.
+type AgentRuntime interface {
+	Status() dto.AgentStatus
+	Resync()
+	ReloadCache() (*dto.AgentCacheEntry, error)
+	ValidateConfig(ctx context.Context, req dto.ConfigRequest) []dto.WorkerConfigValidation
+	ValidateToken() string
+}
+
+// AgentAdminHandler serves the agent's local admin API.
+type AgentAdminHandler struct {
+	runtime AgentRuntime
+	logger  *slog.Logger
+}
+
+func NewAgentAdminHandler(e *echo.Echo, runtime AgentRuntime, logger *slog.Logger, authToken string) {
+	handler := &AgentAdminHandler{
+		runtime: runtime,
+		logger:  logger,
+	}
+
+	admin := e.Group("/v1/admin")
+	admin.GET("/status", handler.Status, middleware.StaticTokenAuth("Authorization", authToken))
+	admin.POST("/resync", handler.Resync, middleware.StaticTokenAuth("Authorization", authToken))
+	admin.POST("/cache/reload", handler.ReloadCache, middleware.StaticTokenAuth("Authorization", authToken))
+	// The controller asks with the token it issued this agent, never its own.
+	admin.POST("/config/validate", handler.ValidateConfig, middleware.TokenAuth("Authorization", func() []string {
+		return []string{authToken, runtime.ValidateToken()}
+	}))
+}
+
+func (h *AgentAdminHandler) Status(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.runtime.Status())
+}
+
+func (h *AgentAdminHandler) Resync(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	h.runtime.Resync()
+	h.logger.Info("Forced resync requested", "request_id", reqID)
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"message":    "resync scheduled",
+		"code":       http.StatusAccepted,
+		"request_id": reqID,
+	})
+}
+
+func (h *AgentAdminHandler) ReloadCache(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	entry, err := h.runtime.ReloadCache()
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrAgentCacheDisabled), errors.Is(err, usecase.ErrAgentCacheEmpty):
+			status = http.StatusNotFound
+		case errors.Is(err, usecase.ErrAgentCacheSignature):
+			status = http.StatusUnprocessableEntity
+		}
+		h.logger.Error("failed to reload agent cache", "error", err.Error(), "request_id", reqID)
+		return c.JSON(status, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       status,
+			"request_id": reqID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":    "cached config reloaded",
+		"version":    entry.Version,
+		"fetched_at": entry.FetchedAt,
+		"code":       http.StatusOK,
+		"request_id": reqID,
+	})
+}
+
+// ValidateConfig forwards a candidate config to every worker of the agent, to
+// check it without applying it.
+func (h *AgentAdminHandler) ValidateConfig(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	var req dto.ConfigRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("failed to bind request", "error", err.Error(), "request_id", reqID)
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       http.StatusBadRequest,
+			"request_id": reqID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.AgentConfigValidateResponse{
+		Workers:   h.runtime.ValidateConfig(c.Request().Context(), req),
+		Code:      http.StatusOK,
+		RequestID: reqID,
+	})
+}
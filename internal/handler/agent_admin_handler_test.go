@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"config-manager/internal/dto"
+	"config-manager/internal/logger"
+	"config-manager/internal/usecase"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAgentRuntime is a mock for the AgentRuntime interface
+type MockAgentRuntime struct {
+	mock.Mock
+}
+
+func (m *MockAgentRuntime) Status() dto.AgentStatus {
+	args := m.Called()
+	return args.Get(0).(dto.AgentStatus)
+}
+
+func (m *MockAgentRuntime) Resync() {
+	m.Called()
+}
+
+func (m *MockAgentRuntime) ReloadCache() (*dto.AgentCacheEntry, error) {
+	args := m.Called()
+	if args.Get(0) != nil {
+		return args.Get(0).(*dto.AgentCacheEntry), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAgentRuntime) ValidateConfig(ctx context.Context, req dto.ConfigRequest) []dto.WorkerConfigValidation {
+	args := m.Called(req)
+	return args.Get(0).([]dto.WorkerConfigValidation)
+}
+
+func (m *MockAgentRuntime) ValidateToken() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+func TestAgentAdminHandler(t *testing.T) {
+	e := echo.New()
+	runtime := new(MockAgentRuntime)
+	NewAgentAdminHandler(e, runtime, logger.NewLogger(), "admin-secret")
+
+	do := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "admin-secret")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Status", func(t *testing.T) {
+		runtime.On("Status").Return(dto.AgentStatus{
+			AgentID:    "agent-1",
+			Registered: true,
+			Version:    "v2",
+			Poll:       dto.PollStatus{ConsecutiveFailures: 3, LastError: "connection refused"},
+			Workers:    []dto.AgentWorkerStatus{{URL: testWorkerURL, AppliedVersion: "v1"}},
+		}).Once()
+
+		rec := do(http.MethodGet, "/v1/admin/status")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var status dto.AgentStatus
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+		assert.Equal(t, "agent-1", status.AgentID)
+		assert.Equal(t, 3, status.Poll.ConsecutiveFailures)
+		assert.Len(t, status.Workers, 1)
+	})
+
+	t.Run("Resync", func(t *testing.T) {
+		runtime.On("Resync").Return().Once()
+
+		rec := do(http.MethodPost, "/v1/admin/resync")
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		runtime.AssertExpectations(t)
+	})
+
+	t.Run("Reload Cache", func(t *testing.T) {
+		runtime.On("ReloadCache").Return(&dto.AgentCacheEntry{Version: "v1"}, nil).Once()
+
+		rec := do(http.MethodPost, "/v1/admin/cache/reload")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"version":"v1"`)
+	})
+
+	t.Run("Validate Config", func(t *testing.T) {
+		config := map[string]interface{}{"url": "http://example.com"}
+		runtime.On("ValidateToken").Return("issued-token")
+		runtime.On("ValidateConfig", dto.ConfigRequest{Config: config}).Return([]dto.WorkerConfigValidation{
+			{URL: testWorkerURL, ConfigValidation: dto.ConfigValidation{Valid: true, Issues: []dto.ConfigIssue{}}},
+		}).Twice()
+
+		validate := func(token string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/v1/admin/config/validate", strings.NewReader(`{"config":{"url":"http://example.com"}}`))
+			req.Header.Set("Authorization", token)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+
+		// The admin token and the token the controller issued at register.
+		for _, token := range []string{"admin-secret", "issued-token"} {
+			rec := validate(token)
+
+			assert.Equal(t, http.StatusOK, rec.Code, token)
+			var res dto.AgentConfigValidateResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			assert.Len(t, res.Workers, 1)
+			assert.True(t, res.Workers[0].Valid)
+		}
+		for _, token := range []string{"", "wrong-token"} {
+			assert.Equal(t, http.StatusUnauthorized, validate(token).Code, token)
+		}
+		runtime.AssertExpectations(t)
+	})
+
+	t.Run("Reload Cache Errors", func(t *testing.T) {
+		for err, code := range map[error]int{
+			ErrAgentCacheDisabled:          http.StatusNotFound,
+			usecase.ErrAgentCacheEmpty:     http.StatusNotFound,
+			usecase.ErrAgentCacheSignature: http.StatusUnprocessableEntity,
+			errors.New("disk error"):       http.StatusInternalServerError,
+		} {
+			runtime.On("ReloadCache").Return(nil, err).Once()
+
+			rec := do(http.MethodPost, "/v1/admin/cache/reload")
+
+			assert.Equal(t, code, rec.Code, err.Error())
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/status", nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
@@ -3,12 +3,16 @@ package handler
 import (
 	"config-manager/internal/dto"
 	"config-manager/internal/usecase"
+	"config-manager/pkg/shared/tracing"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"config-manager/pkg/shared/middleware"
 
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 type AgentHandler struct {
@@ -23,8 +27,17 @@ func NewAgentHandler(e *echo.Group, agentUsecase usecase.AgentUsecase, logger *s
 	}
 
 	e.POST("/register", handler.Register, middleware.StaticTokenAuth("Authorization", authToken))
+	e.POST("/heartbeat", handler.Heartbeat, middleware.StaticTokenAuth("Authorization", authToken))
+	e.POST("/events", handler.ReportEvent, middleware.StaticTokenAuth("Authorization", authToken))
+	e.GET("/agents/:id/events", handler.ListEvents, middleware.StaticTokenAuth("Authorization", authToken))
+	e.GET("/agents/:id/workers", handler.ListWorkers, middleware.StaticTokenAuth("Authorization", authToken))
 }
 
+const (
+	defaultEventsLimit = 50
+	maxEventsLimit     = 500
+)
+
 // Register godoc
 // @Summary Register a new agent
 // @Description Register a new agent and get polling details
@@ -59,7 +72,184 @@ func (h *AgentHandler) Register(c echo.Context) error {
 		})
 	}
 
+	tracing.SetAttributes(c.Request().Context(), tracing.AttrAgentID.String(res.AgentID))
 	res.Code = http.StatusOK
 	res.RequestID = reqID
 	return c.JSON(http.StatusOK, res)
 }
+
+// Heartbeat godoc
+// @Summary Agent heartbeat
+// @Description Report the config version an agent has applied to its worker
+// @Tags Agent
+// @Accept json
+// @Produce json
+// @Param req body dto.AgentHeartbeatRequest true "Agent Heartbeat"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /heartbeat [post]
+func (h *AgentHandler) Heartbeat(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	var req dto.AgentHeartbeatRequest
+	if err := c.Bind(&req); err != nil || req.AgentID == "" {
+		errMsg := "agent_id is required"
+		if err != nil {
+			errMsg = err.Error()
+		}
+		h.logger.Error("failed to bind request", "error", errMsg, "request_id", reqID)
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":      errMsg,
+			"code":       http.StatusBadRequest,
+			"request_id": reqID,
+		})
+	}
+
+	tracing.SetAttributes(c.Request().Context(),
+		tracing.AttrAgentID.String(req.AgentID),
+		tracing.AttrConfigVersion.String(req.AppliedVersion),
+	)
+	if err := h.agentUsecase.Heartbeat(req); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The agent must register again, e.g. after a restore
+			status = http.StatusNotFound
+		}
+		h.logger.Error("failed to record heartbeat", "error", err.Error(), "agent_id", req.AgentID, "request_id", reqID)
+		return c.JSON(status, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       status,
+			"request_id": reqID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":    "success",
+		"code":       http.StatusOK,
+		"request_id": reqID,
+	})
+}
+
+// ReportEvent godoc
+// @Summary Report an agent event
+// @Description Record an event observed by an agent, e.g. a worker drifting from the desired config
+// @Tags Agent
+// @Accept json
+// @Produce json
+// @Param req body dto.AgentEventRequest true "Agent Event"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /events [post]
+func (h *AgentHandler) ReportEvent(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	var req dto.AgentEventRequest
+	if err := c.Bind(&req); err != nil || req.AgentID == "" || req.Type == "" {
+		errMsg := "agent_id and type are required"
+		if err != nil {
+			errMsg = err.Error()
+		}
+		h.logger.Error("failed to bind request", "error", errMsg, "request_id", reqID)
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":      errMsg,
+			"code":       http.StatusBadRequest,
+			"request_id": reqID,
+		})
+	}
+
+	if err := h.agentUsecase.ReportEvent(req); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		h.logger.Error("failed to record agent event", "error", err.Error(), "agent_id", req.AgentID, "request_id", reqID)
+		return c.JSON(status, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       status,
+			"request_id": reqID,
+		})
+	}
+
+	h.logger.Warn("Agent reported event", "agent_id", req.AgentID, "type", req.Type,
+		"desired_version", req.DesiredVersion, "actual_version", req.ActualVersion, "request_id", reqID)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":    "success",
+		"code":       http.StatusOK,
+		"request_id": reqID,
+	})
+}
+
+// ListEvents godoc
+// @Summary List agent events
+// @Description List the most recent events reported by an agent, newest first
+// @Tags Agent
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param limit query int false "Maximum number of events (default 50, max 500)"
+// @Success 200 {object} dto.AgentEventsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /agents/{id}/events [get]
+func (h *AgentHandler) ListEvents(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	limit := defaultEventsLimit
+	if raw := c.QueryParam("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxEventsLimit {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error":      "limit must be between 1 and " + strconv.Itoa(maxEventsLimit),
+				"code":       http.StatusBadRequest,
+				"request_id": reqID,
+			})
+		}
+		limit = n
+	}
+
+	events, err := h.agentUsecase.ListEvents(c.Param("id"), limit)
+	if err != nil {
+		h.logger.Error("failed to list agent events", "error", err.Error(), "request_id", reqID)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       http.StatusInternalServerError,
+			"request_id": reqID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.AgentEventsResponse{
+		Events:    events,
+		Code:      http.StatusOK,
+		RequestID: reqID,
+	})
+}
+
+// ListWorkers godoc
+// @Summary List agent workers
+// @Description List the per-worker push results from the agent's latest heartbeat
+// @Tags Agent
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {object} dto.AgentWorkersResponse
+// @Failure 500 {object} map[string]string
+// @Router /agents/{id}/workers [get]
+func (h *AgentHandler) ListWorkers(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+
+	workers, err := h.agentUsecase.ListWorkers(c.Param("id"))
+	if err != nil {
+		h.logger.Error("failed to list agent workers", "error", err.Error(), "request_id", reqID)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       http.StatusInternalServerError,
+			"request_id": reqID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.AgentWorkersResponse{
+		Workers:   workers,
+		Code:      http.StatusOK,
+		RequestID: reqID,
+	})
+}
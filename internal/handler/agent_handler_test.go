@@ -13,6 +13,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 // MockAgentUsecase is a mock for the AgentUsecase interface
@@ -28,6 +29,40 @@ func (m *MockAgentUsecase) Register(req dto.AgentRegisterRequest) (*dto.AgentReg
 	return nil, args.Error(1)
 }
 
+func (m *MockAgentUsecase) Heartbeat(req dto.AgentHeartbeatRequest) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
+
+func (m *MockAgentUsecase) ReportEvent(req dto.AgentEventRequest) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
+
+func (m *MockAgentUsecase) ListEvents(agentID string, limit int) ([]dto.AgentEvent, error) {
+	args := m.Called(agentID, limit)
+	if args.Get(0) != nil {
+		return args.Get(0).([]dto.AgentEvent), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAgentUsecase) ListWorkers(agentID string) ([]dto.AgentWorker, error) {
+	args := m.Called(agentID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]dto.AgentWorker), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAgentUsecase) CountByAppliedVersion() (map[string]int, error) {
+	args := m.Called()
+	if args.Get(0) != nil {
+		return args.Get(0).(map[string]int), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func TestAgentHandler_Register(t *testing.T) {
 	e := echo.New()
 	log := logger.NewLogger()
@@ -95,3 +130,248 @@ func TestAgentHandler_Register(t *testing.T) {
 		mockUsecase.AssertExpectations(t)
 	})
 }
+
+func TestAgentHandler_Heartbeat(t *testing.T) {
+	e := echo.New()
+	log := logger.NewLogger()
+	mockUsecase := new(MockAgentUsecase)
+
+	h := &AgentHandler{
+		agentUsecase: mockUsecase,
+		logger:       log,
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		reqBody := dto.AgentHeartbeatRequest{AgentID: "agent-1", AppliedVersion: "v1"}
+		bodyBytes, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/heartbeat", bytes.NewBuffer(bodyBytes))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockUsecase.On("Heartbeat", reqBody).Return(nil).Once()
+
+		err := h.Heartbeat(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Missing Agent ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/heartbeat", bytes.NewBufferString(`{"applied_version":"v1"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := h.Heartbeat(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Unknown Agent", func(t *testing.T) {
+		reqBody := dto.AgentHeartbeatRequest{AgentID: "missing"}
+		bodyBytes, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/heartbeat", bytes.NewBuffer(bodyBytes))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockUsecase.On("Heartbeat", reqBody).Return(gorm.ErrRecordNotFound).Once()
+
+		err := h.Heartbeat(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Usecase Error", func(t *testing.T) {
+		reqBody := dto.AgentHeartbeatRequest{AgentID: "agent-1"}
+		bodyBytes, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/heartbeat", bytes.NewBuffer(bodyBytes))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockUsecase.On("Heartbeat", reqBody).Return(errors.New("db error")).Once()
+
+		err := h.Heartbeat(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestAgentHandler_ReportEvent(t *testing.T) {
+	e := echo.New()
+	log := logger.NewLogger()
+	mockUsecase := new(MockAgentUsecase)
+
+	h := &AgentHandler{
+		agentUsecase: mockUsecase,
+		logger:       log,
+	}
+
+	newContext := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPost, "/events", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		return e.NewContext(req, rec), rec
+	}
+	reqBody := dto.AgentEventRequest{AgentID: "agent-1", Type: "drift", DesiredVersion: "v2", ActualVersion: "v1"}
+	body := `{"agent_id":"agent-1","type":"drift","desired_version":"v2","actual_version":"v1"}`
+
+	t.Run("Success", func(t *testing.T) {
+		c, rec := newContext(body)
+		mockUsecase.On("ReportEvent", reqBody).Return(nil).Once()
+
+		err := h.ReportEvent(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Missing Type", func(t *testing.T) {
+		c, rec := newContext(`{"agent_id":"agent-1"}`)
+
+		err := h.ReportEvent(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Unknown Agent", func(t *testing.T) {
+		c, rec := newContext(body)
+		mockUsecase.On("ReportEvent", reqBody).Return(gorm.ErrRecordNotFound).Once()
+
+		err := h.ReportEvent(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Usecase Error", func(t *testing.T) {
+		c, rec := newContext(body)
+		mockUsecase.On("ReportEvent", reqBody).Return(errors.New("db error")).Once()
+
+		err := h.ReportEvent(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestAgentHandler_ListEvents(t *testing.T) {
+	e := echo.New()
+	log := logger.NewLogger()
+	mockUsecase := new(MockAgentUsecase)
+
+	h := &AgentHandler{
+		agentUsecase: mockUsecase,
+		logger:       log,
+	}
+
+	newContext := func(query string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/agents/agent-1/events"+query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("agent-1")
+		return c, rec
+	}
+
+	t.Run("Default Limit", func(t *testing.T) {
+		c, rec := newContext("")
+		mockUsecase.On("ListEvents", "agent-1", 50).Return([]dto.AgentEvent{{ID: 1, AgentID: "agent-1", Type: "drift"}}, nil).Once()
+
+		err := h.ListEvents(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var res dto.AgentEventsResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Len(t, res.Events, 1)
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Custom Limit", func(t *testing.T) {
+		c, rec := newContext("?limit=5")
+		mockUsecase.On("ListEvents", "agent-1", 5).Return([]dto.AgentEvent{}, nil).Once()
+
+		err := h.ListEvents(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Limit", func(t *testing.T) {
+		for _, query := range []string{"?limit=abc", "?limit=0", "?limit=501"} {
+			c, rec := newContext(query)
+
+			err := h.ListEvents(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("Usecase Error", func(t *testing.T) {
+		c, rec := newContext("")
+		mockUsecase.On("ListEvents", "agent-1", 50).Return(nil, errors.New("db error")).Once()
+
+		err := h.ListEvents(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestAgentHandler_ListWorkers(t *testing.T) {
+	e := echo.New()
+	log := logger.NewLogger()
+	mockUsecase := new(MockAgentUsecase)
+
+	h := &AgentHandler{
+		agentUsecase: mockUsecase,
+		logger:       log,
+	}
+
+	newContext := func() (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/agents/agent-1/workers", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("agent-1")
+		return c, rec
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		c, rec := newContext()
+		mockUsecase.On("ListWorkers", "agent-1").Return([]dto.AgentWorker{
+			{URL: "http://worker-a:8082", AppliedVersion: "v1"},
+		}, nil).Once()
+
+		err := h.ListWorkers(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var res dto.AgentWorkersResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Len(t, res.Workers, 1)
+		assert.Equal(t, "v1", res.Workers[0].AppliedVersion)
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Usecase Error", func(t *testing.T) {
+		c, rec := newContext()
+		mockUsecase.On("ListWorkers", "agent-1").Return(nil, errors.New("db error")).Once()
+
+		err := h.ListWorkers(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
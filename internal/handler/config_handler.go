@@ -3,25 +3,40 @@ package handler
 import (
 	"config-manager/internal/dto"
 	"config-manager/internal/usecase"
+	"config-manager/pkg/shared/middleware"
+	"config-manager/pkg/shared/tracing"
+	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 type ConfigHandler struct {
-	configUsecase usecase.ConfigUsecase
-	logger        *slog.Logger
+	configUsecase   usecase.ConfigUsecase
+	configValidator usecase.ConfigValidator
+	metrics         *ControllerMetrics
+	logger          *slog.Logger
 }
 
-func NewConfigHandler(e *echo.Group, configUsecase usecase.ConfigUsecase, logger *slog.Logger) {
+// NewConfigHandler registers the config routes. writeMiddleware is applied only to
+// routes that modify config, e.g. to forward them to the elected leader.
+// Pinning decides what retention deletes and validation fans out to agents and
+// workers, so both require adminToken.
+func NewConfigHandler(e *echo.Group, configUsecase usecase.ConfigUsecase, configValidator usecase.ConfigValidator, metrics *ControllerMetrics, logger *slog.Logger, adminToken string, writeMiddleware ...echo.MiddlewareFunc) {
 	handler := &ConfigHandler{
-		configUsecase: configUsecase,
-		logger:        logger,
+		configUsecase:   configUsecase,
+		configValidator: configValidator,
+		metrics:         metrics,
+		logger:          logger,
 	}
 
-	e.POST("/config", handler.SaveConfig) // Requires admin auth
-	e.GET("/config", handler.GetConfig)   // Requires agent auth
+	e.POST("/config", handler.SaveConfig, writeMiddleware...) // Requires admin auth
+	e.GET("/config", handler.GetConfig)                       // Requires agent auth
+	pinMiddleware := append([]echo.MiddlewareFunc{middleware.StaticTokenAuth("Authorization", adminToken)}, writeMiddleware...)
+	e.PUT("/config/:version/pin", handler.PinConfig, pinMiddleware...)
+	e.POST("/config/validate", handler.ValidateConfig, middleware.StaticTokenAuth("Authorization", adminToken)) // Saves nothing, any replica answers
 }
 
 // SaveConfig godoc
@@ -47,7 +62,9 @@ func (h *ConfigHandler) SaveConfig(c echo.Context) error {
 		})
 	}
 
-	if err := h.configUsecase.Save(req); err != nil {
+	version, err := h.configUsecase.Save(req)
+	h.metrics.configSaved(err)
+	if err != nil {
 		h.logger.Error("failed to save config", "error", err.Error(), "request_id", reqID)
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error":      err.Error(),
@@ -56,8 +73,10 @@ func (h *ConfigHandler) SaveConfig(c echo.Context) error {
 		})
 	}
 
+	tracing.SetAttributes(c.Request().Context(), tracing.AttrConfigVersion.String(version))
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message":    "success",
+		"version":    version,
 		"code":       http.StatusOK,
 		"request_id": reqID,
 	})
@@ -73,7 +92,7 @@ func (h *ConfigHandler) SaveConfig(c echo.Context) error {
 // @Router /config [get]
 func (h *ConfigHandler) GetConfig(c echo.Context) error {
 	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
-	res, err := h.configUsecase.GetLatest()
+	res, err := h.configUsecase.GetLatestEncoded()
 	if err != nil {
 		h.logger.Error("failed to get config", "error", err.Error(), "request_id", reqID)
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
@@ -83,7 +102,94 @@ func (h *ConfigHandler) GetConfig(c echo.Context) error {
 		})
 	}
 
+	tracing.SetAttributes(c.Request().Context(), tracing.AttrConfigVersion.String(res.Version))
 	c.Response().Header().Set("ETag", res.Version)
+	return c.JSONBlob(http.StatusOK, res.ResponseBody(reqID))
+}
+
+// PinConfig godoc
+// @Summary Pin or unpin a config version
+// @Description Pinned versions are never removed by the retention job
+// @Tags Config
+// @Accept json
+// @Produce json
+// @Param version path string true "Config version"
+// @Param req body dto.ConfigPinRequest true "Pin state"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /config/{version}/pin [put]
+func (h *ConfigHandler) PinConfig(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	version := c.Param("version")
+	var req dto.ConfigPinRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("failed to bind request", "error", err.Error(), "request_id", reqID)
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       http.StatusBadRequest,
+			"request_id": reqID,
+		})
+	}
+
+	if err := h.configUsecase.SetPinned(version, req.Pinned); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		h.logger.Error("failed to pin config", "error", err.Error(), "version", version, "request_id", reqID)
+		return c.JSON(status, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       status,
+			"request_id": reqID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":    "success",
+		"version":    version,
+		"pinned":     req.Pinned,
+		"code":       http.StatusOK,
+		"request_id": reqID,
+	})
+}
+
+// ValidateConfig godoc
+// @Summary Validate a config without saving it
+// @Description Check a candidate config and, when agents is set, have the workers of that many agents check it too
+// @Tags Config
+// @Accept json
+// @Produce json
+// @Param req body dto.ConfigValidateRequest true "Candidate Configuration"
+// @Success 200 {object} dto.ConfigValidateResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /config/validate [post]
+func (h *ConfigHandler) ValidateConfig(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	var req dto.ConfigValidateRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("failed to bind request", "error", err.Error(), "request_id", reqID)
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       http.StatusBadRequest,
+			"request_id": reqID,
+		})
+	}
+
+	res, err := h.configValidator.Validate(c.Request().Context(), req)
+	if err != nil {
+		h.logger.Error("failed to validate config", "error", err.Error(), "request_id", reqID)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       http.StatusInternalServerError,
+			"request_id": reqID,
+		})
+	}
+
 	res.Code = http.StatusOK
 	res.RequestID = reqID
 	return c.JSON(http.StatusOK, res)
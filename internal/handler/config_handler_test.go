@@ -4,15 +4,27 @@ import (
 	"bytes"
 	"config-manager/internal/dto"
 	"config-manager/internal/logger"
+	"config-manager/internal/migration"
+	"config-manager/internal/repository"
+	"config-manager/internal/usecase"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 // MockConfigUsecase is a mock for the ConfigUsecase interface
@@ -20,9 +32,9 @@ type MockConfigUsecase struct {
 	mock.Mock
 }
 
-func (m *MockConfigUsecase) Save(req dto.ConfigRequest) error {
+func (m *MockConfigUsecase) Save(req dto.ConfigRequest) (string, error) {
 	args := m.Called(req)
-	return args.Error(0)
+	return args.String(0), args.Error(1)
 }
 
 func (m *MockConfigUsecase) GetLatest() (*dto.ConfigResponse, error) {
@@ -33,6 +45,32 @@ func (m *MockConfigUsecase) GetLatest() (*dto.ConfigResponse, error) {
 	return nil, args.Error(1)
 }
 
+func (m *MockConfigUsecase) GetLatestEncoded() (*usecase.EncodedConfig, error) {
+	args := m.Called()
+	if args.Get(0) != nil {
+		return args.Get(0).(*usecase.EncodedConfig), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockConfigUsecase) SetPinned(version string, pinned bool) error {
+	args := m.Called(version, pinned)
+	return args.Error(0)
+}
+
+// MockConfigValidator is a mock for the ConfigValidator interface
+type MockConfigValidator struct {
+	mock.Mock
+}
+
+func (m *MockConfigValidator) Validate(ctx context.Context, req dto.ConfigValidateRequest) (*dto.ConfigValidateResponse, error) {
+	args := m.Called(req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*dto.ConfigValidateResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func TestConfigHandler_SaveConfig(t *testing.T) {
 	e := echo.New()
 	log := logger.NewLogger()
@@ -47,12 +85,13 @@ func TestConfigHandler_SaveConfig(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		mockUsecase.On("Save", reqBody).Return(nil).Once()
+		mockUsecase.On("Save", reqBody).Return("v1", nil).Once()
 
 		err := h.SaveConfig(c)
 
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"version":"v1"`)
 		mockUsecase.AssertExpectations(t)
 	})
 
@@ -76,7 +115,7 @@ func TestConfigHandler_SaveConfig(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		mockUsecase.On("Save", reqBody).Return(errors.New("db error")).Once()
+		mockUsecase.On("Save", reqBody).Return("", errors.New("db error")).Once()
 
 		err := h.SaveConfig(c)
 
@@ -96,18 +135,23 @@ func TestConfigHandler_GetConfig(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/config", nil)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
+		c.Response().Header().Set(echo.HeaderXRequestID, "req-1")
 
-		expectedResp := &dto.ConfigResponse{
-			Config:  map[string]interface{}{"key": "value"},
-			Version: "123",
-		}
-		mockUsecase.On("GetLatest").Return(expectedResp, nil).Once()
+		encoded := usecase.NewEncodedConfig("123", json.RawMessage(`{"key":"value"}`))
+		mockUsecase.On("GetLatestEncoded").Return(encoded, nil).Once()
 
 		err := h.GetConfig(c)
 
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, "123", rec.Header().Get("ETag"))
+
+		var res dto.ConfigResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, "123", res.Version)
+		assert.Equal(t, "value", res.Config["key"])
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "req-1", res.RequestID)
 		mockUsecase.AssertExpectations(t)
 	})
 
@@ -116,7 +160,7 @@ func TestConfigHandler_GetConfig(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		mockUsecase.On("GetLatest").Return(nil, errors.New("db error")).Once()
+		mockUsecase.On("GetLatestEncoded").Return(nil, errors.New("db error")).Once()
 
 		err := h.GetConfig(c)
 
@@ -125,3 +169,212 @@ func TestConfigHandler_GetConfig(t *testing.T) {
 		mockUsecase.AssertExpectations(t)
 	})
 }
+
+func TestConfigHandler_PinConfig(t *testing.T) {
+	e := echo.New()
+	log := logger.NewLogger()
+	mockUsecase := new(MockConfigUsecase)
+	h := &ConfigHandler{configUsecase: mockUsecase, logger: log}
+
+	newContext := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPut, "/config/v1/pin", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("version")
+		c.SetParamValues("v1")
+		return c, rec
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		c, rec := newContext(`{"pinned":true}`)
+		mockUsecase.On("SetPinned", "v1", true).Return(nil).Once()
+
+		err := h.PinConfig(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"version":"v1"`)
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Bind Error", func(t *testing.T) {
+		c, rec := newContext(`{invalid_json}`)
+
+		err := h.PinConfig(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Version Not Found", func(t *testing.T) {
+		c, rec := newContext(`{"pinned":false}`)
+		mockUsecase.On("SetPinned", "v1", false).Return(gorm.ErrRecordNotFound).Once()
+
+		err := h.PinConfig(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Usecase Error", func(t *testing.T) {
+		c, rec := newContext(`{"pinned":true}`)
+		mockUsecase.On("SetPinned", "v1", true).Return(errors.New("db error")).Once()
+
+		err := h.PinConfig(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestConfigHandler_ValidateConfig(t *testing.T) {
+	e := echo.New()
+	mockValidator := new(MockConfigValidator)
+	h := &ConfigHandler{configValidator: mockValidator, logger: logger.NewLogger()}
+
+	newContext := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPost, "/config/validate", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		return e.NewContext(req, rec), rec
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		c, rec := newContext(`{"config":{"url":"http://example.com"},"agents":2}`)
+		mockValidator.On("Validate", dto.ConfigValidateRequest{Config: map[string]interface{}{"url": "http://example.com"}, Agents: 2}).Return(&dto.ConfigValidateResponse{
+			ConfigValidation: dto.ConfigValidation{Issues: []dto.ConfigIssue{}},
+			Agents:           []dto.AgentConfigValidation{{AgentID: "agent-1", Error: "connection refused"}},
+		}, nil).Once()
+
+		assert.NoError(t, h.ValidateConfig(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var res dto.ConfigValidateResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.False(t, res.Valid)
+		assert.False(t, res.Complete)
+		assert.Contains(t, rec.Body.String(), `"complete":false`)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "connection refused", res.Agents[0].Error)
+		mockValidator.AssertExpectations(t)
+	})
+
+	t.Run("Bind Error", func(t *testing.T) {
+		c, rec := newContext(`{invalid_json}`)
+
+		assert.NoError(t, h.ValidateConfig(c))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Validator Error", func(t *testing.T) {
+		c, rec := newContext(`{"config":{},"agents":1}`)
+		mockValidator.On("Validate", mock.Anything).Return(nil, errors.New("db error")).Once()
+
+		assert.NoError(t, h.ValidateConfig(c))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("Requires Admin Token", func(t *testing.T) {
+		validator := new(MockConfigValidator)
+		e := echo.New()
+		NewConfigHandler(e.Group("/v1"), nil, validator, nil, logger.NewLogger(), "admin-secret")
+
+		for _, token := range []string{"", "agent-secret"} {
+			req := httptest.NewRequest(http.MethodPost, "/v1/config/validate", bytes.NewBufferString(`{"config":{},"agents":1}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("Authorization", token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		}
+		validator.AssertNotCalled(t, "Validate", mock.Anything)
+	})
+
+	t.Run("Pin Requires Admin Token", func(t *testing.T) {
+		configUsecase := new(MockConfigUsecase)
+		var forwarded []string
+		forward := func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				forwarded = append(forwarded, c.Request().Header.Get("Authorization"))
+				return next(c)
+			}
+		}
+		e := echo.New()
+		NewConfigHandler(e.Group("/v1"), configUsecase, nil, nil, logger.NewLogger(), "admin-secret", forward)
+		configUsecase.On("SetPinned", "v1", true).Return(nil).Once()
+
+		pin := func(token string) int {
+			req := httptest.NewRequest(http.MethodPut, "/v1/config/v1/pin", bytes.NewBufferString(`{"pinned":true}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("Authorization", token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec.Code
+		}
+
+		assert.Equal(t, http.StatusUnauthorized, pin(""))
+		assert.Equal(t, http.StatusUnauthorized, pin("agent-secret"))
+		assert.Equal(t, http.StatusOK, pin("admin-secret"))
+		assert.Equal(t, []string{"admin-secret"}, forwarded, "only authorized pins reach the write middleware")
+		configUsecase.AssertExpectations(t)
+	})
+}
+
+// BenchmarkConfigHandler_GetConfig measures agent polls end to end through echo
+// against SQLite, with and without the latest config cache.
+func BenchmarkConfigHandler_GetConfig(b *testing.B) {
+	db, err := gorm.Open(sqlite.Open("file:"+b.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: gormlogger.Discard,
+	})
+	if err != nil {
+		b.Fatalf("Failed to open test database: %v", err)
+	}
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		b.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		b.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	configRepo := repository.NewConfigRepository(db)
+	base := usecase.NewConfigUsecase(configRepo)
+
+	// A realistic history and payload size
+	payload := map[string]interface{}{"url": "http://example.com"}
+	for i := 0; i < 50; i++ {
+		payload[fmt.Sprintf("key_%d", i)] = strings.Repeat("x", 64)
+	}
+	for i := 0; i < 200; i++ {
+		if _, err := base.Save(dto.ConfigRequest{Config: payload}); err != nil {
+			b.Fatalf("Failed to seed config: %v", err)
+		}
+	}
+
+	log := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	run := func(b *testing.B, configUsecase usecase.ConfigUsecase) {
+		e := echo.New()
+		NewConfigHandler(e.Group("/v1"), configUsecase, nil, nil, log, "admin-secret")
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/config", nil))
+				if rec.Code != http.StatusOK {
+					b.Fatalf("unexpected status %d", rec.Code)
+				}
+			}
+		})
+		b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "req/s")
+	}
+
+	b.Run("Uncached", func(b *testing.B) {
+		run(b, base)
+	})
+
+	b.Run("Cached", func(b *testing.B) {
+		run(b, usecase.NewCachedConfigUsecase(base, time.Minute))
+	})
+}
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"config-manager/internal/usecase"
+	"config-manager/pkg/shared/metrics"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+// ControllerMetrics instruments config saves; registered agents are counted
+// from the database on every scrape. A nil *ControllerMetrics records nothing.
+type ControllerMetrics struct {
+	configSaves *prometheus.CounterVec
+}
+
+func NewControllerMetrics(reg prometheus.Registerer, agentUsecase usecase.AgentUsecase) *ControllerMetrics {
+	m := &ControllerMetrics{
+		configSaves: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Name:      "controller_config_saves_total",
+			Help:      "Config saves, by result.",
+		}, []string{"result"}),
+	}
+	reg.MustRegister(m.configSaves, &agentCollector{
+		agentUsecase: agentUsecase,
+		registered: prometheus.NewDesc(metrics.Namespace+"_controller_registered_agents",
+			"Agents registered with the controller.", nil, nil),
+		byVersion: prometheus.NewDesc(metrics.Namespace+"_controller_agents_by_applied_version",
+			"Agents per config version applied to all of their workers.", []string{"version"}, nil),
+	})
+	return m
+}
+
+func (m *ControllerMetrics) configSaved(err error) {
+	if m == nil {
+		return
+	}
+	m.configSaves.WithLabelValues(result(err)).Inc()
+}
+
+// agentCollector reads the agent counts from the database at scrape time, so
+// every controller replica reports the same numbers.
+type agentCollector struct {
+	agentUsecase usecase.AgentUsecase
+	registered   *prometheus.Desc
+	byVersion    *prometheus.Desc
+}
+
+func (c *agentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.registered
+	ch <- c.byVersion
+}
+
+func (c *agentCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.agentUsecase.CountByAppliedVersion()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.registered, err)
+		return
+	}
+
+	total := 0
+	for version, count := range counts {
+		total += count
+		if version == "" {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.byVersion, prometheus.GaugeValue, float64(count), version)
+	}
+	ch <- prometheus.MustNewConstMetric(c.registered, prometheus.GaugeValue, float64(total))
+}
+
+// AgentMetrics instruments the ControllerPoller. A nil *AgentMetrics records nothing.
+type AgentMetrics struct {
+	polls        *prometheus.CounterVec
+	pollRetries  prometheus.Counter
+	pushDuration *prometheus.HistogramVec
+	pushFailures *prometheus.CounterVec
+}
+
+func NewAgentMetrics(reg prometheus.Registerer) *AgentMetrics {
+	m := &AgentMetrics{
+		polls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Name:      "agent_polls_total",
+			Help:      "Polls of the controller, by result: success, error, bad_status or invalid_response.",
+		}, []string{"result"}),
+		pollRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Name:      "agent_poll_backoff_retries_total",
+			Help:      "Polls retried after backing off from an unreachable controller.",
+		}),
+		pushDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Name:      "agent_push_duration_seconds",
+			Help:      "Duration of config pushes to workers, by result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+		pushFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Name:      "agent_push_failures_total",
+			Help:      "Failed config push attempts, by worker.",
+		}, []string{"worker"}),
+	}
+	reg.MustRegister(m.polls, m.pollRetries, m.pushDuration, m.pushFailures)
+	return m
+}
+
+func (m *AgentMetrics) polled(result string) {
+	if m == nil {
+		return
+	}
+	m.polls.WithLabelValues(result).Inc()
+}
+
+func (m *AgentMetrics) pollRetried() {
+	if m == nil {
+		return
+	}
+	m.pollRetries.Inc()
+}
+
+func (m *AgentMetrics) pushed(workerURL string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.pushDuration.WithLabelValues(result(err)).Observe(duration.Seconds())
+	if err != nil {
+		m.pushFailures.WithLabelValues(workerURL).Inc()
+	}
+}
+
+// WorkerMetrics instruments the worker's /hit proxy and applied config; circuit
+// breaker states are read from the config manager on every scrape. A nil
+// *WorkerMetrics records nothing.
+type WorkerMetrics struct {
+	hitDuration       *prometheus.HistogramVec
+	upstreamResponses *prometheus.CounterVec
+	configInfo        *prometheus.GaugeVec
+}
+
+func NewWorkerMetrics(reg prometheus.Registerer, configManager usecase.ConfigManager) *WorkerMetrics {
+	m := &WorkerMetrics{
+		hitDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Name:      "worker_hit_duration_seconds",
+			Help:      "Latency of /hit requests including the upstream call, by result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+		upstreamResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Name:      "worker_upstream_responses_total",
+			Help:      "Upstream responses received by /hit, by status code.",
+		}, []string{"code"}),
+		configInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Name:      "worker_config_info",
+			Help:      "Always 1, labelled with the config version the worker runs.",
+		}, []string{"version"}),
+	}
+	reg.MustRegister(m.hitDuration, m.upstreamResponses, m.configInfo, &circuitCollector{
+		configManager: configManager,
+		state: prometheus.NewDesc(metrics.Namespace+"_worker_circuit_breaker_state",
+			"1 for the current circuit breaker state of each action, by path and state.", []string{"path", "state"}, nil),
+	})
+	return m
+}
+
+// hit records a /hit request; statusCode is 0 when the upstream did not answer.
+func (m *WorkerMetrics) hit(duration time.Duration, statusCode int, err error) {
+	if m == nil {
+		return
+	}
+	m.hitDuration.WithLabelValues(result(err)).Observe(duration.Seconds())
+	if statusCode != 0 {
+		m.upstreamResponses.WithLabelValues(strconv.Itoa(statusCode)).Inc()
+	}
+}
+
+func (m *WorkerMetrics) configApplied(version string) {
+	if m == nil {
+		return
+	}
+	m.configInfo.Reset()
+	m.configInfo.WithLabelValues(version).Set(1)
+}
+
+// circuitCollector reports every state of each circuit breaker, 1 for the
+// current one, so alerts can match on state="open".
+type circuitCollector struct {
+	configManager usecase.ConfigManager
+	state         *prometheus.Desc
+}
+
+func (c *circuitCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.state
+}
+
+func (c *circuitCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, action := range c.configManager.ListActions() {
+		if action.Circuit == "" {
+			continue
+		}
+		for _, state := range []string{usecase.CircuitClosed, usecase.CircuitOpen, usecase.CircuitHalfOpen} {
+			value := 0.0
+			if state == action.Circuit {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, value, action.Path, state)
+		}
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return resultError
+	}
+	return resultSuccess
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"bytes"
+	"config-manager/configs"
+	"config-manager/internal/dto"
+	"config-manager/internal/logger"
+	"config-manager/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestControllerMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	agentUsecase := new(MockAgentUsecase)
+	configUsecase := new(MockConfigUsecase)
+	m := NewControllerMetrics(reg, agentUsecase)
+	h := &ConfigHandler{configUsecase: configUsecase, metrics: m, logger: logger.NewLogger()}
+
+	t.Run("Config Saves", func(t *testing.T) {
+		e := echo.New()
+		configUsecase.On("Save", mock.Anything).Return("v1", nil).Once()
+		configUsecase.On("Save", mock.Anything).Return("", errors.New("db error")).Once()
+
+		for range 2 {
+			req := httptest.NewRequest(http.MethodPost, "/v1/config", strings.NewReader(`{"config":{}}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			assert.NoError(t, h.SaveConfig(e.NewContext(req, httptest.NewRecorder())))
+		}
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.configSaves.WithLabelValues(resultSuccess)))
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.configSaves.WithLabelValues(resultError)))
+	})
+
+	t.Run("Agents By Applied Version", func(t *testing.T) {
+		agentUsecase.On("CountByAppliedVersion").Return(map[string]int{"v1": 2, "v2": 1, "": 1}, nil).Once()
+
+		expected := `
+# HELP config_manager_controller_agents_by_applied_version Agents per config version applied to all of their workers.
+# TYPE config_manager_controller_agents_by_applied_version gauge
+config_manager_controller_agents_by_applied_version{version="v1"} 2
+config_manager_controller_agents_by_applied_version{version="v2"} 1
+# HELP config_manager_controller_registered_agents Agents registered with the controller.
+# TYPE config_manager_controller_registered_agents gauge
+config_manager_controller_registered_agents 4
+`
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+			"config_manager_controller_agents_by_applied_version", "config_manager_controller_registered_agents"))
+	})
+
+	t.Run("Database Error Fails The Scrape", func(t *testing.T) {
+		agentUsecase.On("CountByAppliedVersion").Return(nil, errors.New("database is closed")).Once()
+
+		_, err := reg.Gather()
+		assert.ErrorContains(t, err, "database is closed")
+	})
+}
+
+func TestAgentMetrics_Push(t *testing.T) {
+	m := NewAgentMetrics(prometheus.NewRegistry())
+	mockManager := new(MockAgentManager)
+	poller := NewControllerPoller(&configs.Config{}, mockManager, nil, nil, m, logger.NewLogger())
+	push := poller.pushFunc(testWorkerURL)
+
+	req := dto.ConfigRequest{Version: "v1"}
+	mockManager.On("PushToWorker", testWorkerURL, req).Return(nil).Once()
+	mockManager.On("PushToWorker", testWorkerURL, req).Return(errors.New("connection refused")).Once()
+
+	assert.NoError(t, push(t.Context(), req))
+	assert.Error(t, push(t.Context(), req))
+
+	assert.Equal(t, 2, testutil.CollectAndCount(m.pushDuration))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.pushFailures.WithLabelValues(testWorkerURL)))
+}
+
+func TestWorkerMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	mockManager := new(MockConfigManager)
+	m := NewWorkerMetrics(reg, mockManager)
+	mockManager.On("GetConfig").Return(dto.WorkerState{Version: "v1"}).Once()
+
+	e := echo.New()
+	NewWorkerHandler(e, mockManager, m, logger.NewLogger())
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.configInfo.WithLabelValues("v1")))
+
+	t.Run("Config Version Info", func(t *testing.T) {
+		req := dto.ConfigRequest{Version: "v2", Config: map[string]interface{}{}}
+		mockManager.On("UpdateConfig", req).Return(nil).Once()
+		body, _ := json.Marshal(req)
+		httpReq := httptest.NewRequest(http.MethodPost, "/v1/config", bytes.NewReader(body))
+		httpReq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		e.ServeHTTP(httptest.NewRecorder(), httpReq)
+
+		// Only the running version is reported
+		assert.Equal(t, 1, testutil.CollectAndCount(m.configInfo))
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.configInfo.WithLabelValues("v2")))
+	})
+
+	t.Run("Hit Upstream Status", func(t *testing.T) {
+		mockManager.On("ExecuteHit", mock.Anything).Return(&dto.HitResult{StatusCode: http.StatusBadGateway}, nil).Once()
+		mockManager.On("ExecuteHit", mock.Anything).Return(nil, errors.New("URL not configured")).Once()
+		for range 2 {
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hit", nil))
+		}
+
+		assert.Equal(t, 2, testutil.CollectAndCount(m.hitDuration))
+		assert.Equal(t, 1, testutil.CollectAndCount(m.upstreamResponses))
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.upstreamResponses.WithLabelValues("502")))
+	})
+
+	t.Run("Circuit Breaker State", func(t *testing.T) {
+		mockManager.On("ListActions").Return([]dto.HitActionInfo{
+			{Path: "/hit", Method: http.MethodGet, URL: "http://upstream"},
+			{Name: "create", Path: "/hit/create", Method: http.MethodPost, URL: "http://upstream", Circuit: usecase.CircuitOpen},
+		}).Once()
+
+		expected := `
+# HELP config_manager_worker_circuit_breaker_state 1 for the current circuit breaker state of each action, by path and state.
+# TYPE config_manager_worker_circuit_breaker_state gauge
+config_manager_worker_circuit_breaker_state{path="/hit/create",state="closed"} 0
+config_manager_worker_circuit_breaker_state{path="/hit/create",state="half_open"} 0
+config_manager_worker_circuit_breaker_state{path="/hit/create",state="open"} 1
+`
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "config_manager_worker_circuit_breaker_state"))
+	})
+}
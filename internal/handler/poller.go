@@ -3,106 +3,707 @@ package handler
 import (
 	"config-manager/configs"
 	"config-manager/internal/usecase"
+	"config-manager/pkg/shared/tracing"
 
+	"config-manager/internal/domain"
 	"config-manager/internal/dto"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math"
 	"net/http"
+	"slices"
+	"sort"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	statusSourceCache      = "cache"
+	statusSourceController = "controller"
 )
 
+// Poll results besides resultSuccess and resultError
+const (
+	pollResultBadStatus       = "bad_status"
+	pollResultInvalidResponse = "invalid_response"
+)
+
+const defaultPushConcurrency = 4
+
+var ErrAgentCacheDisabled = errors.New("agent cache is disabled")
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
 type ControllerPoller struct {
 	cfg          *configs.Config
 	agentManager usecase.AgentManager
+	cache        usecase.AgentCache // optional, nil disables the offline fallback
+	discovery    usecase.WorkerDiscovery
+	metrics      *AgentMetrics // optional, nil records nothing
+	concurrency  int           // max pushes and drift checks in flight across workers
+	pushSlots    chan struct{} // shared by every worker's pusher to bound concurrency
 	httpClient   *http.Client
-	agentID      string
 	pollURL      string
-	versionCache string
 	pollInterval time.Duration
 	logger       *slog.Logger
+
+	refreshMu sync.Mutex    // serializes RefreshWorkers so results apply in order
+	resync    chan struct{} // wakes the poll loop for an immediate, forced poll
+
+	mu             sync.RWMutex
+	runCtx         context.Context // cancelled on shutdown, used by background pushes and reports
+	agentID        string
+	validateToken  string // issued at register, accepted by the validate endpoint
+	versionCache   string
+	appliedVersion string // last version applied to every worker
+	desired        *dto.ConfigRequest
+	desiredSpan    trace.SpanContext // span that fetched desired, parent of its pushes
+	workers        map[string]*workerTarget
+	stale          bool
+	source         string
+	lastSyncAt     time.Time
+	registered     bool
+	lastPollAt     time.Time
+	lastPollError  string
+	pollFailures   int
+	nextPollAt     time.Time
+	forceResync    bool
+}
+
+// workerTarget is a worker managed by the agent; fields are guarded by the poller's mu.
+type workerTarget struct {
+	url            string
+	pusher         usecase.WorkerPusher
+	appliedVersion string
 }
 
-func NewControllerPoller(cfg *configs.Config, agentManager usecase.AgentManager, logger *slog.Logger) *ControllerPoller {
+// NewControllerPoller returns a poller pushing to the workers found by discovery,
+// or to cfg.WorkerURL when discovery is nil.
+func NewControllerPoller(cfg *configs.Config, agentManager usecase.AgentManager, cache usecase.AgentCache, discovery usecase.WorkerDiscovery, metrics *AgentMetrics, logger *slog.Logger) *ControllerPoller {
+	if discovery == nil {
+		discovery = usecase.NewStaticDiscovery([]string{cfg.WorkerURL})
+	}
+	concurrency := cfg.PushConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultPushConcurrency
+	}
 	return &ControllerPoller{
 		cfg:          cfg,
 		agentManager: agentManager,
-		httpClient:   &http.Client{Timeout: 5 * time.Second},
+		cache:        cache,
+		discovery:    discovery,
+		metrics:      metrics,
+		concurrency:  concurrency,
+		pushSlots:    make(chan struct{}, concurrency),
+		httpClient:   &http.Client{Timeout: 5 * time.Second, Transport: tracing.NewTransport(nil)},
 		logger:       logger,
+		workers:      make(map[string]*workerTarget),
+		resync:       make(chan struct{}, 1),
+		runCtx:       context.Background(),
+	}
+}
+
+// Status reports the version the agent is serving and whether it may be stale.
+func (p *ControllerPoller) Status() dto.AgentStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := dto.AgentStatus{
+		AgentID:        p.agentID,
+		Version:        p.versionCache,
+		AppliedVersion: p.appliedVersion,
+		Stale:          p.stale,
+		Source:         p.source,
+		Registered:     p.registered,
+		Poll: dto.PollStatus{
+			IntervalSeconds:     int(p.pollInterval / time.Second),
+			LastPollAt:          timePtr(p.lastPollAt),
+			LastError:           p.lastPollError,
+			ConsecutiveFailures: p.pollFailures,
+			NextPollAt:          timePtr(p.nextPollAt),
+		},
+		Workers: make([]dto.AgentWorkerStatus, 0, len(p.workers)),
+	}
+	status.LastSyncAt = timePtr(p.lastSyncAt)
+	for _, w := range p.sortedWorkers() {
+		status.Workers = append(status.Workers, dto.AgentWorkerStatus{
+			URL:            w.url,
+			AppliedVersion: w.appliedVersion,
+			Push:           w.pusher.Status(),
+		})
 	}
+	return status
 }
 
-func (p *ControllerPoller) Start() {
+// Start serves the cached config, registers with the controller and polls it
+// until ctx is cancelled. Pending pushes are cancelled before it returns.
+func (p *ControllerPoller) Start(ctx context.Context) {
 	p.logger.Info("Starting Agent Controller Poller...")
 
+	p.mu.Lock()
+	p.runCtx = ctx
+	p.mu.Unlock()
+	defer p.stopWorkers()
+
+	// 0. Serve the cached config straight away, the controller may be unreachable
+	p.RefreshWorkers()
+	p.restoreFromCache()
+
 	// 1. Register with controller (with exp backoff if needed, kept simple here to match test)
 	var regResp *dto.AgentRegisterResponse
 	var err error
 	for {
-		regResp, err = p.agentManager.Register()
+		regResp, err = p.register(ctx)
 		if err == nil {
 			break
 		}
 		p.logger.Error("Failed to register agent", "error", err)
-		time.Sleep(5 * time.Second)
+		if !waitFor(ctx, 5*time.Second) {
+			return
+		}
 	}
 
+	p.mu.Lock()
 	p.agentID = regResp.AgentID
+	p.validateToken = regResp.ValidateToken
+	p.registered = true
 	p.pollURL = regResp.PollURL
 	p.pollInterval = time.Duration(regResp.PollIntervalSeconds) * time.Second
+	p.mu.Unlock()
 	p.logger.Info("Successfully registered agent", "agent_id", p.agentID, "poll_interval", p.pollInterval)
 
-	p.pollLoop()
+	p.pollLoop(ctx)
+	p.logger.Info("Agent Controller Poller stopped")
+}
+
+// register registers the agent with the controller once, in its own trace.
+func (p *ControllerPoller) register(ctx context.Context) (*dto.AgentRegisterResponse, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "agent.register")
+	defer span.End()
+
+	regResp, err := p.agentManager.Register(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(tracing.AttrAgentID.String(regResp.AgentID))
+	return regResp, nil
 }
 
-func (p *ControllerPoller) pollLoop() {
+func (p *ControllerPoller) pollLoop(ctx context.Context) {
 	backoffRetries := 0
 
 	for {
-		time.Sleep(p.pollInterval)
+		if !p.sleep(ctx, p.pollInterval) {
+			return
+		}
 
-		url := fmt.Sprintf("%s%s", p.cfg.ControllerURL, p.pollURL)
-		req, _ := http.NewRequest(http.MethodGet, url, nil)
-		req.Header.Set("Authorization", p.cfg.AgentAuthToken) // agent credentials
+		p.RefreshWorkers()
 
-		resp, err := p.httpClient.Do(req)
-		if err != nil {
+		if err := p.poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
 			backoffRetries++
 			backoffTime := time.Duration(math.Pow(2, float64(backoffRetries))) * time.Second
+			p.metrics.pollRetried()
 			p.logger.Error("Failed to poll controller", "error", err, "backoff_time", backoffTime)
-			time.Sleep(backoffTime)
+			if !p.sleep(ctx, backoffTime) {
+				return
+			}
 			continue
 		}
 
 		// Reset backoff on success
 		backoffRetries = 0
+	}
+}
 
-		if resp.StatusCode != http.StatusOK {
-			p.logger.Error("Unexpected status code from controller", "status_code", resp.StatusCode)
-			resp.Body.Close()
-			continue
-		}
+// poll fetches the desired config once, pushes it to the workers when it changed
+// and sends a heartbeat. It only returns an error when the controller is
+// unreachable; other failures are recorded and the next poll retries.
+func (p *ControllerPoller) poll(ctx context.Context) error {
+	ctx, span := tracing.Tracer().Start(ctx, "agent.poll", trace.WithAttributes(tracing.AttrAgentID.String(p.Status().AgentID)))
+	defer span.End()
+	failed := func(reason string) {
+		span.SetStatus(codes.Error, reason)
+		p.pollFailed(reason)
+	}
 
-		var configResp dto.ConfigResponse
-		if err := json.NewDecoder(resp.Body).Decode(&configResp); err != nil {
-			p.logger.Error("Failed to parse config from controller", "error", err)
-			resp.Body.Close()
-			continue
+	url := fmt.Sprintf("%s%s", p.cfg.ControllerURL, p.pollURL)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req.Header.Set("Authorization", p.cfg.AgentAuthToken) // agent credentials
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
 		}
+		p.metrics.polled(resultError)
+		failed(err.Error())
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		p.logger.Error("Unexpected status code from controller", "status_code", resp.StatusCode)
+		p.metrics.polled(pollResultBadStatus)
+		failed(fmt.Sprintf("unexpected status code %d", resp.StatusCode))
 		resp.Body.Close()
+		return nil
+	}
+
+	var configResp dto.ConfigResponse
+	if err := json.NewDecoder(resp.Body).Decode(&configResp); err != nil {
+		p.logger.Error("Failed to parse config from controller", "error", err)
+		p.metrics.polled(pollResultInvalidResponse)
+		failed(err.Error())
+		resp.Body.Close()
+		return nil
+	}
+	resp.Body.Close()
+	p.metrics.polled(resultSuccess)
+
+	p.mu.Lock()
+	// A forced resync pushes to every worker even when the version is unchanged
+	changed := configResp.Version != p.versionCache || p.forceResync
+	p.forceResync = false
+	p.versionCache = configResp.Version
+	p.stale = false
+	p.source = statusSourceController
+	p.lastSyncAt = time.Now()
+	p.lastPollAt = p.lastSyncAt
+	p.lastPollError = ""
+	p.pollFailures = 0
+	p.mu.Unlock()
+	span.SetAttributes(tracing.AttrConfigVersion.String(configResp.Version), attribute.Bool("config.changed", changed))
 
-		// Detect config changes
-		if configResp.Version != p.versionCache {
-			p.logger.Info("Configuration change detected!", "new_version", configResp.Version)
-			p.versionCache = configResp.Version
+	// Detect config changes
+	if changed {
+		p.logger.Info("Configuration change detected!", "new_version", configResp.Version)
 
-			// Push to worker
-			if err := p.agentManager.PushToWorker(dto.ConfigRequest{Config: configResp.Config}); err != nil {
-				p.logger.Error("Failed to push config to worker", "error", err)
-			} else {
-				p.logger.Info("Successfully pushed config to worker")
+		if p.cache != nil {
+			if err := p.cache.Save(configResp.Version, configResp.Config); err != nil {
+				p.logger.Error("Failed to update agent cache", "error", err)
 			}
 		}
+
+		p.pushToWorkers(ctx, configResp.Version, configResp.Config)
+	} else {
+		p.reconcileWorkers(ctx, configResp)
+	}
+
+	// Report applied versions so the controller keeps them during retention
+	if err := p.agentManager.Heartbeat(ctx, p.heartbeat()); err != nil {
+		p.logger.Error("Failed to send heartbeat to controller", "error", err)
+	}
+	return nil
+}
+
+// RefreshWorkers applies the latest discovery result: new workers receive the
+// desired config, pushes to removed workers are cancelled. The current set is
+// kept when discovery fails.
+func (p *ControllerPoller) RefreshWorkers() {
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+
+	urls, err := p.discovery.Workers(p.context())
+	if err != nil {
+		p.logger.Error("Failed to discover workers", "error", err)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		current[url] = true
+		if _, ok := p.workers[url]; ok {
+			continue
+		}
+
+		target := &workerTarget{url: url}
+		target.pusher = usecase.NewWorkerPusher(p.pushFunc(url), usecase.WorkerPusherConfig{
+			InitialBackoff: time.Duration(p.cfg.PushRetryInitial) * time.Second,
+			MaxBackoff:     time.Duration(p.cfg.PushRetryMax) * time.Second,
+			MaxAttempts:    p.cfg.PushMaxAttempts,
+			OnSuccess:      func(req dto.ConfigRequest) { p.onPushed(target, req) },
+			OnDeadLetter:   func(entry dto.PushDeadLetter) { p.onPushDeadLetter(target, entry) },
+		})
+		p.workers[url] = target
+		p.logger.Info("Managing worker", "worker_url", url)
+
+		if p.desired != nil {
+			target.pusher.Submit(*p.desired)
+		}
+	}
+
+	for url, target := range p.workers {
+		if !current[url] {
+			target.pusher.Cancel()
+			delete(p.workers, url)
+			p.logger.Info("Worker removed from discovery", "worker_url", url)
+		}
+	}
+	p.updateAppliedVersion()
+}
+
+// pushFunc pushes to one worker while holding one of the shared push slots.
+func (p *ControllerPoller) pushFunc(url string) func(ctx context.Context, req dto.ConfigRequest) error {
+	return func(ctx context.Context, req dto.ConfigRequest) error {
+		select {
+		case p.pushSlots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		defer func() { <-p.pushSlots }()
+
+		ctx, span := tracing.Tracer().Start(trace.ContextWithSpanContext(ctx, p.pushParent(req.Version)), "agent.push",
+			trace.WithAttributes(tracing.AttrConfigVersion.String(req.Version), tracing.AttrWorkerURL.String(url)))
+		defer span.End()
+
+		start := time.Now()
+		err := p.agentManager.PushToWorker(ctx, url, req)
+		if ctx.Err() == nil {
+			// Pushes aborted by a newer config or shutdown are not failures
+			p.metrics.pushed(url, time.Since(start), err)
+		}
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
+	}
+}
+
+// pushParent returns the span that fetched version, so its pushes, retries
+// included, join the poll's trace.
+func (p *ControllerPoller) pushParent(version string) trace.SpanContext {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.desired == nil || p.desired.Version != version {
+		return trace.SpanContext{}
+	}
+	return p.desiredSpan
+}
+
+// stopWorkers cancels every worker's pending and in-flight push.
+func (p *ControllerPoller) stopWorkers() {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, w := range p.workers {
+		w.pusher.Cancel()
+	}
+}
+
+// ValidateToken returns the token the controller was issued for this agent at
+// register, empty until then.
+func (p *ControllerPoller) ValidateToken() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.validateToken
+}
+
+func (p *ControllerPoller) context() context.Context {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.runCtx
+}
+
+// pushToWorkers hands a config to every worker's pusher, which retry in the
+// background. Pushes are traced as children of the span in ctx.
+func (p *ControllerPoller) pushToWorkers(ctx context.Context, version string, config map[string]interface{}) {
+	req := dto.ConfigRequest{
+		Config:  config,
+		Version: version,
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.desired = &req
+	p.desiredSpan = trace.SpanContextFromContext(ctx)
+	for _, w := range p.workers {
+		w.pusher.Submit(req)
+	}
+}
+
+func (p *ControllerPoller) onPushed(target *workerTarget, req dto.ConfigRequest) {
+	p.mu.Lock()
+	target.appliedVersion = req.Version
+	p.updateAppliedVersion()
+	p.mu.Unlock()
+	p.logger.Info("Successfully pushed config to worker", "worker_url", target.url, "version", req.Version)
+}
+
+// updateAppliedVersion advances the agent's applied version once every worker
+// runs the desired version. Callers must hold mu.
+func (p *ControllerPoller) updateAppliedVersion() {
+	if p.desired == nil || len(p.workers) == 0 {
+		return
+	}
+	for _, w := range p.workers {
+		if w.appliedVersion != p.desired.Version {
+			return
+		}
+	}
+	p.appliedVersion = p.desired.Version
+}
+
+func (p *ControllerPoller) onPushDeadLetter(target *workerTarget, entry dto.PushDeadLetter) {
+	p.logger.Error("Giving up pushing config to worker", "worker_url", target.url, "version", entry.Version, "attempts", entry.Attempts, "error", entry.LastError)
+
+	if err := p.agentManager.ReportEvent(p.context(), dto.AgentEventRequest{
+		AgentID:        p.Status().AgentID,
+		WorkerURL:      target.url,
+		Type:           domain.AgentEventPushDeadLetter,
+		DesiredVersion: entry.Version,
+		Message:        fmt.Sprintf("push failed after %d attempts: %s", entry.Attempts, entry.LastError),
+	}); err != nil {
+		p.logger.Error("Failed to report dead-lettered push to controller", "error", err)
+	}
+}
+
+// heartbeat reports the version applied to every worker and each worker's push result.
+func (p *ControllerPoller) heartbeat() dto.AgentHeartbeatRequest {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	req := dto.AgentHeartbeatRequest{
+		AgentID:        p.agentID,
+		AppliedVersion: p.appliedVersion,
+		Workers:        make([]dto.WorkerHeartbeat, 0, len(p.workers)),
+	}
+	for _, w := range p.sortedWorkers() {
+		push := w.pusher.Status()
+		req.Workers = append(req.Workers, dto.WorkerHeartbeat{
+			URL:            w.url,
+			AppliedVersion: w.appliedVersion,
+			PendingVersion: push.PendingVersion,
+			LastError:      push.LastError,
+		})
+	}
+	return req
+}
+
+// sortedWorkers returns the managed workers ordered by URL. Callers must hold mu.
+func (p *ControllerPoller) sortedWorkers() []*workerTarget {
+	workers := make([]*workerTarget, 0, len(p.workers))
+	for _, w := range p.workers {
+		workers = append(workers, w)
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].url < workers[j].url })
+	return workers
+}
+
+// reconcileWorkers checks every worker for drift, bounded by the push concurrency.
+func (p *ControllerPoller) reconcileWorkers(ctx context.Context, desired dto.ConfigResponse) {
+	p.mu.Lock()
+	if p.desired == nil || p.desired.Version != desired.Version {
+		p.desiredSpan = trace.SpanContextFromContext(ctx)
+	}
+	p.desired = &dto.ConfigRequest{Config: desired.Config, Version: desired.Version}
+	workers := p.sortedWorkers()
+	p.mu.Unlock()
+
+	var g errgroup.Group
+	g.SetLimit(p.concurrency)
+	for _, w := range workers {
+		g.Go(func() error {
+			p.reconcileWorker(ctx, w, desired)
+			return nil
+		})
+	}
+	g.Wait()
+}
+
+// reconcileWorker compares what a worker runs with the desired config and
+// re-pushes on drift, e.g. after a worker restart or a manual POST /v1/config.
+// Drift is reported to the controller as an agent event.
+func (p *ControllerPoller) reconcileWorker(ctx context.Context, target *workerTarget, desired dto.ConfigResponse) {
+	workerConfig, err := p.agentManager.GetWorkerConfig(ctx, target.url)
+	if err != nil {
+		p.logger.Error("Failed to query worker config", "worker_url", target.url, "error", err)
+		return
+	}
+
+	// Workers that do not report a digest are compared on version only
+	digestMatches := workerConfig.Digest == "" || workerConfig.Digest == usecase.ConfigDigest(desired.Config)
+	if workerConfig.Version == desired.Version && digestMatches {
+		return
+	}
+
+	push := target.pusher.Status()
+	if push.PendingVersion == desired.Version {
+		// Already being retried, drift is expected until the push lands
+		return
+	}
+	if slices.ContainsFunc(push.DeadLetters, func(entry dto.PushDeadLetter) bool {
+		return entry.Version == desired.Version && entry.Rejected
+	}) {
+		// The worker refused it, re-pushing every poll would only be refused
+		// again. A resync or the next version tries again. Versions given up on
+		// while the worker was unreachable are repaired now that it answers.
+		return
+	}
+
+	p.logger.Warn("Worker config drifted from desired config", "worker_url", target.url, "desired_version", desired.Version, "worker_version", workerConfig.Version)
+	req := dto.ConfigRequest{
+		Config:  desired.Config,
+		Version: desired.Version,
+	}
+	p.mu.Lock()
+	target.appliedVersion = workerConfig.Version
+	p.mu.Unlock()
+	target.pusher.Submit(req)
+
+	event := dto.AgentEventRequest{
+		AgentID:        p.Status().AgentID,
+		WorkerURL:      target.url,
+		Type:           domain.AgentEventDrift,
+		DesiredVersion: desired.Version,
+		ActualVersion:  workerConfig.Version,
+		Message:        "re-pushing desired config",
+	}
+	if err := p.agentManager.ReportEvent(ctx, event); err != nil {
+		p.logger.Error("Failed to report drift event to controller", "error", err)
+	}
+}
+
+// ValidateConfig has every managed worker check req.Config without applying it,
+// bounded by the push concurrency.
+func (p *ControllerPoller) ValidateConfig(ctx context.Context, req dto.ConfigRequest) []dto.WorkerConfigValidation {
+	p.mu.RLock()
+	workers := p.sortedWorkers()
+	p.mu.RUnlock()
+
+	results := make([]dto.WorkerConfigValidation, len(workers))
+	var g errgroup.Group
+	g.SetLimit(p.concurrency)
+	for i, w := range workers {
+		g.Go(func() error {
+			results[i].URL = w.url
+			validation, err := p.agentManager.ValidateOnWorker(ctx, w.url, req)
+			if err != nil {
+				results[i].Error = err.Error()
+				return nil
+			}
+			results[i].ConfigValidation = *validation
+			return nil
+		})
+	}
+	g.Wait()
+	return results
+}
+
+// restoreFromCache pushes the last config fetched by a previous run to the workers
+// and marks it stale until the controller confirms or replaces it.
+func (p *ControllerPoller) restoreFromCache() {
+	if p.cache == nil {
+		return
+	}
+
+	if _, err := p.ReloadCache(); err != nil && !errors.Is(err, usecase.ErrAgentCacheEmpty) {
+		p.logger.Error("Failed to load agent cache", "error", err)
+	}
+}
+
+// ReloadCache serves the config in the agent cache file again: it is pushed to
+// every worker and reported stale until the next successful poll.
+func (p *ControllerPoller) ReloadCache() (*dto.AgentCacheEntry, error) {
+	if p.cache == nil {
+		return nil, ErrAgentCacheDisabled
+	}
+
+	entry, err := p.cache.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.versionCache = entry.Version
+	p.stale = true
+	p.source = statusSourceCache
+	p.mu.Unlock()
+	p.pushToWorkers(context.Background(), entry.Version, entry.Config)
+	p.logger.Warn("Serving cached config until the controller is reachable", "version", entry.Version, "fetched_at", entry.FetchedAt)
+	return entry, nil
+}
+
+// Resync wakes the poll loop for an immediate poll that re-pushes the desired
+// config to every worker, even when the version did not change.
+func (p *ControllerPoller) Resync() {
+	p.mu.Lock()
+	p.forceResync = true
+	p.mu.Unlock()
+
+	select {
+	case p.resync <- struct{}{}:
+	default:
+	}
+}
+
+// sleep waits for d or until a resync is requested. It returns false once ctx
+// is cancelled.
+func (p *ControllerPoller) sleep(ctx context.Context, d time.Duration) bool {
+	p.mu.Lock()
+	p.nextPollAt = time.Now().Add(d)
+	p.mu.Unlock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-p.resync:
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	p.nextPollAt = time.Time{}
+	p.mu.Unlock()
+	return ctx.Err() == nil
+}
+
+// waitFor waits for d and returns false if ctx is cancelled first.
+func waitFor(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (p *ControllerPoller) pollFailed(reason string) {
+	p.mu.Lock()
+	p.lastPollAt = time.Now()
+	p.lastPollError = reason
+	p.pollFailures++
+	p.mu.Unlock()
+	p.markStale()
+}
+
+func (p *ControllerPoller) markStale() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.source != "" {
+		p.stale = true
 	}
 }
@@ -4,15 +4,25 @@ import (
 	"config-manager/configs"
 	"config-manager/internal/dto"
 	"config-manager/internal/logger"
+	"config-manager/internal/usecase"
+	"config-manager/pkg/shared/tracing"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // MockAgentManager is a mock for the AgentManager interface
@@ -20,7 +30,7 @@ type MockAgentManager struct {
 	mock.Mock
 }
 
-func (m *MockAgentManager) Register() (*dto.AgentRegisterResponse, error) {
+func (m *MockAgentManager) Register(ctx context.Context) (*dto.AgentRegisterResponse, error) {
 	args := m.Called()
 	if args.Get(0) != nil {
 		return args.Get(0).(*dto.AgentRegisterResponse), args.Error(1)
@@ -28,11 +38,64 @@ func (m *MockAgentManager) Register() (*dto.AgentRegisterResponse, error) {
 	return nil, args.Error(1)
 }
 
-func (m *MockAgentManager) PushToWorker(req dto.ConfigRequest) error {
+func (m *MockAgentManager) PushToWorker(ctx context.Context, workerURL string, req dto.ConfigRequest) error {
+	args := m.Called(workerURL, req)
+	return args.Error(0)
+}
+
+func (m *MockAgentManager) Heartbeat(ctx context.Context, req dto.AgentHeartbeatRequest) error {
 	args := m.Called(req)
 	return args.Error(0)
 }
 
+func (m *MockAgentManager) GetWorkerConfig(ctx context.Context, workerURL string) (*dto.WorkerConfigResponse, error) {
+	args := m.Called(workerURL)
+	if args.Get(0) != nil {
+		return args.Get(0).(*dto.WorkerConfigResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAgentManager) ValidateOnWorker(ctx context.Context, workerURL string, req dto.ConfigRequest) (*dto.ConfigValidation, error) {
+	args := m.Called(workerURL, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*dto.ConfigValidation), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAgentManager) ReportEvent(ctx context.Context, req dto.AgentEventRequest) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
+
+// MockAgentCache is a mock for the AgentCache interface
+type MockAgentCache struct {
+	mock.Mock
+}
+
+func (m *MockAgentCache) Load() (*dto.AgentCacheEntry, error) {
+	args := m.Called()
+	if args.Get(0) != nil {
+		return args.Get(0).(*dto.AgentCacheEntry), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAgentCache) Save(version string, config map[string]interface{}) error {
+	args := m.Called(version, config)
+	return args.Error(0)
+}
+
+const testWorkerURL = "http://worker-a:8082"
+
+// newTestPoller returns a poller managing the single worker testWorkerURL.
+func newTestPoller(cfg *configs.Config, agentManager usecase.AgentManager, cache usecase.AgentCache) *ControllerPoller {
+	poller := NewControllerPoller(cfg, agentManager, cache, usecase.NewStaticDiscovery([]string{testWorkerURL}), nil, logger.NewLogger())
+	poller.RefreshWorkers()
+	return poller
+}
+
 func TestControllerPoller_StartAndPollLoop(t *testing.T) {
 	log := logger.NewLogger()
 	mockManager := new(MockAgentManager)
@@ -41,7 +104,7 @@ func TestControllerPoller_StartAndPollLoop(t *testing.T) {
 		ControllerURL: "http://localhost:8080",
 	}
 
-	poller := NewControllerPoller(cfg, mockManager, log)
+	poller := NewControllerPoller(cfg, mockManager, nil, nil, nil, log)
 	assert.NotNil(t, poller)
 	assert.Equal(t, cfg, poller.cfg)
 	assert.Equal(t, mockManager, poller.agentManager)
@@ -56,7 +119,7 @@ func TestControllerPoller_Start(t *testing.T) {
 	cfg := &configs.Config{
 		ControllerURL: "http://localhost:8080",
 	}
-	poller := NewControllerPoller(cfg, mockManager, log)
+	poller := NewControllerPoller(cfg, mockManager, nil, nil, nil, log)
 
 	// Mock Register to fail once, then succeed
 	mockManager.On("Register").Return(nil, errors.New("register error")).Once()
@@ -70,7 +133,7 @@ func TestControllerPoller_Start(t *testing.T) {
 
 	// Since Start calls pollLoop indefinitely, we run it in a goroutine
 	// and assert the fields were updated by register
-	go poller.Start()
+	go poller.Start(t.Context())
 
 	// Wait long enough for the retry to happen
 	time.Sleep(10 * time.Millisecond) // we hacked the sleep below? no, Start sleeps for 5s...
@@ -78,8 +141,6 @@ func TestControllerPoller_Start(t *testing.T) {
 }
 
 func TestControllerPoller_PollIteration(t *testing.T) {
-	log := logger.NewLogger()
-
 	t.Run("Success", func(t *testing.T) {
 		mockManager := new(MockAgentManager)
 
@@ -98,18 +159,27 @@ func TestControllerPoller_PollIteration(t *testing.T) {
 			ControllerURL: ts.URL,
 		}
 
-		poller := NewControllerPoller(cfg, mockManager, log)
+		poller := newTestPoller(cfg, mockManager, nil)
 		poller.pollURL = "/v1/poll"
 		poller.pollInterval = 5 * time.Millisecond
 		poller.versionCache = "old_version"
 
-		mockManager.On("PushToWorker", mock.AnythingOfType("dto.ConfigRequest")).Return(nil).Once()
+		mockManager.On("PushToWorker", testWorkerURL, dto.ConfigRequest{
+			Config:  map[string]interface{}{"key": "value"},
+			Version: "new_version",
+		}).Return(nil).Once()
+		mockManager.On("Heartbeat", mock.AnythingOfType("dto.AgentHeartbeatRequest")).Return(nil).Maybe()
+		mockManager.On("GetWorkerConfig", testWorkerURL).Return(&dto.WorkerConfigResponse{
+			Version: "new_version",
+			Digest:  usecase.ConfigDigest(map[string]interface{}{"key": "value"}),
+		}, nil).Maybe()
 
-		go poller.pollLoop()
+		go poller.pollLoop(t.Context())
 
 		time.Sleep(50 * time.Millisecond)
 		mockManager.AssertExpectations(t)
 		assert.Equal(t, "new_version", poller.versionCache)
+		assert.Equal(t, "new_version", poller.appliedVersion)
 	})
 
 	t.Run("Push Error", func(t *testing.T) {
@@ -129,14 +199,18 @@ func TestControllerPoller_PollIteration(t *testing.T) {
 			ControllerURL: ts.URL,
 		}
 
-		poller := NewControllerPoller(cfg, mockManager, log)
+		poller := newTestPoller(cfg, mockManager, nil)
 		poller.pollURL = "/v1/poll"
 		poller.pollInterval = 5 * time.Millisecond
 		poller.versionCache = "old_version"
 
-		mockManager.On("PushToWorker", mock.Anything).Return(errors.New("push error")).Maybe() // can be called many times
+		mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(errors.New("push error")).Maybe() // can be called many times
+		mockManager.On("Heartbeat", mock.Anything).Return(errors.New("heartbeat error")).Maybe()
+		// The worker still runs the old config, so every later poll retries the push
+		mockManager.On("GetWorkerConfig", testWorkerURL).Return(&dto.WorkerConfigResponse{Version: "old_version"}, nil).Maybe()
+		mockManager.On("ReportEvent", mock.Anything).Return(nil).Maybe()
 
-		go poller.pollLoop()
+		go poller.pollLoop(t.Context())
 
 		time.Sleep(50 * time.Millisecond)
 		mockManager.AssertExpectations(t)
@@ -154,13 +228,16 @@ func TestControllerPoller_PollIteration(t *testing.T) {
 			ControllerURL: ts.URL,
 		}
 
-		poller := NewControllerPoller(cfg, mockManager, log)
+		poller := newTestPoller(cfg, mockManager, nil)
 		poller.pollURL = "/v1/poll"
 		poller.pollInterval = 5 * time.Millisecond
 		poller.versionCache = "old_version"
 
-		go poller.pollLoop()
+		poller.metrics = NewAgentMetrics(prometheus.NewRegistry())
+
+		go poller.pollLoop(t.Context())
 		time.Sleep(50 * time.Millisecond)
+		assert.Positive(t, testutil.ToFloat64(poller.metrics.polls.WithLabelValues(pollResultBadStatus)))
 	})
 
 	t.Run("Fetch Decode Error", func(t *testing.T) {
@@ -176,13 +253,16 @@ func TestControllerPoller_PollIteration(t *testing.T) {
 			ControllerURL: ts.URL,
 		}
 
-		poller := NewControllerPoller(cfg, mockManager, log)
+		poller := newTestPoller(cfg, mockManager, nil)
 		poller.pollURL = "/v1/poll"
 		poller.pollInterval = 5 * time.Millisecond
 		poller.versionCache = "old_version"
 
-		go poller.pollLoop()
+		poller.metrics = NewAgentMetrics(prometheus.NewRegistry())
+
+		go poller.pollLoop(t.Context())
 		time.Sleep(50 * time.Millisecond)
+		assert.Positive(t, testutil.ToFloat64(poller.metrics.polls.WithLabelValues(pollResultInvalidResponse)))
 	})
 
 	t.Run("Fetch HTTP Error", func(t *testing.T) {
@@ -191,12 +271,700 @@ func TestControllerPoller_PollIteration(t *testing.T) {
 			ControllerURL: "http://localhost:1",
 		}
 
-		poller := NewControllerPoller(cfg, mockManager, log)
+		poller := newTestPoller(cfg, mockManager, nil)
 		poller.pollURL = "/v1/poll"
 		poller.pollInterval = 5 * time.Millisecond
 		poller.versionCache = "old_version"
 
-		go poller.pollLoop()
+		poller.metrics = NewAgentMetrics(prometheus.NewRegistry())
+
+		go poller.pollLoop(t.Context())
 		time.Sleep(50 * time.Millisecond)
+		assert.Positive(t, testutil.ToFloat64(poller.metrics.polls.WithLabelValues(resultError)))
+		assert.Positive(t, testutil.ToFloat64(poller.metrics.pollRetries))
+	})
+}
+
+func TestControllerPoller_RestoreFromCache(t *testing.T) {
+	cfg := &configs.Config{}
+	entry := &dto.AgentCacheEntry{
+		Version: "cached_version",
+		Config:  map[string]interface{}{"key": "value"},
+	}
+
+	t.Run("Pushes Cached Config", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		mockCache := new(MockAgentCache)
+		poller := newTestPoller(cfg, mockManager, mockCache)
+
+		mockCache.On("Load").Return(entry, nil).Once()
+		mockManager.On("PushToWorker", testWorkerURL, dto.ConfigRequest{Config: entry.Config, Version: "cached_version"}).Return(nil).Once()
+
+		poller.restoreFromCache()
+
+		status := poller.Status()
+		assert.Equal(t, "cached_version", status.Version)
+		assert.True(t, status.Stale)
+		assert.Equal(t, "cache", status.Source)
+		assert.Nil(t, status.LastSyncAt)
+		assert.Eventually(t, func() bool {
+			return poller.Status().AppliedVersion == "cached_version"
+		}, time.Second, time.Millisecond)
+		mockManager.AssertExpectations(t)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("Push Error Is Retried", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		mockCache := new(MockAgentCache)
+		poller := newTestPoller(cfg, mockManager, mockCache)
+
+		mockCache.On("Load").Return(entry, nil).Once()
+		mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(errors.New("push error")).Once()
+		mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(nil).Maybe()
+
+		poller.restoreFromCache()
+
+		assert.Eventually(t, func() bool {
+			return poller.Status().Workers[0].Push.Attempts == 1
+		}, time.Second, time.Millisecond)
+		status := poller.Status()
+		assert.Equal(t, "cached_version", status.Workers[0].Push.PendingVersion)
+		assert.Equal(t, "push error", status.Workers[0].Push.LastError)
+		assert.Empty(t, status.AppliedVersion)
+	})
+
+	t.Run("Empty Or Invalid Cache", func(t *testing.T) {
+		for _, err := range []error{usecase.ErrAgentCacheEmpty, usecase.ErrAgentCacheSignature} {
+			mockManager := new(MockAgentManager)
+			mockCache := new(MockAgentCache)
+			poller := newTestPoller(cfg, mockManager, mockCache)
+
+			mockCache.On("Load").Return(nil, err).Once()
+
+			poller.restoreFromCache()
+
+			assert.Empty(t, poller.Status().Version)
+			mockManager.AssertNotCalled(t, "PushToWorker", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("No Cache", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		poller := newTestPoller(cfg, mockManager, nil)
+
+		assert.NotPanics(t, poller.restoreFromCache)
+		assert.Empty(t, poller.Status().Source)
 	})
 }
+
+func TestControllerPoller_ReconcileCachedConfig(t *testing.T) {
+	t.Run("Same Version Clears Stale", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		mockCache := new(MockAgentCache)
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(dto.ConfigResponse{Version: "cached_version", Config: map[string]interface{}{"key": "value"}})
+		}))
+		defer ts.Close()
+
+		poller := newTestPoller(&configs.Config{ControllerURL: ts.URL}, mockManager, mockCache)
+		poller.pollURL = "/v1/poll"
+		poller.pollInterval = 5 * time.Millisecond
+
+		mockCache.On("Load").Return(&dto.AgentCacheEntry{Version: "cached_version", Config: map[string]interface{}{"key": "value"}}, nil).Once()
+		mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(nil).Once()
+		mockManager.On("Heartbeat", mock.Anything).Return(nil).Maybe()
+		mockManager.On("GetWorkerConfig", testWorkerURL).Return(&dto.WorkerConfigResponse{Version: "cached_version"}, nil).Maybe()
+
+		poller.restoreFromCache()
+		go poller.pollLoop(t.Context())
+		time.Sleep(50 * time.Millisecond)
+
+		status := poller.Status()
+		assert.False(t, status.Stale)
+		assert.Equal(t, "controller", status.Source)
+		assert.NotNil(t, status.LastSyncAt)
+		mockManager.AssertExpectations(t)
+		mockCache.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+
+	t.Run("New Version Is Cached And Pushed", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		mockCache := new(MockAgentCache)
+		config := map[string]interface{}{"key": "new"}
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(dto.ConfigResponse{Version: "new_version", Config: config})
+		}))
+		defer ts.Close()
+
+		poller := newTestPoller(&configs.Config{ControllerURL: ts.URL}, mockManager, mockCache)
+		poller.pollURL = "/v1/poll"
+		poller.pollInterval = 5 * time.Millisecond
+		poller.versionCache = "cached_version"
+
+		mockCache.On("Save", "new_version", config).Return(nil).Once()
+		mockManager.On("PushToWorker", testWorkerURL, dto.ConfigRequest{Config: config, Version: "new_version"}).Return(nil).Once()
+		mockManager.On("Heartbeat", mock.Anything).Return(nil).Maybe()
+		mockManager.On("GetWorkerConfig", testWorkerURL).Return(&dto.WorkerConfigResponse{Version: "new_version"}, nil).Maybe()
+
+		go poller.pollLoop(t.Context())
+		time.Sleep(50 * time.Millisecond)
+
+		assert.Equal(t, "new_version", poller.Status().AppliedVersion)
+		mockManager.AssertExpectations(t)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("Controller Unreachable Marks Stale", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		poller := newTestPoller(&configs.Config{ControllerURL: ts.URL}, mockManager, nil)
+		poller.pollURL = "/v1/poll"
+		poller.pollInterval = 5 * time.Millisecond
+		poller.versionCache = "v1"
+		poller.source = "controller"
+
+		go poller.pollLoop(t.Context())
+		time.Sleep(50 * time.Millisecond)
+
+		assert.True(t, poller.Status().Stale)
+	})
+}
+
+func TestControllerPoller_ReconcileWorker(t *testing.T) {
+	desired := dto.ConfigResponse{Version: "v2", Config: map[string]interface{}{"key": "value"}}
+
+	newPoller := func(mockManager *MockAgentManager) *ControllerPoller {
+		// Failed pushes must not be retried after the test ends
+		poller := newTestPoller(&configs.Config{PushRetryInitial: 3600}, mockManager, nil)
+		poller.agentID = "agent-1"
+		return poller
+	}
+
+	t.Run("In Sync", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		mockManager.On("GetWorkerConfig", testWorkerURL).Return(&dto.WorkerConfigResponse{
+			Version: "v2",
+			Digest:  usecase.ConfigDigest(desired.Config),
+		}, nil).Once()
+
+		newPoller(mockManager).reconcileWorkers(t.Context(), desired)
+
+		mockManager.AssertExpectations(t)
+		mockManager.AssertNotCalled(t, "PushToWorker", mock.Anything, mock.Anything)
+		mockManager.AssertNotCalled(t, "ReportEvent", mock.Anything)
+	})
+
+	t.Run("Version Drift Re-Pushes And Reports", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		poller := newPoller(mockManager)
+		mockManager.On("GetWorkerConfig", testWorkerURL).Return(&dto.WorkerConfigResponse{Version: ""}, nil).Once()
+		mockManager.On("PushToWorker", testWorkerURL, dto.ConfigRequest{Config: desired.Config, Version: "v2"}).Return(nil).Once()
+		mockManager.On("ReportEvent", dto.AgentEventRequest{
+			AgentID:        "agent-1",
+			WorkerURL:      testWorkerURL,
+			Type:           "drift",
+			DesiredVersion: "v2",
+			ActualVersion:  "",
+			Message:        "re-pushing desired config",
+		}).Return(nil).Once()
+
+		poller.reconcileWorkers(t.Context(), desired)
+
+		assert.Eventually(t, func() bool {
+			return poller.Status().AppliedVersion == "v2"
+		}, time.Second, time.Millisecond)
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("Content Drift With Same Version", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		mockManager.On("GetWorkerConfig", testWorkerURL).Return(&dto.WorkerConfigResponse{
+			Version: "v2",
+			Digest:  usecase.ConfigDigest(map[string]interface{}{"key": "manual"}),
+		}, nil).Once()
+		mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(nil).Once()
+		mockManager.On("ReportEvent", mock.MatchedBy(func(e dto.AgentEventRequest) bool {
+			return e.DesiredVersion == "v2" && e.ActualVersion == "v2"
+		})).Return(nil).Once()
+
+		poller := newPoller(mockManager)
+		poller.reconcileWorkers(t.Context(), desired)
+
+		assert.Eventually(t, func() bool {
+			return poller.Status().AppliedVersion == "v2"
+		}, time.Second, time.Millisecond)
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("Pending Push Is Not Drift", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		poller := newPoller(mockManager)
+		mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(errors.New("push error")).Once()
+		mockManager.On("GetWorkerConfig", testWorkerURL).Return(&dto.WorkerConfigResponse{Version: "v1"}, nil).Once()
+
+		poller.pushToWorkers(t.Context(), "v2", desired.Config)
+		assert.Eventually(t, func() bool {
+			return poller.Status().Workers[0].Push.Attempts == 1
+		}, time.Second, time.Millisecond)
+
+		poller.reconcileWorkers(t.Context(), desired)
+
+		mockManager.AssertNumberOfCalls(t, "PushToWorker", 1)
+		mockManager.AssertNotCalled(t, "ReportEvent", mock.Anything)
+	})
+
+	t.Run("Worker Unreachable", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		mockManager.On("GetWorkerConfig", testWorkerURL).Return(nil, errors.New("connection refused")).Once()
+
+		newPoller(mockManager).reconcileWorkers(t.Context(), desired)
+
+		mockManager.AssertNotCalled(t, "PushToWorker", mock.Anything, mock.Anything)
+	})
+}
+
+func TestControllerPoller_PushDeadLetter(t *testing.T) {
+	mockManager := new(MockAgentManager)
+	cfg := &configs.Config{PushMaxAttempts: 1}
+
+	poller := newTestPoller(cfg, mockManager, nil)
+	poller.agentID = "agent-1"
+
+	reported := make(chan dto.AgentEventRequest, 1)
+	mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(errors.New("connection refused")).Once()
+	mockManager.On("ReportEvent", mock.Anything).Run(func(args mock.Arguments) {
+		reported <- args.Get(0).(dto.AgentEventRequest)
+	}).Return(nil).Once()
+
+	poller.pushToWorkers(t.Context(), "v1", map[string]interface{}{"key": "value"})
+
+	select {
+	case event := <-reported:
+		assert.Equal(t, "agent-1", event.AgentID)
+		assert.Equal(t, testWorkerURL, event.WorkerURL)
+		assert.Equal(t, "push_dead_letter", event.Type)
+		assert.Equal(t, "v1", event.DesiredVersion)
+		assert.Equal(t, "push failed after 1 attempts: connection refused", event.Message)
+	case <-time.After(time.Second):
+		t.Fatal("dead-lettered push was not reported")
+	}
+
+	status := poller.Status()
+	assert.Empty(t, status.Workers[0].Push.PendingVersion)
+	assert.Len(t, status.Workers[0].Push.DeadLetters, 1)
+	assert.Equal(t, "v1", status.Workers[0].Push.DeadLetters[0].Version)
+}
+
+func TestControllerPoller_PermanentlyFailingWorker(t *testing.T) {
+	mockManager := new(MockAgentManager)
+	poller := newTestPoller(&configs.Config{PushMaxAttempts: 1}, mockManager, nil)
+	poller.agentID = "agent-1"
+	desired := dto.ConfigResponse{Version: "v2", Config: map[string]interface{}{"key": "value"}}
+
+	mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(fmt.Errorf("%w, status: 422: egress denied", usecase.ErrPushRejected))
+	mockManager.On("GetWorkerConfig", testWorkerURL).Return(&dto.WorkerConfigResponse{Version: "v1"}, nil)
+	reported := make(chan struct{}, 4)
+	mockManager.On("ReportEvent", mock.Anything).Run(func(args mock.Arguments) {
+		reported <- struct{}{}
+	}).Return(nil)
+
+	poller.pushToWorkers(t.Context(), "v2", desired.Config)
+	select {
+	case <-reported:
+	case <-time.After(time.Second):
+		t.Fatal("dead-lettered push was not reported")
+	}
+
+	for range 3 {
+		poller.reconcileWorkers(t.Context(), desired)
+	}
+
+	mockManager.AssertNumberOfCalls(t, "PushToWorker", 1)
+	mockManager.AssertNumberOfCalls(t, "ReportEvent", 1) // the dead letter, no drift
+	status := poller.Status().Workers[0].Push
+	assert.Empty(t, status.PendingVersion)
+	assert.Len(t, status.DeadLetters, 1)
+
+	// A resync still retries it
+	mockManager.ExpectedCalls = nil
+	mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(nil).Once()
+	poller.pushToWorkers(t.Context(), "v2", desired.Config)
+	assert.Eventually(t, func() bool {
+		return poller.Status().AppliedVersion == "v2"
+	}, time.Second, time.Millisecond)
+}
+
+func TestControllerPoller_WorkerRecoversAfterDeadLetter(t *testing.T) {
+	mockManager := new(MockAgentManager)
+	poller := newTestPoller(&configs.Config{PushMaxAttempts: 1}, mockManager, nil)
+	poller.agentID = "agent-1"
+	desired := dto.ConfigResponse{Version: "v2", Config: map[string]interface{}{"key": "value"}}
+
+	// Down for every attempt
+	mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(errors.New("connection refused")).Once()
+	mockManager.On("GetWorkerConfig", testWorkerURL).Return(nil, errors.New("connection refused")).Once()
+	reported := make(chan struct{}, 4)
+	mockManager.On("ReportEvent", mock.Anything).Run(func(args mock.Arguments) {
+		reported <- struct{}{}
+	}).Return(nil)
+
+	poller.pushToWorkers(t.Context(), "v2", desired.Config)
+	select {
+	case <-reported:
+	case <-time.After(time.Second):
+		t.Fatal("dead-lettered push was not reported")
+	}
+	poller.reconcileWorkers(t.Context(), desired)
+	assert.Len(t, poller.Status().Workers[0].Push.DeadLetters, 1)
+
+	// Back, but empty after a restart
+	mockManager.On("GetWorkerConfig", testWorkerURL).Return(&dto.WorkerConfigResponse{}, nil).Once()
+	mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(nil).Once()
+	poller.reconcileWorkers(t.Context(), desired)
+
+	assert.Eventually(t, func() bool {
+		return poller.Status().AppliedVersion == "v2"
+	}, time.Second, time.Millisecond)
+	mockManager.AssertNumberOfCalls(t, "PushToWorker", 2)
+}
+
+// fakeDiscovery returns whatever workers the test sets.
+type fakeDiscovery struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+func (d *fakeDiscovery) Workers(ctx context.Context) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.urls, nil
+}
+
+func (d *fakeDiscovery) set(urls ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.urls = urls
+}
+
+func TestControllerPoller_FanOut(t *testing.T) {
+	log := logger.NewLogger()
+	config := map[string]interface{}{"key": "value"}
+	req := dto.ConfigRequest{Config: config, Version: "v1"}
+	workerB := "http://worker-b:8082"
+
+	t.Run("Applied Once Every Worker Has The Version", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		discovery := &fakeDiscovery{urls: []string{testWorkerURL, workerB}}
+		poller := NewControllerPoller(&configs.Config{PushRetryInitial: 3600}, mockManager, nil, discovery, nil, log)
+		poller.agentID = "agent-1"
+		poller.RefreshWorkers()
+
+		mockManager.On("PushToWorker", testWorkerURL, req).Return(nil).Once()
+		mockManager.On("PushToWorker", workerB, req).Return(errors.New("connection refused")).Once()
+
+		poller.pushToWorkers(t.Context(), "v1", config)
+
+		assert.Eventually(t, func() bool {
+			status := poller.Status()
+			return status.Workers[0].AppliedVersion == "v1" && status.Workers[1].Push.Attempts == 1
+		}, time.Second, time.Millisecond)
+		assert.Empty(t, poller.Status().AppliedVersion)
+
+		heartbeat := poller.heartbeat()
+		assert.Equal(t, "agent-1", heartbeat.AgentID)
+		assert.Equal(t, []dto.WorkerHeartbeat{
+			{URL: testWorkerURL, AppliedVersion: "v1"},
+			{URL: workerB, PendingVersion: "v1", LastError: "connection refused"},
+		}, heartbeat.Workers)
+
+		// Dropping the failing worker cancels its retries and completes the rollout
+		discovery.set(testWorkerURL)
+		poller.RefreshWorkers()
+
+		status := poller.Status()
+		assert.Len(t, status.Workers, 1)
+		assert.Equal(t, "v1", status.AppliedVersion)
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("New Worker Receives Desired Config", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		discovery := &fakeDiscovery{urls: []string{testWorkerURL}}
+		poller := NewControllerPoller(&configs.Config{}, mockManager, nil, discovery, nil, log)
+		poller.RefreshWorkers()
+
+		mockManager.On("PushToWorker", testWorkerURL, req).Return(nil).Once()
+		mockManager.On("PushToWorker", workerB, req).Return(nil).Once()
+
+		poller.pushToWorkers(t.Context(), "v1", config)
+		assert.Eventually(t, func() bool {
+			return poller.Status().AppliedVersion == "v1"
+		}, time.Second, time.Millisecond)
+
+		discovery.set(testWorkerURL, workerB)
+		poller.RefreshWorkers()
+
+		assert.Eventually(t, func() bool {
+			status := poller.Status()
+			return len(status.Workers) == 2 && status.Workers[1].AppliedVersion == "v1"
+		}, time.Second, time.Millisecond)
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("Push Concurrency Is Bounded", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		urls := []string{"http://w1", "http://w2", "http://w3", "http://w4", "http://w5"}
+		poller := NewControllerPoller(&configs.Config{PushConcurrency: 2}, mockManager, nil, &fakeDiscovery{urls: urls}, nil, log)
+		poller.RefreshWorkers()
+
+		var mu sync.Mutex
+		inFlight, maxInFlight := 0, 0
+		release := make(chan struct{})
+		mockManager.On("PushToWorker", mock.Anything, req).Run(func(args mock.Arguments) {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+			<-release
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}).Return(nil).Times(len(urls))
+
+		poller.pushToWorkers(t.Context(), "v1", config)
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return inFlight == 2
+		}, time.Second, time.Millisecond)
+		close(release)
+
+		assert.Eventually(t, func() bool {
+			return poller.Status().AppliedVersion == "v1"
+		}, time.Second, time.Millisecond)
+		mu.Lock()
+		assert.Equal(t, 2, maxInFlight)
+		mu.Unlock()
+		mockManager.AssertExpectations(t)
+	})
+}
+
+func TestControllerPoller_Admin(t *testing.T) {
+	t.Run("Resync Polls Immediately And Re-Pushes", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		config := map[string]interface{}{"key": "value"}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(dto.ConfigResponse{Version: "v1", Config: config})
+		}))
+		defer ts.Close()
+
+		poller := newTestPoller(&configs.Config{ControllerURL: ts.URL}, mockManager, nil)
+		poller.pollURL = "/v1/poll"
+		poller.pollInterval = time.Hour
+		poller.versionCache = "v1"
+
+		pushed := make(chan struct{}, 1)
+		mockManager.On("PushToWorker", testWorkerURL, dto.ConfigRequest{Config: config, Version: "v1"}).Return(nil).Once().Run(func(mock.Arguments) { pushed <- struct{}{} })
+		mockManager.On("Heartbeat", mock.Anything).Return(nil).Maybe()
+
+		go poller.pollLoop(t.Context())
+		assert.Eventually(t, func() bool {
+			return poller.Status().Poll.NextPollAt != nil
+		}, time.Second, time.Millisecond)
+		poller.Resync()
+
+		select {
+		case <-pushed:
+		case <-time.After(time.Second):
+			t.Fatal("resync did not re-push the unchanged version")
+		}
+		assert.Eventually(t, func() bool {
+			return poller.Status().Poll.LastPollAt != nil
+		}, time.Second, time.Millisecond)
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("Failed Polls Are Reported", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+
+		poller := newTestPoller(&configs.Config{ControllerURL: ts.URL}, new(MockAgentManager), nil)
+		poller.pollURL = "/v1/poll"
+		poller.pollInterval = time.Millisecond
+
+		go poller.pollLoop(t.Context())
+		assert.Eventually(t, func() bool {
+			return poller.Status().Poll.ConsecutiveFailures >= 2
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, "unexpected status code 503", poller.Status().Poll.LastError)
+	})
+
+	t.Run("Reload Cache", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		mockCache := new(MockAgentCache)
+		poller := newTestPoller(&configs.Config{}, mockManager, mockCache)
+		entry := &dto.AgentCacheEntry{Version: "cached_version", Config: map[string]interface{}{"key": "value"}}
+
+		mockCache.On("Load").Return(entry, nil).Once()
+		mockManager.On("PushToWorker", testWorkerURL, dto.ConfigRequest{Config: entry.Config, Version: "cached_version"}).Return(nil).Once()
+
+		reloaded, err := poller.ReloadCache()
+		assert.NoError(t, err)
+		assert.Equal(t, entry, reloaded)
+		assert.Equal(t, "cache", poller.Status().Source)
+		assert.Eventually(t, func() bool {
+			return poller.Status().AppliedVersion == "cached_version"
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("Reload Without Cache", func(t *testing.T) {
+		_, err := newTestPoller(&configs.Config{}, new(MockAgentManager), nil).ReloadCache()
+		assert.ErrorIs(t, err, ErrAgentCacheDisabled)
+	})
+
+	t.Run("Validate Config", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		poller := newTestPoller(&configs.Config{}, mockManager, nil)
+		req := dto.ConfigRequest{Config: map[string]interface{}{"url": "ftp://example.com"}}
+		issues := []dto.ConfigIssue{{Error: "egress denied: scheme \"ftp\" is not allowed"}}
+
+		mockManager.On("ValidateOnWorker", testWorkerURL, req).Return(&dto.ConfigValidation{Issues: issues}, nil).Once()
+		assert.Equal(t, []dto.WorkerConfigValidation{
+			{URL: testWorkerURL, ConfigValidation: dto.ConfigValidation{Issues: issues}},
+		}, poller.ValidateConfig(t.Context(), req))
+
+		mockManager.On("ValidateOnWorker", testWorkerURL, req).Return(nil, errors.New("connection refused")).Once()
+		assert.Equal(t, []dto.WorkerConfigValidation{
+			{URL: testWorkerURL, Error: "connection refused"},
+		}, poller.ValidateConfig(t.Context(), req))
+		mockManager.AssertExpectations(t)
+	})
+}
+
+func TestControllerPoller_Shutdown(t *testing.T) {
+	t.Run("Stops While Registering", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		poller := newTestPoller(&configs.Config{}, mockManager, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		mockManager.On("Register").Return(nil, errors.New("controller down")).Run(func(mock.Arguments) { cancel() })
+
+		done := make(chan struct{})
+		go func() {
+			poller.Start(ctx)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("poller did not stop while waiting to retry registration")
+		}
+	})
+
+	t.Run("Stops Polling And Aborts Pushes", func(t *testing.T) {
+		mockManager := new(MockAgentManager)
+		config := map[string]interface{}{"key": "value"}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(dto.ConfigResponse{Version: "v1", Config: config})
+		}))
+		defer ts.Close()
+
+		poller := newTestPoller(&configs.Config{ControllerURL: ts.URL}, mockManager, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		pushing := make(chan struct{})
+		aborted := make(chan error, 1)
+		mockManager.On("Register").Return(&dto.AgentRegisterResponse{AgentID: "agent-1", PollURL: "/v1/poll", ValidateToken: "agent-1-token"}, nil).Once()
+		mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(context.Canceled).Run(func(args mock.Arguments) {
+			close(pushing)
+		}).Once()
+		mockManager.On("Heartbeat", mock.Anything).Return(nil).Maybe()
+
+		// Capture the context of the in-flight push through the pusher's push func
+		poller.mu.Lock()
+		poller.workers[testWorkerURL].pusher = usecase.NewWorkerPusher(func(ctx context.Context, req dto.ConfigRequest) error {
+			err := mockManager.PushToWorker(ctx, testWorkerURL, req)
+			<-ctx.Done()
+			aborted <- ctx.Err()
+			return err
+		}, usecase.WorkerPusherConfig{})
+		poller.mu.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			poller.Start(ctx)
+			close(done)
+		}()
+
+		<-pushing
+		assert.Equal(t, "agent-1-token", poller.ValidateToken(), "the token issued at register is kept")
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("poller did not stop")
+		}
+		select {
+		case err := <-aborted:
+			assert.ErrorIs(t, err, context.Canceled)
+		case <-time.After(time.Second):
+			t.Fatal("in-flight push was not aborted")
+		}
+		mockManager.AssertExpectations(t)
+	})
+}
+
+func TestControllerPoller_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	_, err := tracing.Setup(t.Context(), "agent", tracing.Config{}) // propagator only
+	assert.NoError(t, err)
+
+	traceparents := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("traceparent")
+		json.NewEncoder(w).Encode(dto.ConfigResponse{Version: "v2", Config: map[string]interface{}{"key": "value"}})
+	}))
+	defer ts.Close()
+
+	mockManager := new(MockAgentManager)
+	poller := newTestPoller(&configs.Config{ControllerURL: ts.URL}, mockManager, nil)
+	poller.pollURL = "/v1/poll"
+
+	pushed := make(chan struct{})
+	mockManager.On("PushToWorker", testWorkerURL, mock.Anything).Return(nil).Run(func(mock.Arguments) { close(pushed) }).Once()
+	mockManager.On("Heartbeat", mock.Anything).Return(nil).Once()
+
+	assert.NoError(t, poller.poll(t.Context()))
+	<-pushed
+	assert.NotEmpty(t, <-traceparents)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	assert.Eventually(t, func() bool {
+		for _, span := range recorder.Ended() {
+			spans[span.Name()] = span
+		}
+		return spans["agent.push"] != nil
+	}, time.Second, 5*time.Millisecond)
+
+	// The push runs in the background but joins the trace of the poll that found v2
+	poll, push := spans["agent.poll"], spans["agent.push"]
+	assert.Contains(t, poll.Attributes(), tracing.AttrConfigVersion.String("v2"))
+	assert.Equal(t, poll.SpanContext().SpanID(), push.Parent().SpanID())
+	assert.Contains(t, push.Attributes(), tracing.AttrConfigVersion.String("v2"))
+	assert.Contains(t, push.Attributes(), tracing.AttrWorkerURL.String(testWorkerURL))
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"config-manager/internal/usecase"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RegistrationJob keeps a worker registered with its local agent and
+// deregisters it on shutdown.
+type RegistrationJob struct {
+	registrar usecase.WorkerRegistrar
+	interval  time.Duration
+	logger    *slog.Logger
+}
+
+func NewRegistrationJob(registrar usecase.WorkerRegistrar, interval time.Duration, logger *slog.Logger) *RegistrationJob {
+	return &RegistrationJob{
+		registrar: registrar,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Start registers immediately and renews the registration every interval until
+// ctx is cancelled, then deregisters before returning.
+func (j *RegistrationJob) Start(ctx context.Context) {
+	j.register()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := j.registrar.Deregister(); err != nil {
+				j.logger.Error("Failed to deregister from agent", "error", err)
+				return
+			}
+			j.logger.Info("Deregistered from agent")
+			return
+		case <-ticker.C:
+			j.register()
+		}
+	}
+}
+
+func (j *RegistrationJob) register() {
+	if err := j.registrar.Register(); err != nil {
+		j.logger.Error("Failed to register with agent", "error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"config-manager/internal/logger"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockWorkerRegistrar is a mock for the WorkerRegistrar interface
+type MockWorkerRegistrar struct {
+	mock.Mock
+}
+
+func (m *MockWorkerRegistrar) Register() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockWorkerRegistrar) Deregister() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func TestRegistrationJob_Start(t *testing.T) {
+	log := logger.NewLogger()
+
+	t.Run("Renews Until Cancelled Then Deregisters", func(t *testing.T) {
+		registrar := new(MockWorkerRegistrar)
+		registered := make(chan struct{}, 10)
+		registrar.On("Register").Return(errors.New("agent down")).Once().Run(func(mock.Arguments) { registered <- struct{}{} })
+		registrar.On("Register").Return(nil).Run(func(mock.Arguments) { registered <- struct{}{} })
+		registrar.On("Deregister").Return(nil).Once()
+
+		job := NewRegistrationJob(registrar, time.Millisecond, log)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			job.Start(ctx)
+			close(done)
+		}()
+
+		<-registered
+		<-registered
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("job did not stop")
+		}
+		registrar.AssertExpectations(t)
+	})
+
+	t.Run("Deregister Error", func(t *testing.T) {
+		registrar := new(MockWorkerRegistrar)
+		registrar.On("Register").Return(nil).Once()
+		registrar.On("Deregister").Return(errors.New("agent down")).Once()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.NotPanics(t, func() { NewRegistrationJob(registrar, time.Hour, log).Start(ctx) })
+		registrar.AssertExpectations(t)
+	})
+}
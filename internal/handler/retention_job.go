@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"config-manager/internal/usecase"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RetentionJob periodically compacts old config versions on the controller.
+// Only the elected leader compacts, so replicas never delete concurrently.
+type RetentionJob struct {
+	retentionUsecase usecase.RetentionUsecase
+	elector          usecase.LeaderElector
+	interval         time.Duration
+	logger           *slog.Logger
+}
+
+func NewRetentionJob(retentionUsecase usecase.RetentionUsecase, elector usecase.LeaderElector, interval time.Duration, logger *slog.Logger) *RetentionJob {
+	return &RetentionJob{
+		retentionUsecase: retentionUsecase,
+		elector:          elector,
+		interval:         interval,
+		logger:           logger,
+	}
+}
+
+// Start runs compaction every interval until ctx is cancelled.
+func (j *RetentionJob) Start(ctx context.Context) {
+	if j.interval <= 0 {
+		j.logger.Info("Config retention job disabled")
+		return
+	}
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.runOnce()
+		}
+	}
+}
+
+func (j *RetentionJob) runOnce() {
+	if !j.elector.IsLeader() {
+		return
+	}
+
+	removed, err := j.retentionUsecase.Compact()
+	if err != nil {
+		j.logger.Error("Failed to compact config versions", "error", err)
+		return
+	}
+	if removed > 0 {
+		j.logger.Info("Compacted config versions", "removed", removed)
+	}
+}
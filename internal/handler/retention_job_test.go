@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"config-manager/internal/logger"
+	"config-manager/internal/usecase"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRetentionUsecase is a mock for the RetentionUsecase interface
+type MockRetentionUsecase struct {
+	mock.Mock
+}
+
+func (m *MockRetentionUsecase) Compact() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type followerElector struct{}
+
+func (followerElector) IsLeader() bool          { return false }
+func (followerElector) LeaderURL() string       { return "" }
+func (followerElector) Run(ctx context.Context) {}
+
+func TestRetentionJob_Start(t *testing.T) {
+	log := logger.NewLogger()
+
+	t.Run("Runs Until Cancelled", func(t *testing.T) {
+		mockUsecase := new(MockRetentionUsecase)
+		ran := make(chan struct{}, 10)
+		mockUsecase.On("Compact").Return(int64(2), nil).Once().Run(func(mock.Arguments) { ran <- struct{}{} })
+		mockUsecase.On("Compact").Return(int64(0), errors.New("db error")).Run(func(mock.Arguments) { ran <- struct{}{} })
+
+		job := NewRetentionJob(mockUsecase, usecase.NewStaticElector(""), time.Millisecond, log)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			job.Start(ctx)
+			close(done)
+		}()
+
+		<-ran
+		<-ran
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("retention job did not stop after cancel")
+		}
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		mockUsecase := new(MockRetentionUsecase)
+		job := NewRetentionJob(mockUsecase, usecase.NewStaticElector(""), 0, log)
+
+		job.Start(context.Background())
+		mockUsecase.AssertNotCalled(t, "Compact")
+	})
+
+	t.Run("Follower Skips Compaction", func(t *testing.T) {
+		mockUsecase := new(MockRetentionUsecase)
+		job := NewRetentionJob(mockUsecase, &followerElector{}, time.Millisecond, log)
+
+		job.runOnce()
+		mockUsecase.AssertNotCalled(t, "Compact")
+	})
+}
@@ -3,30 +3,75 @@ package handler
 import (
 	"config-manager/internal/dto"
 	"config-manager/internal/usecase"
+	"config-manager/pkg/shared/tracing"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 
 	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
 type WorkerHandler struct {
 	configManager usecase.ConfigManager
+	metrics       *WorkerMetrics
 	logger        *slog.Logger
 }
 
-func NewWorkerHandler(e *echo.Echo, configManager usecase.ConfigManager, logger *slog.Logger) {
+func NewWorkerHandler(e *echo.Echo, configManager usecase.ConfigManager, metrics *WorkerMetrics, logger *slog.Logger) {
 	handler := &WorkerHandler{
 		configManager: configManager,
+		metrics:       metrics,
 		logger:        logger,
 	}
+	if version := configManager.GetConfig().Version; version != "" {
+		metrics.configApplied(version)
+	}
 
 	v1 := e.Group("/v1")
 	v1.POST("/config", handler.ReceiveConfig)
+	v1.GET("/config", handler.GetConfig)
+	v1.POST("/config/validate", handler.ValidateConfig)
+	v1.GET("/actions", handler.ListActions)
 
 	e.GET("/hit", handler.HitProxy)
+	e.GET("/hit/:name", handler.HitProxy)
+	e.POST("/hit/:name", handler.HitProxy)
 }
 
+// ClientIPExtractor returns how /hit tells its clients apart for per-client rate
+// limits: by the address of the peer, or, when the peer is one of the
+// trustedProxies CIDRs, by the nearest X-Forwarded-For entry that is not.
+func ClientIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	var ranges []echo.TrustOption
+	for _, cidr := range trustedProxies {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy CIDR %q: %w", cidr, err)
+		}
+		ranges = append(ranges, echo.TrustIPRange(ipNet))
+	}
+	if len(ranges) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	// Echo trusts loopback and private peers by default, only the listed ranges are
+	options := append([]echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}, ranges...)
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// maxHitBodyBytes bounds the request body passed to an action's body template.
+const maxHitBodyBytes = 1 << 20
+
 func (h *WorkerHandler) ReceiveConfig(c echo.Context) error {
 	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
 	var req dto.ConfigRequest
@@ -41,14 +86,20 @@ func (h *WorkerHandler) ReceiveConfig(c echo.Context) error {
 
 	if err := h.configManager.UpdateConfig(req); err != nil {
 		h.logger.Error("failed to update config", "error", err.Error(), "request_id", reqID)
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		code := http.StatusInternalServerError
+		if errors.Is(err, usecase.ErrEgressDenied) {
+			code = http.StatusUnprocessableEntity
+		}
+		return c.JSON(code, map[string]interface{}{
 			"error":      err.Error(),
-			"code":       http.StatusInternalServerError,
+			"code":       code,
 			"request_id": reqID,
 		})
 	}
 
-	h.logger.Info("Worker received new config", "config", req.Config, "request_id", reqID)
+	h.metrics.configApplied(req.Version)
+	tracing.SetAttributes(c.Request().Context(), tracing.AttrConfigVersion.String(req.Version))
+	h.logger.Info("Worker received new config", "version", req.Version, "config", req.Config, "request_id", reqID)
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message":    "config updated",
 		"code":       http.StatusOK,
@@ -56,21 +107,168 @@ func (h *WorkerHandler) ReceiveConfig(c echo.Context) error {
 	})
 }
 
+// GetConfig reports which config the worker runs. The config itself is not
+// returned: it holds upstream credentials and this port also serves /hit.
+func (h *WorkerHandler) GetConfig(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	state := h.configManager.GetConfig()
+
+	config := state.Config
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	res := dto.WorkerConfigResponse{
+		Version:   state.Version,
+		Digest:    usecase.ConfigDigest(config),
+		Code:      http.StatusOK,
+		RequestID: reqID,
+	}
+	if !state.UpdatedAt.IsZero() {
+		res.UpdatedAt = &state.UpdatedAt
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// ValidateConfig checks a config the way ReceiveConfig would apply it, egress
+// policy included, without applying it.
+func (h *WorkerHandler) ValidateConfig(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	var req dto.ConfigRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("failed to bind request", "error", err.Error(), "request_id", reqID)
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       http.StatusBadRequest,
+			"request_id": reqID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, dto.WorkerConfigValidateResponse{
+		ConfigValidation: h.configManager.ValidateConfig(req.Config),
+		Code:             http.StatusOK,
+		RequestID:        reqID,
+	})
+}
+
+func (h *WorkerHandler) ListActions(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	return c.JSON(http.StatusOK, dto.HitActionsResponse{
+		Actions:   h.configManager.ListActions(),
+		Code:      http.StatusOK,
+		RequestID: reqID,
+	})
+}
+
 func (h *WorkerHandler) HitProxy(c echo.Context) error {
 	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
-	result, err := h.configManager.ExecuteHit()
+	req := dto.HitRequest{
+		Action:   c.Param("name"),
+		Query:    make(map[string]string),
+		ClientIP: c.RealIP(),
+		Headers:  c.Request().Header,
+	}
+	for k := range c.QueryParams() {
+		req.Query[k] = c.QueryParam(k)
+	}
+	if c.Request().Method == http.MethodPost {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, maxHitBodyBytes))
+		if err != nil {
+			return c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+				"error":      err.Error(),
+				"code":       http.StatusRequestEntityTooLarge,
+				"request_id": reqID,
+			})
+		}
+		req.Body = string(body)
+	}
+
+	start := time.Now()
+	result, err := h.configManager.ExecuteHit(c.Request().Context(), req)
+	statusCode := 0
+	if result != nil {
+		statusCode = result.StatusCode
+	}
+	h.metrics.hit(time.Since(start), statusCode, err)
 	if err != nil {
-		h.logger.Error("failed to execute hit proxy", "error", err.Error(), "request_id", reqID)
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		var limitErr *usecase.RateLimitError
+		if errors.As(err, &limitErr) {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+			return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+				"error":      err.Error(),
+				"code":       http.StatusTooManyRequests,
+				"request_id": reqID,
+			})
+		}
+		if errors.Is(err, usecase.ErrActionNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{
+				"error":      err.Error(),
+				"code":       http.StatusNotFound,
+				"request_id": reqID,
+			})
+		}
+		h.logger.Error("failed to execute hit proxy", "action", req.Action, "error", err.Error(), "request_id", reqID)
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, usecase.ErrCircuitOpen):
+			code = http.StatusServiceUnavailable
+		case errors.Is(err, usecase.ErrEgressDenied):
+			code = http.StatusForbidden
+		// The upstream answered, but not with what the action expects
+		case errors.Is(err, usecase.ErrUnexpectedStatus), errors.Is(err, usecase.ErrResponseTooLarge):
+			code = http.StatusBadGateway
+		}
+		res := map[string]interface{}{
 			"error":      "Worker Error: " + err.Error(),
-			"code":       http.StatusInternalServerError,
+			"code":       code,
 			"request_id": reqID,
-		})
+		}
+		if result != nil {
+			res["status_code"] = result.StatusCode
+		}
+		return c.JSON(code, res)
 	}
 
+	if result.Stream != nil {
+		return h.streamHit(c, result)
+	}
+	if result.Cache != "" {
+		c.Response().Header().Set("X-Cache", result.Cache)
+	}
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"result":     result,
-		"code":       http.StatusOK,
-		"request_id": reqID,
+		"result":      result.Body,
+		"status_code": result.StatusCode,
+		"headers":     result.Headers,
+		"code":        http.StatusOK,
+		"request_id":  reqID,
 	})
 }
+
+// hopHeaders only apply to the connection to the upstream.
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// streamHit copies a passthrough response to the client as it is received.
+func (h *WorkerHandler) streamHit(c echo.Context, result *dto.HitResult) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	defer result.Stream.Close()
+
+	header := c.Response().Header()
+	for k, values := range result.Headers {
+		for _, v := range values {
+			header.Add(k, v)
+		}
+	}
+	for _, k := range hopHeaders {
+		header.Del(k)
+	}
+	c.Response().WriteHeader(result.StatusCode)
+
+	if _, err := io.Copy(c.Response(), result.Stream); err != nil {
+		h.logger.Error("failed to stream hit response", "error", err.Error(), "request_id", reqID)
+		// The status is sent already, breaking the connection tells the client the body is incomplete
+		panic(http.ErrAbortHandler)
+	}
+	return nil
+}
@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"config-manager/internal/dto"
 	"config-manager/internal/logger"
+	"config-manager/internal/usecase"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -25,9 +31,27 @@ func (m *MockConfigManager) UpdateConfig(req dto.ConfigRequest) error {
 	return args.Error(0)
 }
 
-func (m *MockConfigManager) ExecuteHit() (string, error) {
+func (m *MockConfigManager) GetConfig() dto.WorkerState {
 	args := m.Called()
-	return args.String(0), args.Error(1)
+	return args.Get(0).(dto.WorkerState)
+}
+
+func (m *MockConfigManager) ExecuteHit(ctx context.Context, req dto.HitRequest) (*dto.HitResult, error) {
+	args := m.Called(req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*dto.HitResult), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockConfigManager) ListActions() []dto.HitActionInfo {
+	args := m.Called()
+	return args.Get(0).([]dto.HitActionInfo)
+}
+
+func (m *MockConfigManager) ValidateConfig(config map[string]interface{}) dto.ConfigValidation {
+	args := m.Called(config)
+	return args.Get(0).(dto.ConfigValidation)
 }
 
 func TestWorkerHandler_ReceiveConfig(t *testing.T) {
@@ -85,6 +109,107 @@ func TestWorkerHandler_ReceiveConfig(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 		mockManager.AssertExpectations(t)
 	})
+
+	t.Run("Egress Denied", func(t *testing.T) {
+		reqBody := dto.ConfigRequest{Config: map[string]interface{}{"url": "http://169.254.169.254/"}}
+		bodyBytes, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/v1/config", bytes.NewBuffer(bodyBytes))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockManager.On("UpdateConfig", reqBody).Return(fmt.Errorf("%w: address 169.254.169.254 is not allowed", usecase.ErrEgressDenied)).Once()
+
+		assert.NoError(t, h.ReceiveConfig(c))
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), "egress denied")
+		mockManager.AssertExpectations(t)
+	})
+}
+
+func TestWorkerHandler_ValidateConfig(t *testing.T) {
+	e := echo.New()
+	mockManager := new(MockConfigManager)
+	h := &WorkerHandler{configManager: mockManager, logger: logger.NewLogger()}
+
+	t.Run("Reports Issues Without Applying", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/config/validate", strings.NewReader(`{"config":{"url":"http://169.254.169.254/"}}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		mockManager.On("ValidateConfig", map[string]interface{}{"url": "http://169.254.169.254/"}).Return(dto.ConfigValidation{
+			Issues: []dto.ConfigIssue{{Error: "egress denied: address 169.254.169.254 is not allowed"}},
+		}).Once()
+
+		assert.NoError(t, h.ValidateConfig(e.NewContext(req, rec)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var res dto.WorkerConfigValidateResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.False(t, res.Valid)
+		assert.Len(t, res.Issues, 1)
+		mockManager.AssertExpectations(t)
+		mockManager.AssertNotCalled(t, "UpdateConfig", mock.Anything)
+	})
+
+	t.Run("Bind Error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/config/validate", strings.NewReader("{invalid_json}"))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		assert.NoError(t, h.ValidateConfig(e.NewContext(req, rec)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestWorkerHandler_GetConfig(t *testing.T) {
+	e := echo.New()
+	log := logger.NewLogger()
+	mockManager := new(MockConfigManager)
+
+	h := &WorkerHandler{
+		configManager: mockManager,
+		logger:        log,
+	}
+
+	t.Run("Configured", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/config", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		updatedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		mockManager.On("GetConfig").Return(dto.WorkerState{
+			Version:   "v1",
+			Config:    map[string]interface{}{"url": "http://example.com"},
+			UpdatedAt: updatedAt,
+		}).Once()
+
+		err := h.GetConfig(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var res dto.WorkerConfigResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, "v1", res.Version)
+		assert.Equal(t, usecase.ConfigDigest(map[string]interface{}{"url": "http://example.com"}), res.Digest)
+		assert.NotContains(t, rec.Body.String(), "example.com", "the config may hold upstream credentials")
+		assert.True(t, updatedAt.Equal(*res.UpdatedAt))
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("Not Configured", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/config", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockManager.On("GetConfig").Return(dto.WorkerState{}).Once()
+
+		err := h.GetConfig(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"version":"","digest":"44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a","code":200,"request_id":""}`, rec.Body.String())
+	})
 }
 
 func TestWorkerHandler_HitProxy(t *testing.T) {
@@ -102,7 +227,7 @@ func TestWorkerHandler_HitProxy(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		mockManager.On("ExecuteHit").Return("success body", nil).Once()
+		mockManager.On("ExecuteHit", mock.Anything).Return(&dto.HitResult{StatusCode: http.StatusOK, Body: "success body"}, nil).Once()
 
 		err := h.HitProxy(c)
 
@@ -111,12 +236,131 @@ func TestWorkerHandler_HitProxy(t *testing.T) {
 		mockManager.AssertExpectations(t)
 	})
 
+	t.Run("Passes Query And Returns Upstream Response", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/hit?name=widget", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		matchesRequest := mock.MatchedBy(func(r dto.HitRequest) bool {
+			return r.Query["name"] == "widget" && r.ClientIP == "192.0.2.1"
+		})
+		mockManager.On("ExecuteHit", matchesRequest).Return(&dto.HitResult{
+			StatusCode: http.StatusCreated,
+			Headers:    http.Header{"Content-Type": {"application/json"}},
+			Body:       `{"id":1}`,
+		}, nil).Once()
+
+		assert.NoError(t, h.HitProxy(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var res struct {
+			Result     string              `json:"result"`
+			StatusCode int                 `json:"status_code"`
+			Headers    map[string][]string `json:"headers"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, `{"id":1}`, res.Result)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, []string{"application/json"}, res.Headers["Content-Type"])
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("Named Action With Body", func(t *testing.T) {
+		e := echo.New()
+		mockManager.On("GetConfig").Return(dto.WorkerState{}).Once()
+		NewWorkerHandler(e, mockManager, nil, log)
+
+		matchesRequest := mock.MatchedBy(func(r dto.HitRequest) bool {
+			return r.Action == "create" && r.Body == `{"id":1}`
+		})
+		mockManager.On("ExecuteHit", matchesRequest).Return(&dto.HitResult{StatusCode: http.StatusCreated}, nil).Once()
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hit/create", strings.NewReader(`{"id":1}`)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		mockManager.On("ExecuteHit", mock.Anything).Return(nil, fmt.Errorf("%w: missing", usecase.ErrActionNotFound)).Once()
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hit/missing", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("Passthrough", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/hit", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		binary := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+		mockManager.On("ExecuteHit", mock.Anything).Return(&dto.HitResult{
+			StatusCode: http.StatusAccepted,
+			Headers:    http.Header{"Content-Type": {"image/png"}, "Connection": {"close"}},
+			Stream:     io.NopCloser(bytes.NewReader(binary)),
+		}, nil).Once()
+
+		assert.NoError(t, h.HitProxy(c))
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+		assert.Empty(t, rec.Header().Get("Connection"))
+		assert.Equal(t, binary, rec.Body.Bytes())
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("Unexpected Upstream Status", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/hit", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockManager.On("ExecuteHit", mock.Anything).Return(&dto.HitResult{StatusCode: http.StatusNotFound},
+			fmt.Errorf("%w 404", usecase.ErrUnexpectedStatus)).Once()
+
+		assert.NoError(t, h.HitProxy(c))
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"status_code":404`)
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("Rate Limited", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/hit", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockManager.On("ExecuteHit", mock.Anything).Return(nil, &usecase.RateLimitError{RetryAfter: 1500 * time.Millisecond}).Once()
+
+		assert.NoError(t, h.HitProxy(c))
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("Circuit Open", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/hit", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockManager.On("ExecuteHit", mock.Anything).Return(nil, usecase.ErrCircuitOpen).Once()
+
+		assert.NoError(t, h.HitProxy(c))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		mockManager.AssertExpectations(t)
+	})
+
+	t.Run("Egress Denied", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/hit", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		mockManager.On("ExecuteHit", mock.Anything).Return(nil, fmt.Errorf("%w: address 10.0.0.1 is not allowed", usecase.ErrEgressDenied)).Once()
+
+		assert.NoError(t, h.HitProxy(c))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		mockManager.AssertExpectations(t)
+	})
+
 	t.Run("Manager Error", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/hit", nil)
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		mockManager.On("ExecuteHit").Return("", errors.New("hit error")).Once()
+		mockManager.On("ExecuteHit", mock.Anything).Return(nil, errors.New("hit error")).Once()
 
 		err := h.HitProxy(c)
 
@@ -125,3 +369,54 @@ func TestWorkerHandler_HitProxy(t *testing.T) {
 		mockManager.AssertExpectations(t)
 	})
 }
+
+func TestClientIPExtractor(t *testing.T) {
+	newRequest := func(remoteAddr, forwardedFor string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/hit", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		req.Header.Set(echo.HeaderXRealIP, "198.51.100.99")
+		return req
+	}
+
+	t.Run("Peer Address By Default", func(t *testing.T) {
+		extract, err := ClientIPExtractor(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "203.0.113.7", extract(newRequest("203.0.113.7:4321", "198.51.100.1")))
+		assert.Equal(t, "127.0.0.1", extract(newRequest("127.0.0.1:4321", "198.51.100.1")))
+	})
+
+	t.Run("Trusted Proxies", func(t *testing.T) {
+		extract, err := ClientIPExtractor([]string{"10.0.0.0/8", " "})
+		assert.NoError(t, err)
+		assert.Equal(t, "198.51.100.1", extract(newRequest("10.0.0.5:4321", "198.51.100.1, 10.0.0.9")))
+		assert.Equal(t, "198.51.100.2", extract(newRequest("10.0.0.5:4321", "198.51.100.1, 198.51.100.2")), "only the nearest untrusted entry counts")
+		assert.Equal(t, "192.168.1.1", extract(newRequest("192.168.1.1:4321", "198.51.100.1")), "private peers are not trusted unless listed")
+	})
+
+	t.Run("Invalid CIDR", func(t *testing.T) {
+		_, err := ClientIPExtractor([]string{"10.0.0.1"})
+		assert.ErrorContains(t, err, `invalid trusted proxy CIDR "10.0.0.1"`)
+	})
+}
+
+func TestWorkerHandler_ListActions(t *testing.T) {
+	e := echo.New()
+	mockManager := new(MockConfigManager)
+	mockManager.On("GetConfig").Return(dto.WorkerState{})
+	NewWorkerHandler(e, mockManager, nil, logger.NewLogger())
+
+	actions := []dto.HitActionInfo{
+		{Name: "", Path: "/hit", Method: http.MethodGet, URL: "http://upstream"},
+		{Name: "create", Path: "/hit/create", Error: "URL not configured"},
+	}
+	mockManager.On("ListActions").Return(actions).Once()
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/actions", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var res dto.HitActionsResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, actions, res.Actions)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"config-manager/internal/dto"
+	"config-manager/internal/usecase"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"config-manager/pkg/shared/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WorkerRegistryHandler serves the agent's worker registration API.
+type WorkerRegistryHandler struct {
+	registry usecase.WorkerRegistry
+	onChange func() // applies registry changes, e.g. pushes the current config to a new worker
+	logger   *slog.Logger
+}
+
+func NewWorkerRegistryHandler(e *echo.Echo, registry usecase.WorkerRegistry, onChange func(), logger *slog.Logger, authToken string) {
+	handler := &WorkerRegistryHandler{
+		registry: registry,
+		onChange: onChange,
+		logger:   logger,
+	}
+
+	v1 := e.Group("/v1")
+	v1.POST("/workers", handler.Register, middleware.StaticTokenAuth("Authorization", authToken))
+	v1.DELETE("/workers", handler.Deregister, middleware.StaticTokenAuth("Authorization", authToken))
+	v1.GET("/workers", handler.List, middleware.StaticTokenAuth("Authorization", authToken))
+}
+
+func (h *WorkerRegistryHandler) Register(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	var req dto.WorkerRegistrationRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("failed to bind request", "error", err.Error(), "request_id", reqID)
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       http.StatusBadRequest,
+			"request_id": reqID,
+		})
+	}
+
+	joined, err := h.registry.Register(req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, usecase.ErrInvalidWorkerURL) {
+			status = http.StatusBadRequest
+		}
+		h.logger.Error("failed to register worker", "error", err.Error(), "request_id", reqID)
+		return c.JSON(status, map[string]interface{}{
+			"error":      err.Error(),
+			"code":       status,
+			"request_id": reqID,
+		})
+	}
+
+	if joined {
+		h.logger.Info("Worker registered", "worker_url", req.URL, "namespaces", req.Namespaces, "capabilities", req.Capabilities, "request_id", reqID)
+		h.onChange()
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":    "worker registered",
+		"code":       http.StatusOK,
+		"request_id": reqID,
+	})
+}
+
+func (h *WorkerRegistryHandler) Deregister(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	workerURL := c.QueryParam("url")
+	if workerURL == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":      "url is required",
+			"code":       http.StatusBadRequest,
+			"request_id": reqID,
+		})
+	}
+
+	if !h.registry.Deregister(workerURL) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error":      "worker not registered",
+			"code":       http.StatusNotFound,
+			"request_id": reqID,
+		})
+	}
+
+	h.logger.Info("Worker deregistered", "worker_url", workerURL, "request_id", reqID)
+	h.onChange()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":    "worker deregistered",
+		"code":       http.StatusOK,
+		"request_id": reqID,
+	})
+}
+
+func (h *WorkerRegistryHandler) List(c echo.Context) error {
+	reqID := c.Response().Header().Get(echo.HeaderXRequestID)
+	return c.JSON(http.StatusOK, dto.RegisteredWorkersResponse{
+		Workers:   h.registry.List(),
+		Code:      http.StatusOK,
+		RequestID: reqID,
+	})
+}
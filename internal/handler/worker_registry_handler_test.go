@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"config-manager/internal/dto"
+	"config-manager/internal/logger"
+	"config-manager/internal/usecase"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerRegistryHandler(t *testing.T) {
+	e := echo.New()
+	registry := usecase.NewWorkerRegistry(time.Minute)
+	changes := 0
+	NewWorkerRegistryHandler(e, registry, func() { changes++ }, logger.NewLogger(), "agent-secret")
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("Authorization", "agent-secret")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Register Applies New Workers Only", func(t *testing.T) {
+		rec := do(http.MethodPost, "/v1/workers", `{"url":"http://worker-a:8082","namespaces":["edge"]}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 1, changes)
+
+		rec = do(http.MethodPost, "/v1/workers", `{"url":"http://worker-a:8082","namespaces":["edge"]}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 1, changes)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		rec := do(http.MethodGet, "/v1/workers", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var res dto.RegisteredWorkersResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Len(t, res.Workers, 1)
+		assert.Equal(t, []string{"edge"}, res.Workers[0].Namespaces)
+	})
+
+	t.Run("Register Invalid", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/v1/workers", `{"url":"worker-a"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/v1/workers", `invalid json`).Code)
+	})
+
+	t.Run("Deregister", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodDelete, "/v1/workers", "").Code)
+
+		rec := do(http.MethodDelete, "/v1/workers?url=http%3A%2F%2Fworker-a%3A8082", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 2, changes)
+
+		rec = do(http.MethodDelete, "/v1/workers?url=http%3A%2F%2Fworker-a%3A8082", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/workers", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
@@ -0,0 +1,148 @@
+package migration
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	return db
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.NotEmpty(t, m.Up, "migration %d has no up script", m.Version)
+		assert.NotEmpty(t, m.Down, "migration %d has no down script", m.Version)
+		if i > 0 {
+			assert.Greater(t, m.Version, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestLoadFS_Invalid(t *testing.T) {
+	t.Run("Bad Version", func(t *testing.T) {
+		fsys := fstest.MapFS{"sql/abc_init.up.sql": {Data: []byte("SELECT 1;")}}
+		_, err := loadFS(fsys, "sql")
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing Up", func(t *testing.T) {
+		fsys := fstest.MapFS{"sql/0001_init.down.sql": {Data: []byte("SELECT 1;")}}
+		_, err := loadFS(fsys, "sql")
+		assert.Error(t, err)
+	})
+
+	t.Run("Conflicting Names", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"sql/0001_init.up.sql":  {Data: []byte("SELECT 1;")},
+			"sql/0001_other.up.sql": {Data: []byte("SELECT 1;")},
+		}
+		_, err := loadFS(fsys, "sql")
+		assert.Error(t, err)
+	})
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	db := setupTestDB(t)
+	m, err := NewMigrator(db)
+	require.NoError(t, err)
+
+	t.Run("Check Before Up", func(t *testing.T) {
+		err := m.Check()
+		assert.ErrorIs(t, err, ErrSchemaBehind)
+	})
+
+	t.Run("Up Applies All", func(t *testing.T) {
+		count, err := m.Up()
+		assert.NoError(t, err)
+		assert.Equal(t, len(m.migrations), count)
+		assert.NoError(t, m.Check())
+		assert.True(t, db.Migrator().HasTable("agents"))
+		assert.True(t, db.Migrator().HasTable("global_configs"))
+	})
+
+	t.Run("Up Is Idempotent", func(t *testing.T) {
+		count, err := m.Up()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		statuses, err := m.Status()
+		assert.NoError(t, err)
+		assert.Len(t, statuses, len(m.migrations))
+		for _, s := range statuses {
+			assert.True(t, s.Applied)
+			assert.NotNil(t, s.AppliedAt)
+		}
+	})
+
+	t.Run("Down Rolls Back Latest", func(t *testing.T) {
+		mig, err := m.Down()
+		assert.NoError(t, err)
+		assert.NotNil(t, mig)
+		assert.Equal(t, m.LatestVersion(), mig.Version)
+		assert.ErrorIs(t, m.Check(), ErrSchemaBehind)
+	})
+
+	t.Run("Down To Empty", func(t *testing.T) {
+		for {
+			mig, err := m.Down()
+			assert.NoError(t, err)
+			if mig == nil {
+				break
+			}
+		}
+		assert.False(t, db.Migrator().HasTable("agents"))
+		assert.False(t, db.Migrator().HasTable("global_configs"))
+	})
+}
+
+func TestMigrator_CheckAhead(t *testing.T) {
+	db := setupTestDB(t)
+	m, err := NewMigrator(db)
+	require.NoError(t, err)
+
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	// Simulate a newer binary having migrated the database.
+	future := m.LatestVersion() + 1
+	require.NoError(t, db.Create(&SchemaMigration{Version: future, Name: "future"}).Error)
+
+	assert.ErrorIs(t, m.Check(), ErrSchemaAhead)
+
+	_, err = m.Down()
+	assert.ErrorIs(t, err, ErrSchemaAhead)
+}
+
+func TestMigrator_AdoptsAutoMigratedSchema(t *testing.T) {
+	db := setupTestDB(t)
+
+	// Databases created before versioned migrations already contain the tables.
+	require.NoError(t, db.Exec("CREATE TABLE `agents` (`id` text,`name` text,`created_at` datetime,PRIMARY KEY (`id`))").Error)
+	require.NoError(t, db.Exec("INSERT INTO `agents` (`id`, `name`) VALUES ('a1', 'legacy')").Error)
+
+	m, err := NewMigrator(db)
+	require.NoError(t, err)
+
+	_, err = m.Up()
+	assert.NoError(t, err)
+
+	var count int64
+	db.Table("agents").Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `global_configs`;
+DROP TABLE IF EXISTS `agents`;
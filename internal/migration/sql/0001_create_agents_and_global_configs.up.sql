@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `agents` (
+    `id` text,
+    `name` text,
+    `created_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `global_configs` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `config` text,
+    `version` text,
+    `created_at` datetime
+);
@@ -0,0 +1,7 @@
+ALTER TABLE `agents` DROP COLUMN `last_seen_at`;
+ALTER TABLE `agents` DROP COLUMN `applied_version`;
+
+DROP INDEX IF EXISTS `idx_global_configs_version`;
+DROP INDEX IF EXISTS `idx_global_configs_created_at`;
+ALTER TABLE `global_configs` DROP COLUMN `pinned`;
+ALTER TABLE `global_configs` DROP COLUMN `tag`;
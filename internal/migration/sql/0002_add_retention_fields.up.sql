@@ -0,0 +1,7 @@
+ALTER TABLE `global_configs` ADD COLUMN `tag` text NOT NULL DEFAULT '';
+ALTER TABLE `global_configs` ADD COLUMN `pinned` numeric NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS `idx_global_configs_created_at` ON `global_configs` (`created_at`);
+CREATE INDEX IF NOT EXISTS `idx_global_configs_version` ON `global_configs` (`version`);
+
+ALTER TABLE `agents` ADD COLUMN `applied_version` text NOT NULL DEFAULT '';
+ALTER TABLE `agents` ADD COLUMN `last_seen_at` datetime;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `leader_leases` (
+    `name` text,
+    `holder_id` text NOT NULL DEFAULT '',
+    `holder_url` text NOT NULL DEFAULT '',
+    `expires_at` datetime,
+    `updated_at` datetime,
+    PRIMARY KEY (`name`)
+);
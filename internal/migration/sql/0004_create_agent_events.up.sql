@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS `agent_events` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `agent_id` text NOT NULL,
+    `type` text NOT NULL,
+    `desired_version` text NOT NULL DEFAULT '',
+    `actual_version` text NOT NULL DEFAULT '',
+    `message` text NOT NULL DEFAULT '',
+    `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_agent_events_agent_id_created_at` ON `agent_events` (`agent_id`, `created_at`);
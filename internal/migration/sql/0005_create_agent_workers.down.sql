@@ -0,0 +1,2 @@
+ALTER TABLE `agent_events` DROP COLUMN `worker_url`;
+DROP TABLE IF EXISTS `agent_workers`;
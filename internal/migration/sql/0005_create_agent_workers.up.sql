@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `agent_workers` (
+    `agent_id` text NOT NULL,
+    `url` text NOT NULL,
+    `applied_version` text NOT NULL DEFAULT '',
+    `pending_version` text NOT NULL DEFAULT '',
+    `last_error` text NOT NULL DEFAULT '',
+    `updated_at` datetime,
+    PRIMARY KEY (`agent_id`, `url`)
+);
+CREATE INDEX IF NOT EXISTS `idx_agent_workers_applied_version` ON `agent_workers` (`applied_version`);
+
+ALTER TABLE `agent_events` ADD COLUMN `worker_url` text NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE `agents` ADD COLUMN `url` text NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE `agents` ADD COLUMN `validate_token` text NOT NULL DEFAULT '';
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"config-manager/internal/domain"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -9,6 +10,13 @@ import (
 type AgentRepository interface {
 	Create(agent *domain.Agent) error
 	GetByID(id string) (*domain.Agent, error)
+	UpdateHeartbeat(id, appliedVersion string, seenAt time.Time, workers []domain.AgentWorker) error
+	ListAppliedVersions(seenSince time.Time) ([]string, error)
+	CountByAppliedVersion() (map[string]int, error)
+	CreateEvent(event *domain.AgentEvent) error
+	ListEvents(agentID string, limit int) ([]domain.AgentEvent, error)
+	ListWorkers(agentID string) ([]domain.AgentWorker, error)
+	ListReachable(seenSince time.Time) ([]domain.Agent, error)
 }
 
 type agentRepository struct {
@@ -30,3 +38,97 @@ func (r *agentRepository) GetByID(id string) (*domain.Agent, error) {
 	}
 	return &agent, nil
 }
+
+// UpdateHeartbeat records an agent heartbeat. A non-nil workers slice replaces
+// the per-worker results stored for the agent, nil leaves them untouched.
+func (r *agentRepository) UpdateHeartbeat(id, appliedVersion string, seenAt time.Time, workers []domain.AgentWorker) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&domain.Agent{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"applied_version": appliedVersion,
+			"last_seen_at":    seenAt,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if workers == nil {
+			return nil
+		}
+		if err := tx.Where("agent_id = ?", id).Delete(&domain.AgentWorker{}).Error; err != nil {
+			return err
+		}
+		if len(workers) == 0 {
+			return nil
+		}
+		for i := range workers {
+			workers[i].AgentID = id
+			workers[i].UpdatedAt = seenAt
+		}
+		return tx.Create(&workers).Error
+	})
+}
+
+// ListAppliedVersions returns the distinct config versions currently applied by
+// agents seen since seenSince or any of their workers. Agents register anew on
+// every start, so older rows are left behind by agents that are gone.
+func (r *agentRepository) ListAppliedVersions(seenSince time.Time) ([]string, error) {
+	var versions []string
+	err := r.db.Raw("SELECT applied_version FROM agents WHERE applied_version <> '' AND last_seen_at >= ? "+
+		"UNION SELECT w.applied_version FROM agent_workers w JOIN agents a ON a.id = w.agent_id "+
+		"WHERE w.applied_version <> '' AND a.last_seen_at >= ?", seenSince, seenSince).
+		Scan(&versions).Error
+	return versions, err
+}
+
+// CountByAppliedVersion returns the number of registered agents per applied
+// version; agents that have not applied a config yet are counted under "".
+func (r *agentRepository) CountByAppliedVersion() (map[string]int, error) {
+	var rows []struct {
+		AppliedVersion string
+		Count          int
+	}
+	err := r.db.Model(&domain.Agent{}).
+		Select("applied_version, COUNT(*) AS count").
+		Group("applied_version").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.AppliedVersion] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *agentRepository) CreateEvent(event *domain.AgentEvent) error {
+	return r.db.Create(event).Error
+}
+
+// ListEvents returns the most recent events reported by an agent, newest first.
+func (r *agentRepository) ListEvents(agentID string, limit int) ([]domain.AgentEvent, error) {
+	var events []domain.AgentEvent
+	err := r.db.Where("agent_id = ?", agentID).
+		Order("created_at desc, id desc").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *agentRepository) ListWorkers(agentID string) ([]domain.AgentWorker, error) {
+	var workers []domain.AgentWorker
+	err := r.db.Where("agent_id = ?", agentID).Order("url asc").Find(&workers).Error
+	return workers, err
+}
+
+// ListReachable returns the agents that advertised a URL, and so were issued a
+// validate token, and were seen since seenSince.
+func (r *agentRepository) ListReachable(seenSince time.Time) ([]domain.Agent, error) {
+	var agents []domain.Agent
+	err := r.db.Where("url <> '' AND validate_token <> '' AND last_seen_at >= ?", seenSince).Order("id asc").Find(&agents).Error
+	return agents, err
+}
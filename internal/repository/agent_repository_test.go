@@ -2,6 +2,7 @@ package repository
 
 import (
 	"config-manager/internal/domain"
+	"config-manager/internal/migration"
 	"testing"
 	"time"
 
@@ -17,8 +18,11 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	}
 
 	// Migrate the schema
-	err = db.AutoMigrate(&domain.Agent{}, &domain.GlobalConfig{})
+	migrator, err := migration.NewMigrator(db)
 	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
 	return db
@@ -54,3 +58,159 @@ func TestAgentRepository_CreateAndGet(t *testing.T) {
 		assert.Equal(t, gorm.ErrRecordNotFound, err)
 	})
 }
+
+func TestAgentRepository_Heartbeat(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewAgentRepository(db)
+
+	assert.NoError(t, repo.Create(&domain.Agent{ID: "agent-hb-1", Name: "a", CreatedAt: time.Now()}))
+	assert.NoError(t, repo.Create(&domain.Agent{ID: "agent-hb-2", Name: "b", CreatedAt: time.Now()}))
+
+	t.Run("UpdateHeartbeat Success", func(t *testing.T) {
+		seenAt := time.Now()
+		err := repo.UpdateHeartbeat("agent-hb-1", "v1", seenAt, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, repo.UpdateHeartbeat("agent-hb-2", "v1", seenAt, nil))
+
+		fetched, err := repo.GetByID("agent-hb-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", fetched.AppliedVersion)
+		assert.NotNil(t, fetched.LastSeenAt)
+	})
+
+	t.Run("UpdateHeartbeat Not Found", func(t *testing.T) {
+		err := repo.UpdateHeartbeat("invalid-id", "v1", time.Now(), nil)
+		assert.Equal(t, gorm.ErrRecordNotFound, err)
+	})
+
+	t.Run("ListAppliedVersions Distinct", func(t *testing.T) {
+		versions, err := repo.ListAppliedVersions(time.Now().Add(-time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"v1"}, versions)
+	})
+}
+
+func TestAgentRepository_Workers(t *testing.T) {
+	repo := NewAgentRepository(setupIsolatedTestDB(t))
+	assert.NoError(t, repo.Create(&domain.Agent{ID: "agent-w-1", Name: "a", CreatedAt: time.Now()}))
+
+	seenAt := time.Now()
+	assert.NoError(t, repo.UpdateHeartbeat("agent-w-1", "v2", seenAt, []domain.AgentWorker{
+		{URL: "http://worker-b:8082", AppliedVersion: "v1", PendingVersion: "v2", LastError: "connection refused"},
+		{URL: "http://worker-a:8082", AppliedVersion: "v2"},
+	}))
+
+	t.Run("ListWorkers Ordered By URL", func(t *testing.T) {
+		workers, err := repo.ListWorkers("agent-w-1")
+		assert.NoError(t, err)
+		assert.Len(t, workers, 2)
+		assert.Equal(t, "http://worker-a:8082", workers[0].URL)
+		assert.Equal(t, "http://worker-b:8082", workers[1].URL)
+		assert.Equal(t, "v2", workers[1].PendingVersion)
+		assert.Equal(t, "connection refused", workers[1].LastError)
+	})
+
+	t.Run("ListAppliedVersions Includes Workers", func(t *testing.T) {
+		versions, err := repo.ListAppliedVersions(seenAt)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"v1", "v2"}, versions)
+	})
+
+	t.Run("Nil Workers Keeps Results", func(t *testing.T) {
+		assert.NoError(t, repo.UpdateHeartbeat("agent-w-1", "v2", seenAt, nil))
+		workers, err := repo.ListWorkers("agent-w-1")
+		assert.NoError(t, err)
+		assert.Len(t, workers, 2)
+	})
+
+	t.Run("Workers Are Replaced", func(t *testing.T) {
+		assert.NoError(t, repo.UpdateHeartbeat("agent-w-1", "v2", seenAt, []domain.AgentWorker{
+			{URL: "http://worker-a:8082", AppliedVersion: "v2"},
+		}))
+		workers, err := repo.ListWorkers("agent-w-1")
+		assert.NoError(t, err)
+		assert.Len(t, workers, 1)
+		assert.Equal(t, "http://worker-a:8082", workers[0].URL)
+
+		versions, err := repo.ListAppliedVersions(seenAt)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"v2"}, versions)
+	})
+
+	t.Run("ListAppliedVersions Skips Stale Agents", func(t *testing.T) {
+		// Left behind by an agent that restarted under a new ID an hour ago
+		assert.NoError(t, repo.Create(&domain.Agent{ID: "agent-w-stale", Name: "a", CreatedAt: seenAt.Add(-2 * time.Hour)}))
+		assert.NoError(t, repo.UpdateHeartbeat("agent-w-stale", "v0", seenAt.Add(-time.Hour), []domain.AgentWorker{
+			{URL: "http://worker-a:8082", AppliedVersion: "v-1"},
+		}))
+
+		versions, err := repo.ListAppliedVersions(seenAt.Add(-time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"v2"}, versions)
+
+		versions, err = repo.ListAppliedVersions(time.Time{})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"v0", "v-1", "v2"}, versions)
+	})
+}
+
+func TestAgentRepository_CountByAppliedVersion(t *testing.T) {
+	repo := NewAgentRepository(setupIsolatedTestDB(t))
+	for _, id := range []string{"agent-c-1", "agent-c-2", "agent-c-3"} {
+		assert.NoError(t, repo.Create(&domain.Agent{ID: id, Name: id, CreatedAt: time.Now()}))
+	}
+	assert.NoError(t, repo.UpdateHeartbeat("agent-c-1", "v1", time.Now(), nil))
+	assert.NoError(t, repo.UpdateHeartbeat("agent-c-2", "v1", time.Now(), nil))
+
+	counts, err := repo.CountByAppliedVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"v1": 2, "": 1}, counts)
+}
+
+func TestAgentRepository_ListReachable(t *testing.T) {
+	repo := NewAgentRepository(setupIsolatedTestDB(t))
+	now := time.Now()
+	assert.NoError(t, repo.Create(&domain.Agent{ID: "agent-r-1", URL: "http://agent-1:8081", ValidateToken: "token-1", CreatedAt: now}))
+	assert.NoError(t, repo.Create(&domain.Agent{ID: "agent-r-2", URL: "http://agent-2:8081", ValidateToken: "token-2", CreatedAt: now}))
+	assert.NoError(t, repo.Create(&domain.Agent{ID: "agent-r-3", CreatedAt: now}))
+	assert.NoError(t, repo.Create(&domain.Agent{ID: "agent-r-4", URL: "http://agent-4:8081", CreatedAt: now}))
+	assert.NoError(t, repo.UpdateHeartbeat("agent-r-1", "v1", now, nil))
+	assert.NoError(t, repo.UpdateHeartbeat("agent-r-2", "v1", now.Add(-time.Hour), nil))
+	assert.NoError(t, repo.UpdateHeartbeat("agent-r-3", "v1", now, nil))
+	assert.NoError(t, repo.UpdateHeartbeat("agent-r-4", "v1", now, nil))
+
+	agents, err := repo.ListReachable(now.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, agents, 1)
+	assert.Equal(t, "http://agent-1:8081", agents[0].URL)
+	assert.Equal(t, "token-1", agents[0].ValidateToken)
+}
+
+func TestAgentRepository_Events(t *testing.T) {
+	repo := NewAgentRepository(setupIsolatedTestDB(t))
+
+	now := time.Now()
+	for i, version := range []string{"v1", "v2", "v3"} {
+		assert.NoError(t, repo.CreateEvent(&domain.AgentEvent{
+			AgentID:        "agent-ev-1",
+			Type:           domain.AgentEventDrift,
+			DesiredVersion: version,
+			CreatedAt:      now.Add(time.Duration(i) * time.Second),
+		}))
+	}
+	assert.NoError(t, repo.CreateEvent(&domain.AgentEvent{AgentID: "agent-ev-2", Type: domain.AgentEventDrift, CreatedAt: now}))
+
+	t.Run("Newest First With Limit", func(t *testing.T) {
+		events, err := repo.ListEvents("agent-ev-1", 2)
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, "v3", events[0].DesiredVersion)
+		assert.Equal(t, "v2", events[1].DesiredVersion)
+	})
+
+	t.Run("Unknown Agent", func(t *testing.T) {
+		events, err := repo.ListEvents("missing", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+	})
+}
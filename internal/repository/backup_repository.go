@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"config-manager/internal/domain"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrRestoreTargetNotEmpty is returned when restoring into a database that already holds data.
+var ErrRestoreTargetNotEmpty = errors.New("restore target database is not empty")
+
+type BackupRepository interface {
+	Snapshot() (*domain.Snapshot, error)
+	Restore(snapshot *domain.Snapshot, overwrite bool) error
+	SchemaVersion() (int, error)
+}
+
+type backupRepository struct {
+	db *gorm.DB
+}
+
+func NewBackupRepository(db *gorm.DB) BackupRepository {
+	return &backupRepository{db: db}
+}
+
+// Snapshot reads every table inside a single transaction so the copy is consistent
+// even while agents keep registering and configs keep being saved.
+func (r *backupRepository) Snapshot() (*domain.Snapshot, error) {
+	snapshot := &domain.Snapshot{TakenAt: time.Now()}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		version, err := schemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		snapshot.SchemaVersion = version
+
+		if err := tx.Order("created_at asc").Find(&snapshot.Agents).Error; err != nil {
+			return err
+		}
+		if err := tx.Order("agent_id asc, url asc").Find(&snapshot.Workers).Error; err != nil {
+			return err
+		}
+		if err := tx.Order("id asc").Find(&snapshot.Configs).Error; err != nil {
+			return err
+		}
+		return tx.Order("id asc").Find(&snapshot.Events).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Restore loads a snapshot in a single transaction. Unless overwrite is set the
+// target must be empty, so a restore never silently merges two histories.
+func (r *backupRepository) Restore(snapshot *domain.Snapshot, overwrite bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if overwrite {
+			if err := tx.Where("1 = 1").Delete(&domain.AgentEvent{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("1 = 1").Delete(&domain.AgentWorker{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("1 = 1").Delete(&domain.GlobalConfig{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("1 = 1").Delete(&domain.Agent{}).Error; err != nil {
+				return err
+			}
+		} else {
+			var agents, workers, configs, events int64
+			if err := tx.Model(&domain.Agent{}).Count(&agents).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&domain.AgentWorker{}).Count(&workers).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&domain.GlobalConfig{}).Count(&configs).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&domain.AgentEvent{}).Count(&events).Error; err != nil {
+				return err
+			}
+			if agents > 0 || workers > 0 || configs > 0 || events > 0 {
+				return ErrRestoreTargetNotEmpty
+			}
+		}
+
+		if len(snapshot.Agents) > 0 {
+			if err := tx.CreateInBatches(snapshot.Agents, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(snapshot.Workers) > 0 {
+			if err := tx.CreateInBatches(snapshot.Workers, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(snapshot.Configs) > 0 {
+			if err := tx.CreateInBatches(snapshot.Configs, 100).Error; err != nil {
+				return err
+			}
+		}
+		if len(snapshot.Events) > 0 {
+			if err := tx.CreateInBatches(snapshot.Events, 100).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SchemaVersion returns the highest applied migration version.
+func (r *backupRepository) SchemaVersion() (int, error) {
+	return schemaVersion(r.db)
+}
+
+func schemaVersion(db *gorm.DB) (int, error) {
+	var version int
+	err := db.Raw("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version).Error
+	return version, err
+}
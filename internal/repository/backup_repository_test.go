@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"config-manager/internal/domain"
+	"config-manager/internal/migration"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupIsolatedTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+func TestBackupRepository_SnapshotAndRestore(t *testing.T) {
+	source := NewBackupRepository(setupIsolatedTestDB(t))
+	agentRepo := NewAgentRepository(source.(*backupRepository).db)
+	configRepo := NewConfigRepository(source.(*backupRepository).db)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	assert.NoError(t, agentRepo.Create(&domain.Agent{ID: "agent-1", Name: "a", URL: "http://agent-1:8081", ValidateToken: "agent-1-token", CreatedAt: now}))
+	assert.NoError(t, configRepo.Save(&domain.GlobalConfig{Version: "v1", Config: `{"url":"a"}`, CreatedAt: now}))
+	assert.NoError(t, configRepo.Save(&domain.GlobalConfig{Version: "v2", Config: `{"url":"b"}`, CreatedAt: now.Add(time.Minute)}))
+	assert.NoError(t, agentRepo.CreateEvent(&domain.AgentEvent{AgentID: "agent-1", Type: domain.AgentEventDrift, DesiredVersion: "v2", CreatedAt: now}))
+	assert.NoError(t, agentRepo.UpdateHeartbeat("agent-1", "v1", now, []domain.AgentWorker{{URL: "http://worker-a:8082", AppliedVersion: "v1"}}))
+
+	var snapshot *domain.Snapshot
+
+	t.Run("Snapshot", func(t *testing.T) {
+		var err error
+		snapshot, err = source.Snapshot()
+		assert.NoError(t, err)
+		assert.Greater(t, snapshot.SchemaVersion, 0)
+		assert.Len(t, snapshot.Agents, 1)
+		assert.Len(t, snapshot.Workers, 1)
+		assert.Len(t, snapshot.Configs, 2)
+		assert.Equal(t, "v1", snapshot.Configs[0].Version)
+		assert.Len(t, snapshot.Events, 1)
+	})
+
+	t.Run("Restore Into Empty", func(t *testing.T) {
+		target := NewBackupRepository(setupIsolatedTestDB(t))
+		assert.NoError(t, target.Restore(snapshot, false))
+
+		restored, err := target.Snapshot()
+		assert.NoError(t, err)
+		assert.Len(t, restored.Agents, 1)
+		assert.Equal(t, "http://agent-1:8081", restored.Agents[0].URL)
+		assert.Equal(t, "agent-1-token", restored.Agents[0].ValidateToken)
+		assert.Equal(t, []domain.AgentWorker{{AgentID: "agent-1", URL: "http://worker-a:8082", AppliedVersion: "v1", UpdatedAt: now}},
+			normalizeWorkers(restored.Workers))
+		assert.Len(t, restored.Configs, 2)
+		assert.Equal(t, `{"url":"b"}`, restored.Configs[1].Config)
+		assert.Len(t, restored.Events, 1)
+		assert.Equal(t, "v2", restored.Events[0].DesiredVersion)
+	})
+
+	t.Run("Restore Into Non Empty", func(t *testing.T) {
+		err := source.Restore(snapshot, false)
+		assert.ErrorIs(t, err, ErrRestoreTargetNotEmpty)
+	})
+
+	t.Run("Restore Into Target With Only Workers", func(t *testing.T) {
+		db := setupIsolatedTestDB(t)
+		assert.NoError(t, db.Create(&domain.AgentWorker{AgentID: "agent-9", URL: "http://worker-z:8082", AppliedVersion: "v9"}).Error)
+
+		err := NewBackupRepository(db).Restore(snapshot, false)
+		assert.ErrorIs(t, err, ErrRestoreTargetNotEmpty)
+	})
+
+	t.Run("Restore Overwrite", func(t *testing.T) {
+		assert.NoError(t, agentRepo.Create(&domain.Agent{ID: "agent-2", CreatedAt: now}))
+		assert.NoError(t, agentRepo.UpdateHeartbeat("agent-2", "v0", now, []domain.AgentWorker{{URL: "http://worker-b:8082", AppliedVersion: "v0"}}))
+
+		assert.NoError(t, source.Restore(snapshot, true))
+
+		restored, err := source.Snapshot()
+		assert.NoError(t, err)
+		assert.Len(t, restored.Configs, 2)
+		assert.Len(t, restored.Events, 1)
+		assert.Len(t, restored.Agents, 1)
+		assert.Len(t, restored.Workers, 1, "workers of agents not in the backup are removed")
+		versions, err := agentRepo.ListAppliedVersions(time.Time{})
+		assert.NoError(t, err)
+		assert.NotContains(t, versions, "v0")
+	})
+}
+
+// normalizeWorkers makes timestamps read back from SQLite comparable.
+func normalizeWorkers(workers []domain.AgentWorker) []domain.AgentWorker {
+	for i := range workers {
+		workers[i].UpdatedAt = workers[i].UpdatedAt.UTC()
+	}
+	return workers
+}
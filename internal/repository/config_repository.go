@@ -9,6 +9,9 @@ import (
 type ConfigRepository interface {
 	Save(config *domain.GlobalConfig) error
 	GetLatest() (*domain.GlobalConfig, error)
+	SetPinned(version string, pinned bool) error
+	ListVersions() ([]domain.GlobalConfig, error)
+	DeleteByIDs(ids []uint) (int64, error)
 }
 
 type configRepository struct {
@@ -30,3 +33,32 @@ func (r *configRepository) GetLatest() (*domain.GlobalConfig, error) {
 	}
 	return &config, nil
 }
+
+func (r *configRepository) SetPinned(version string, pinned bool) error {
+	res := r.db.Model(&domain.GlobalConfig{}).Where("version = ?", version).Update("pinned", pinned)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListVersions returns every stored version, newest first, without the config body.
+func (r *configRepository) ListVersions() ([]domain.GlobalConfig, error) {
+	var configs []domain.GlobalConfig
+	err := r.db.Select("id", "version", "tag", "pinned", "created_at").
+		Order("created_at desc").
+		Order("id desc").
+		Find(&configs).Error
+	return configs, err
+}
+
+func (r *configRepository) DeleteByIDs(ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	res := r.db.Delete(&domain.GlobalConfig{}, ids)
+	return res.RowsAffected, res.Error
+}
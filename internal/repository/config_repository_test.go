@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 func TestConfigRepository_SaveAndGetLatest(t *testing.T) {
@@ -45,3 +46,49 @@ func TestConfigRepository_SaveAndGetLatest(t *testing.T) {
 		assert.Equal(t, "v2", fetchedConfig.Version)
 	})
 }
+
+func TestConfigRepository_Retention(t *testing.T) {
+	repo := NewConfigRepository(setupIsolatedTestDB(t))
+
+	now := time.Now()
+	for i, v := range []string{"v1", "v2", "v3"} {
+		err := repo.Save(&domain.GlobalConfig{
+			Version:   v,
+			Config:    `{}`,
+			CreatedAt: now.Add(time.Duration(i) * time.Minute),
+		})
+		assert.NoError(t, err)
+	}
+
+	t.Run("SetPinned Success", func(t *testing.T) {
+		assert.NoError(t, repo.SetPinned("v1", true))
+	})
+
+	t.Run("SetPinned Not Found", func(t *testing.T) {
+		assert.Equal(t, gorm.ErrRecordNotFound, repo.SetPinned("missing", true))
+	})
+
+	t.Run("ListVersions Newest First", func(t *testing.T) {
+		versions, err := repo.ListVersions()
+		assert.NoError(t, err)
+		assert.Len(t, versions, 3)
+		assert.Equal(t, "v3", versions[0].Version)
+		assert.Equal(t, "v1", versions[2].Version)
+		assert.True(t, versions[2].Pinned)
+		assert.Empty(t, versions[0].Config)
+	})
+
+	t.Run("DeleteByIDs", func(t *testing.T) {
+		versions, _ := repo.ListVersions()
+		removed, err := repo.DeleteByIDs([]uint{versions[1].ID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), removed)
+
+		removed, err = repo.DeleteByIDs(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), removed)
+
+		remaining, _ := repo.ListVersions()
+		assert.Len(t, remaining, 2)
+	})
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"config-manager/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LeaseRepository interface {
+	TryAcquire(name, holderID, holderURL string, ttl time.Duration, now time.Time) (bool, error)
+	Get(name string) (*domain.LeaderLease, error)
+	Release(name, holderID string) error
+}
+
+type leaseRepository struct {
+	db *gorm.DB
+}
+
+func NewLeaseRepository(db *gorm.DB) LeaseRepository {
+	return &leaseRepository{db: db}
+}
+
+// TryAcquire takes or renews the lease. It succeeds when the caller already holds
+// the lease or the current one has expired. The conditional UPDATE is a single
+// statement, so two replicas racing for an expired lease cannot both win.
+func (r *leaseRepository) TryAcquire(name, holderID, holderURL string, ttl time.Duration, now time.Time) (bool, error) {
+	// SQLite compares datetimes as text, so always store a single time zone
+	now = now.UTC()
+
+	err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&domain.LeaderLease{
+		Name:      name,
+		ExpiresAt: time.Time{},
+		UpdatedAt: now,
+	}).Error
+	if err != nil {
+		return false, err
+	}
+
+	res := r.db.Model(&domain.LeaderLease{}).
+		Where("name = ? AND (holder_id = ? OR expires_at < ?)", name, holderID, now).
+		Updates(map[string]interface{}{
+			"holder_id":  holderID,
+			"holder_url": holderURL,
+			"expires_at": now.Add(ttl),
+			"updated_at": now,
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *leaseRepository) Get(name string) (*domain.LeaderLease, error) {
+	var lease domain.LeaderLease
+	if err := r.db.First(&lease, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// Release expires the lease immediately if the caller holds it, so another replica can take over.
+func (r *leaseRepository) Release(name, holderID string) error {
+	return r.db.Model(&domain.LeaderLease{}).
+		Where("name = ? AND holder_id = ?", name, holderID).
+		Update("expires_at", time.Time{}).Error
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestLeaseRepository_TryAcquire(t *testing.T) {
+	repo := NewLeaseRepository(setupIsolatedTestDB(t))
+	now := time.Now()
+	ttl := 10 * time.Second
+
+	t.Run("Get Not Found", func(t *testing.T) {
+		_, err := repo.Get("controller")
+		assert.Equal(t, gorm.ErrRecordNotFound, err)
+	})
+
+	t.Run("First Holder Acquires", func(t *testing.T) {
+		acquired, err := repo.TryAcquire("controller", "a", "http://a", ttl, now)
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+
+		lease, err := repo.Get("controller")
+		assert.NoError(t, err)
+		assert.Equal(t, "a", lease.HolderID)
+		assert.Equal(t, "http://a", lease.HolderURL)
+	})
+
+	t.Run("Other Holder Blocked While Valid", func(t *testing.T) {
+		acquired, err := repo.TryAcquire("controller", "b", "http://b", ttl, now.Add(time.Second))
+		assert.NoError(t, err)
+		assert.False(t, acquired)
+	})
+
+	t.Run("Holder Renews", func(t *testing.T) {
+		acquired, err := repo.TryAcquire("controller", "a", "http://a", ttl, now.Add(5*time.Second))
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+	})
+
+	t.Run("Other Holder Takes Over After Expiry", func(t *testing.T) {
+		acquired, err := repo.TryAcquire("controller", "b", "http://b", ttl, now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+	})
+
+	t.Run("Release By Non Holder Is Ignored", func(t *testing.T) {
+		assert.NoError(t, repo.Release("controller", "a"))
+		acquired, err := repo.TryAcquire("controller", "a", "http://a", ttl, now.Add(time.Minute+time.Second))
+		assert.NoError(t, err)
+		assert.False(t, acquired)
+	})
+
+	t.Run("Release By Holder Frees Lease", func(t *testing.T) {
+		assert.NoError(t, repo.Release("controller", "b"))
+		acquired, err := repo.TryAcquire("controller", "a", "http://a", ttl, now.Add(time.Minute+2*time.Second))
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+	})
+}
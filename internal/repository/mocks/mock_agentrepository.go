@@ -6,6 +6,7 @@ package mocks
 
 import (
 	"config-manager/internal/domain"
+	"time"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -37,6 +38,61 @@ func (_m *MockAgentRepository) EXPECT() *MockAgentRepository_Expecter {
 	return &MockAgentRepository_Expecter{mock: &_m.Mock}
 }
 
+// CountByAppliedVersion provides a mock function for the type MockAgentRepository
+func (_mock *MockAgentRepository) CountByAppliedVersion() (map[string]int, error) {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountByAppliedVersion")
+	}
+
+	var r0 map[string]int
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() (map[string]int, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() map[string]int); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]int)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAgentRepository_CountByAppliedVersion_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CountByAppliedVersion'
+type MockAgentRepository_CountByAppliedVersion_Call struct {
+	*mock.Call
+}
+
+// CountByAppliedVersion is a helper method to define mock.On call
+func (_e *MockAgentRepository_Expecter) CountByAppliedVersion() *MockAgentRepository_CountByAppliedVersion_Call {
+	return &MockAgentRepository_CountByAppliedVersion_Call{Call: _e.mock.On("CountByAppliedVersion")}
+}
+
+func (_c *MockAgentRepository_CountByAppliedVersion_Call) Run(run func()) *MockAgentRepository_CountByAppliedVersion_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockAgentRepository_CountByAppliedVersion_Call) Return(counts map[string]int, err error) *MockAgentRepository_CountByAppliedVersion_Call {
+	_c.Call.Return(counts, err)
+	return _c
+}
+
+func (_c *MockAgentRepository_CountByAppliedVersion_Call) RunAndReturn(run func() (map[string]int, error)) *MockAgentRepository_CountByAppliedVersion_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Create provides a mock function for the type MockAgentRepository
 func (_mock *MockAgentRepository) Create(agent *domain.Agent) error {
 	ret := _mock.Called(agent)
@@ -88,6 +144,57 @@ func (_c *MockAgentRepository_Create_Call) RunAndReturn(run func(agent *domain.A
 	return _c
 }
 
+// CreateEvent provides a mock function for the type MockAgentRepository
+func (_mock *MockAgentRepository) CreateEvent(event *domain.AgentEvent) error {
+	ret := _mock.Called(event)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateEvent")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(*domain.AgentEvent) error); ok {
+		r0 = returnFunc(event)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAgentRepository_CreateEvent_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateEvent'
+type MockAgentRepository_CreateEvent_Call struct {
+	*mock.Call
+}
+
+// CreateEvent is a helper method to define mock.On call
+//   - event *domain.AgentEvent
+func (_e *MockAgentRepository_Expecter) CreateEvent(event interface{}) *MockAgentRepository_CreateEvent_Call {
+	return &MockAgentRepository_CreateEvent_Call{Call: _e.mock.On("CreateEvent", event)}
+}
+
+func (_c *MockAgentRepository_CreateEvent_Call) Run(run func(event *domain.AgentEvent)) *MockAgentRepository_CreateEvent_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 *domain.AgentEvent
+		if args[0] != nil {
+			arg0 = args[0].(*domain.AgentEvent)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAgentRepository_CreateEvent_Call) Return(err error) *MockAgentRepository_CreateEvent_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAgentRepository_CreateEvent_Call) RunAndReturn(run func(event *domain.AgentEvent) error) *MockAgentRepository_CreateEvent_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetByID provides a mock function for the type MockAgentRepository
 func (_mock *MockAgentRepository) GetByID(id string) (*domain.Agent, error) {
 	ret := _mock.Called(id)
@@ -149,3 +256,326 @@ func (_c *MockAgentRepository_GetByID_Call) RunAndReturn(run func(id string) (*d
 	_c.Call.Return(run)
 	return _c
 }
+
+// ListAppliedVersions provides a mock function for the type MockAgentRepository
+func (_mock *MockAgentRepository) ListAppliedVersions(seenSince time.Time) ([]string, error) {
+	ret := _mock.Called(seenSince)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAppliedVersions")
+	}
+
+	var r0 []string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(time.Time) ([]string, error)); ok {
+		return returnFunc(seenSince)
+	}
+	if returnFunc, ok := ret.Get(0).(func(time.Time) []string); ok {
+		r0 = returnFunc(seenSince)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(time.Time) error); ok {
+		r1 = returnFunc(seenSince)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAgentRepository_ListAppliedVersions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListAppliedVersions'
+type MockAgentRepository_ListAppliedVersions_Call struct {
+	*mock.Call
+}
+
+// ListAppliedVersions is a helper method to define mock.On call
+//   - seenSince time.Time
+func (_e *MockAgentRepository_Expecter) ListAppliedVersions(seenSince interface{}) *MockAgentRepository_ListAppliedVersions_Call {
+	return &MockAgentRepository_ListAppliedVersions_Call{Call: _e.mock.On("ListAppliedVersions", seenSince)}
+}
+
+func (_c *MockAgentRepository_ListAppliedVersions_Call) Run(run func(seenSince time.Time)) *MockAgentRepository_ListAppliedVersions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 time.Time
+		if args[0] != nil {
+			arg0 = args[0].(time.Time)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAgentRepository_ListAppliedVersions_Call) Return(strings []string, err error) *MockAgentRepository_ListAppliedVersions_Call {
+	_c.Call.Return(strings, err)
+	return _c
+}
+
+func (_c *MockAgentRepository_ListAppliedVersions_Call) RunAndReturn(run func(seenSince time.Time) ([]string, error)) *MockAgentRepository_ListAppliedVersions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListEvents provides a mock function for the type MockAgentRepository
+func (_mock *MockAgentRepository) ListEvents(agentID string, limit int) ([]domain.AgentEvent, error) {
+	ret := _mock.Called(agentID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEvents")
+	}
+
+	var r0 []domain.AgentEvent
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string, int) ([]domain.AgentEvent, error)); ok {
+		return returnFunc(agentID, limit)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string, int) []domain.AgentEvent); ok {
+		r0 = returnFunc(agentID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.AgentEvent)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = returnFunc(agentID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAgentRepository_ListEvents_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListEvents'
+type MockAgentRepository_ListEvents_Call struct {
+	*mock.Call
+}
+
+// ListEvents is a helper method to define mock.On call
+//   - agentID string
+//   - limit int
+func (_e *MockAgentRepository_Expecter) ListEvents(agentID interface{}, limit interface{}) *MockAgentRepository_ListEvents_Call {
+	return &MockAgentRepository_ListEvents_Call{Call: _e.mock.On("ListEvents", agentID, limit)}
+}
+
+func (_c *MockAgentRepository_ListEvents_Call) Run(run func(agentID string, limit int)) *MockAgentRepository_ListEvents_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 int
+		if args[1] != nil {
+			arg1 = args[1].(int)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAgentRepository_ListEvents_Call) Return(agentEvents []domain.AgentEvent, err error) *MockAgentRepository_ListEvents_Call {
+	_c.Call.Return(agentEvents, err)
+	return _c
+}
+
+func (_c *MockAgentRepository_ListEvents_Call) RunAndReturn(run func(agentID string, limit int) ([]domain.AgentEvent, error)) *MockAgentRepository_ListEvents_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListReachable provides a mock function for the type MockAgentRepository
+func (_mock *MockAgentRepository) ListReachable(seenSince time.Time) ([]domain.Agent, error) {
+	ret := _mock.Called(seenSince)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListReachable")
+	}
+
+	var r0 []domain.Agent
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(time.Time) ([]domain.Agent, error)); ok {
+		return returnFunc(seenSince)
+	}
+	if returnFunc, ok := ret.Get(0).(func(time.Time) []domain.Agent); ok {
+		r0 = returnFunc(seenSince)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Agent)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(time.Time) error); ok {
+		r1 = returnFunc(seenSince)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAgentRepository_ListReachable_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListReachable'
+type MockAgentRepository_ListReachable_Call struct {
+	*mock.Call
+}
+
+// ListReachable is a helper method to define mock.On call
+//   - seenSince time.Time
+func (_e *MockAgentRepository_Expecter) ListReachable(seenSince interface{}) *MockAgentRepository_ListReachable_Call {
+	return &MockAgentRepository_ListReachable_Call{Call: _e.mock.On("ListReachable", seenSince)}
+}
+
+func (_c *MockAgentRepository_ListReachable_Call) Run(run func(seenSince time.Time)) *MockAgentRepository_ListReachable_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 time.Time
+		if args[0] != nil {
+			arg0 = args[0].(time.Time)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAgentRepository_ListReachable_Call) Return(agents []domain.Agent, err error) *MockAgentRepository_ListReachable_Call {
+	_c.Call.Return(agents, err)
+	return _c
+}
+
+func (_c *MockAgentRepository_ListReachable_Call) RunAndReturn(run func(seenSince time.Time) ([]domain.Agent, error)) *MockAgentRepository_ListReachable_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListWorkers provides a mock function for the type MockAgentRepository
+func (_mock *MockAgentRepository) ListWorkers(agentID string) ([]domain.AgentWorker, error) {
+	ret := _mock.Called(agentID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWorkers")
+	}
+
+	var r0 []domain.AgentWorker
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string) ([]domain.AgentWorker, error)); ok {
+		return returnFunc(agentID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) []domain.AgentWorker); ok {
+		r0 = returnFunc(agentID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.AgentWorker)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) error); ok {
+		r1 = returnFunc(agentID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAgentRepository_ListWorkers_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListWorkers'
+type MockAgentRepository_ListWorkers_Call struct {
+	*mock.Call
+}
+
+// ListWorkers is a helper method to define mock.On call
+//   - agentID string
+func (_e *MockAgentRepository_Expecter) ListWorkers(agentID interface{}) *MockAgentRepository_ListWorkers_Call {
+	return &MockAgentRepository_ListWorkers_Call{Call: _e.mock.On("ListWorkers", agentID)}
+}
+
+func (_c *MockAgentRepository_ListWorkers_Call) Run(run func(agentID string)) *MockAgentRepository_ListWorkers_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAgentRepository_ListWorkers_Call) Return(agentWorkers []domain.AgentWorker, err error) *MockAgentRepository_ListWorkers_Call {
+	_c.Call.Return(agentWorkers, err)
+	return _c
+}
+
+func (_c *MockAgentRepository_ListWorkers_Call) RunAndReturn(run func(agentID string) ([]domain.AgentWorker, error)) *MockAgentRepository_ListWorkers_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateHeartbeat provides a mock function for the type MockAgentRepository
+func (_mock *MockAgentRepository) UpdateHeartbeat(id string, appliedVersion string, seenAt time.Time, workers []domain.AgentWorker) error {
+	ret := _mock.Called(id, appliedVersion, seenAt, workers)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateHeartbeat")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, string, time.Time, []domain.AgentWorker) error); ok {
+		r0 = returnFunc(id, appliedVersion, seenAt, workers)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAgentRepository_UpdateHeartbeat_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateHeartbeat'
+type MockAgentRepository_UpdateHeartbeat_Call struct {
+	*mock.Call
+}
+
+// UpdateHeartbeat is a helper method to define mock.On call
+//   - id string
+//   - appliedVersion string
+//   - seenAt time.Time
+//   - workers []domain.AgentWorker
+func (_e *MockAgentRepository_Expecter) UpdateHeartbeat(id interface{}, appliedVersion interface{}, seenAt interface{}, workers interface{}) *MockAgentRepository_UpdateHeartbeat_Call {
+	return &MockAgentRepository_UpdateHeartbeat_Call{Call: _e.mock.On("UpdateHeartbeat", id, appliedVersion, seenAt, workers)}
+}
+
+func (_c *MockAgentRepository_UpdateHeartbeat_Call) Run(run func(id string, appliedVersion string, seenAt time.Time, workers []domain.AgentWorker)) *MockAgentRepository_UpdateHeartbeat_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 time.Time
+		if args[2] != nil {
+			arg2 = args[2].(time.Time)
+		}
+		var arg3 []domain.AgentWorker
+		if args[3] != nil {
+			arg3 = args[3].([]domain.AgentWorker)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAgentRepository_UpdateHeartbeat_Call) Return(err error) *MockAgentRepository_UpdateHeartbeat_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAgentRepository_UpdateHeartbeat_Call) RunAndReturn(run func(id string, appliedVersion string, seenAt time.Time, workers []domain.AgentWorker) error) *MockAgentRepository_UpdateHeartbeat_Call {
+	_c.Call.Return(run)
+	return _c
+}
@@ -37,6 +37,66 @@ func (_m *MockConfigRepository) EXPECT() *MockConfigRepository_Expecter {
 	return &MockConfigRepository_Expecter{mock: &_m.Mock}
 }
 
+// DeleteByIDs provides a mock function for the type MockConfigRepository
+func (_mock *MockConfigRepository) DeleteByIDs(ids []uint) (int64, error) {
+	ret := _mock.Called(ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByIDs")
+	}
+
+	var r0 int64
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func([]uint) (int64, error)); ok {
+		return returnFunc(ids)
+	}
+	if returnFunc, ok := ret.Get(0).(func([]uint) int64); ok {
+		r0 = returnFunc(ids)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+	if returnFunc, ok := ret.Get(1).(func([]uint) error); ok {
+		r1 = returnFunc(ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockConfigRepository_DeleteByIDs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteByIDs'
+type MockConfigRepository_DeleteByIDs_Call struct {
+	*mock.Call
+}
+
+// DeleteByIDs is a helper method to define mock.On call
+//   - ids []uint
+func (_e *MockConfigRepository_Expecter) DeleteByIDs(ids interface{}) *MockConfigRepository_DeleteByIDs_Call {
+	return &MockConfigRepository_DeleteByIDs_Call{Call: _e.mock.On("DeleteByIDs", ids)}
+}
+
+func (_c *MockConfigRepository_DeleteByIDs_Call) Run(run func(ids []uint)) *MockConfigRepository_DeleteByIDs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 []uint
+		if args[0] != nil {
+			arg0 = args[0].([]uint)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockConfigRepository_DeleteByIDs_Call) Return(n int64, err error) *MockConfigRepository_DeleteByIDs_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockConfigRepository_DeleteByIDs_Call) RunAndReturn(run func(ids []uint) (int64, error)) *MockConfigRepository_DeleteByIDs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetLatest provides a mock function for the type MockConfigRepository
 func (_mock *MockConfigRepository) GetLatest() (*domain.GlobalConfig, error) {
 	ret := _mock.Called()
@@ -92,6 +152,61 @@ func (_c *MockConfigRepository_GetLatest_Call) RunAndReturn(run func() (*domain.
 	return _c
 }
 
+// ListVersions provides a mock function for the type MockConfigRepository
+func (_mock *MockConfigRepository) ListVersions() ([]domain.GlobalConfig, error) {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListVersions")
+	}
+
+	var r0 []domain.GlobalConfig
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() ([]domain.GlobalConfig, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() []domain.GlobalConfig); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.GlobalConfig)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockConfigRepository_ListVersions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListVersions'
+type MockConfigRepository_ListVersions_Call struct {
+	*mock.Call
+}
+
+// ListVersions is a helper method to define mock.On call
+func (_e *MockConfigRepository_Expecter) ListVersions() *MockConfigRepository_ListVersions_Call {
+	return &MockConfigRepository_ListVersions_Call{Call: _e.mock.On("ListVersions")}
+}
+
+func (_c *MockConfigRepository_ListVersions_Call) Run(run func()) *MockConfigRepository_ListVersions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockConfigRepository_ListVersions_Call) Return(globalConfigs []domain.GlobalConfig, err error) *MockConfigRepository_ListVersions_Call {
+	_c.Call.Return(globalConfigs, err)
+	return _c
+}
+
+func (_c *MockConfigRepository_ListVersions_Call) RunAndReturn(run func() ([]domain.GlobalConfig, error)) *MockConfigRepository_ListVersions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Save provides a mock function for the type MockConfigRepository
 func (_mock *MockConfigRepository) Save(config *domain.GlobalConfig) error {
 	ret := _mock.Called(config)
@@ -142,3 +257,60 @@ func (_c *MockConfigRepository_Save_Call) RunAndReturn(run func(config *domain.G
 	_c.Call.Return(run)
 	return _c
 }
+
+// SetPinned provides a mock function for the type MockConfigRepository
+func (_mock *MockConfigRepository) SetPinned(version string, pinned bool) error {
+	ret := _mock.Called(version, pinned)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPinned")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, bool) error); ok {
+		r0 = returnFunc(version, pinned)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockConfigRepository_SetPinned_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetPinned'
+type MockConfigRepository_SetPinned_Call struct {
+	*mock.Call
+}
+
+// SetPinned is a helper method to define mock.On call
+//   - version string
+//   - pinned bool
+func (_e *MockConfigRepository_Expecter) SetPinned(version interface{}, pinned interface{}) *MockConfigRepository_SetPinned_Call {
+	return &MockConfigRepository_SetPinned_Call{Call: _e.mock.On("SetPinned", version, pinned)}
+}
+
+func (_c *MockConfigRepository_SetPinned_Call) Run(run func(version string, pinned bool)) *MockConfigRepository_SetPinned_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 bool
+		if args[1] != nil {
+			arg1 = args[1].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockConfigRepository_SetPinned_Call) Return(err error) *MockConfigRepository_SetPinned_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockConfigRepository_SetPinned_Call) RunAndReturn(run func(version string, pinned bool) error) *MockConfigRepository_SetPinned_Call {
+	_c.Call.Return(run)
+	return _c
+}
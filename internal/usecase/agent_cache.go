@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"config-manager/pkg/shared/utils"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// ErrAgentCacheEmpty is returned by AgentCache.Load when nothing has been cached yet.
+var ErrAgentCacheEmpty = errors.New("agent cache is empty")
+
+// ErrAgentCacheSignature is returned when the cached config does not match its signature.
+var ErrAgentCacheSignature = errors.New("agent cache signature mismatch")
+
+type AgentCache interface {
+	Load() (*dto.AgentCacheEntry, error)
+	Save(version string, config map[string]interface{}) error
+}
+
+type fileAgentCache struct {
+	path string
+	key  []byte
+}
+
+// NewFileAgentCache stores the cache at path, signed with an HMAC-SHA256 of key
+// so a corrupted or hand-edited file is never pushed to a worker.
+func NewFileAgentCache(path, key string) AgentCache {
+	return &fileAgentCache{path: path, key: []byte(key)}
+}
+
+func (c *fileAgentCache) Load() (*dto.AgentCacheEntry, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrAgentCacheEmpty
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry dto.AgentCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("invalid agent cache file %s: %w", c.path, err)
+	}
+
+	expected, err := c.sign(entry.Version, entry.Config)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(expected), []byte(entry.Signature)) {
+		return nil, ErrAgentCacheSignature
+	}
+	return &entry, nil
+}
+
+func (c *fileAgentCache) Save(version string, config map[string]interface{}) error {
+	signature, err := c.sign(version, config)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(dto.AgentCacheEntry{
+		Version:   version,
+		Config:    config,
+		Signature: signature,
+		FetchedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(c.path, data, 0o600)
+}
+
+// sign covers the version and the canonical JSON encoding of the config;
+// encoding/json sorts map keys, so the result survives a load/save round trip.
+func (c *fileAgentCache) sign(version string, config map[string]interface{}) (string, error) {
+	body, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(version))
+	mac.Write([]byte{0})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileAgentCache(t *testing.T) {
+	config := map[string]interface{}{"url": "http://example.com", "retries": float64(3)}
+
+	t.Run("Empty", func(t *testing.T) {
+		cache := NewFileAgentCache(filepath.Join(t.TempDir(), "agent-cache.json"), "secret")
+
+		entry, err := cache.Load()
+		assert.ErrorIs(t, err, ErrAgentCacheEmpty)
+		assert.Nil(t, entry)
+	})
+
+	t.Run("Round Trip", func(t *testing.T) {
+		cache := NewFileAgentCache(filepath.Join(t.TempDir(), "agent-cache.json"), "secret")
+		assert.NoError(t, cache.Save("v1", config))
+
+		entry, err := cache.Load()
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", entry.Version)
+		assert.Equal(t, config, entry.Config)
+		assert.NotEmpty(t, entry.Signature)
+		assert.False(t, entry.FetchedAt.IsZero())
+	})
+
+	t.Run("Different Key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "agent-cache.json")
+		assert.NoError(t, NewFileAgentCache(path, "secret").Save("v1", config))
+
+		_, err := NewFileAgentCache(path, "other-secret").Load()
+		assert.ErrorIs(t, err, ErrAgentCacheSignature)
+	})
+
+	t.Run("Tampered Config", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "agent-cache.json")
+		cache := NewFileAgentCache(path, "secret")
+		assert.NoError(t, cache.Save("v1", config))
+
+		data, _ := os.ReadFile(path)
+		tampered := bytes.Replace(data, []byte("example.com"), []byte("evil.example"), 1)
+		assert.NoError(t, os.WriteFile(path, tampered, 0o600))
+
+		_, err := cache.Load()
+		assert.ErrorIs(t, err, ErrAgentCacheSignature)
+	})
+
+	t.Run("Invalid File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "agent-cache.json")
+		assert.NoError(t, os.WriteFile(path, []byte("invalid json"), 0o600))
+
+		_, err := NewFileAgentCache(path, "secret").Load()
+		assert.Error(t, err)
+	})
+}
@@ -4,15 +4,27 @@ import (
 	"bytes"
 	"config-manager/configs"
 	"config-manager/internal/dto"
+	"config-manager/pkg/shared/tracing"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 )
 
+// ErrPushRejected is returned when a worker refuses a pushed config with a 4xx
+// status, e.g. because its egress policy denies an upstream. Pushing the same
+// config again would fail the same way.
+var ErrPushRejected = errors.New("worker rejected config")
+
 type AgentManager interface {
-	Register() (*dto.AgentRegisterResponse, error)
-	PushToWorker(req dto.ConfigRequest) error
+	Register(ctx context.Context) (*dto.AgentRegisterResponse, error)
+	PushToWorker(ctx context.Context, workerURL string, req dto.ConfigRequest) error
+	Heartbeat(ctx context.Context, req dto.AgentHeartbeatRequest) error
+	GetWorkerConfig(ctx context.Context, workerURL string) (*dto.WorkerConfigResponse, error)
+	ValidateOnWorker(ctx context.Context, workerURL string, req dto.ConfigRequest) (*dto.ConfigValidation, error)
+	ReportEvent(ctx context.Context, req dto.AgentEventRequest) error
 }
 
 type agentManager struct {
@@ -23,15 +35,15 @@ type agentManager struct {
 func NewAgentManager(cfg *configs.Config) AgentManager {
 	return &agentManager{
 		cfg:        cfg,
-		httpClient: &http.Client{Timeout: 5 * time.Second},
+		httpClient: &http.Client{Timeout: 5 * time.Second, Transport: tracing.NewTransport(nil)},
 	}
 }
 
-func (m *agentManager) Register() (*dto.AgentRegisterResponse, error) {
-	reqBody, _ := json.Marshal(dto.AgentRegisterRequest{Name: "agent-1"})
+func (m *agentManager) Register(ctx context.Context) (*dto.AgentRegisterResponse, error) {
+	reqBody, _ := json.Marshal(dto.AgentRegisterRequest{Name: "agent-1", URL: m.cfg.AgentAdvertiseURL})
 
 	url := fmt.Sprintf("%s/v1/register", m.cfg.ControllerURL)
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, err
 	}
@@ -56,11 +68,11 @@ func (m *agentManager) Register() (*dto.AgentRegisterResponse, error) {
 	return &registerResp, nil
 }
 
-func (m *agentManager) PushToWorker(req dto.ConfigRequest) error {
+func (m *agentManager) PushToWorker(ctx context.Context, workerURL string, req dto.ConfigRequest) error {
 	reqBody, _ := json.Marshal(req)
 
-	url := m.cfg.WorkerURL + "/v1/config"
-	httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(reqBody))
+	url := workerURL + "/v1/config"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return err
 	}
@@ -72,8 +84,113 @@ func (m *agentManager) PushToWorker(req dto.ConfigRequest) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("%w, status: %d: %s", ErrPushRejected, resp.StatusCode, errResp.Error)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("failed to push config to worker, status: %d", resp.StatusCode)
 	}
 	return nil
 }
+
+func (m *agentManager) Heartbeat(ctx context.Context, req dto.AgentHeartbeatRequest) error {
+	reqBody, _ := json.Marshal(req)
+
+	url := fmt.Sprintf("%s/v1/heartbeat", m.cfg.ControllerURL)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", m.cfg.AgentAuthToken)
+
+	resp, err := m.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to send heartbeat, status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GetWorkerConfig returns the version and digest of the config the worker at
+// workerURL is currently running.
+func (m *agentManager) GetWorkerConfig(ctx context.Context, workerURL string) (*dto.WorkerConfigResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, workerURL+"/v1/config", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := m.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get worker config, status: %d", resp.StatusCode)
+	}
+
+	var workerResp dto.WorkerConfigResponse
+	if err := json.NewDecoder(resp.Body).Decode(&workerResp); err != nil {
+		return nil, err
+	}
+	return &workerResp, nil
+}
+
+// ValidateOnWorker has the worker at workerURL check req.Config without applying it.
+func (m *agentManager) ValidateOnWorker(ctx context.Context, workerURL string, req dto.ConfigRequest) (*dto.ConfigValidation, error) {
+	reqBody, _ := json.Marshal(req)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, workerURL+"/v1/config/validate", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to validate config on worker, status: %d", resp.StatusCode)
+	}
+
+	var workerResp dto.WorkerConfigValidateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&workerResp); err != nil {
+		return nil, err
+	}
+	return &workerResp.ConfigValidation, nil
+}
+
+func (m *agentManager) ReportEvent(ctx context.Context, req dto.AgentEventRequest) error {
+	reqBody, _ := json.Marshal(req)
+
+	url := fmt.Sprintf("%s/v1/events", m.cfg.ControllerURL)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", m.cfg.AgentAuthToken)
+
+	resp, err := m.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to report event, status: %d", resp.StatusCode)
+	}
+	return nil
+}
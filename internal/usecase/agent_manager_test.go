@@ -3,6 +3,7 @@ package usecase
 import (
 	"config-manager/configs"
 	"config-manager/internal/dto"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -17,6 +18,9 @@ func TestAgentManager_Register(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			assert.Equal(t, "/v1/register", r.URL.Path)
 			assert.Equal(t, "secret-token", r.Header.Get("Authorization"))
+			var req dto.AgentRegisterRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "http://agent-1:8081", req.URL)
 			w.WriteHeader(http.StatusOK)
 			resp := dto.AgentRegisterResponse{
 				AgentID: "agent-123",
@@ -25,10 +29,10 @@ func TestAgentManager_Register(t *testing.T) {
 		}))
 		defer ts.Close()
 
-		cfg := &configs.Config{ControllerURL: ts.URL, AgentAuthToken: "secret-token"}
+		cfg := &configs.Config{ControllerURL: ts.URL, AgentAuthToken: "secret-token", AgentAdvertiseURL: "http://agent-1:8081"}
 		manager := NewAgentManager(cfg)
 
-		resp, err := manager.Register()
+		resp, err := manager.Register(context.Background())
 		assert.NoError(t, err)
 		assert.NotNil(t, resp)
 		assert.Equal(t, "agent-123", resp.AgentID)
@@ -43,7 +47,7 @@ func TestAgentManager_Register(t *testing.T) {
 		cfg := &configs.Config{ControllerURL: ts.URL}
 		manager := NewAgentManager(cfg)
 
-		resp, err := manager.Register()
+		resp, err := manager.Register(context.Background())
 		assert.Error(t, err)
 		assert.Nil(t, resp)
 	})
@@ -52,7 +56,7 @@ func TestAgentManager_Register(t *testing.T) {
 		cfg := &configs.Config{ControllerURL: "http://\x00invalid"}
 		manager := NewAgentManager(cfg)
 
-		resp, err := manager.Register()
+		resp, err := manager.Register(context.Background())
 		assert.Error(t, err)
 		assert.Nil(t, resp)
 	})
@@ -67,7 +71,7 @@ func TestAgentManager_Register(t *testing.T) {
 		cfg := &configs.Config{ControllerURL: ts.URL}
 		manager := NewAgentManager(cfg)
 
-		resp, err := manager.Register()
+		resp, err := manager.Register(context.Background())
 		assert.Error(t, err)
 		assert.Nil(t, resp)
 	})
@@ -85,7 +89,7 @@ func TestAgentManager_PushToWorker(t *testing.T) {
 		manager := NewAgentManager(cfg)
 
 		req := dto.ConfigRequest{Config: map[string]interface{}{"k": "v"}}
-		err := manager.PushToWorker(req)
+		err := manager.PushToWorker(context.Background(), cfg.WorkerURL, req)
 		assert.NoError(t, err)
 	})
 
@@ -99,8 +103,33 @@ func TestAgentManager_PushToWorker(t *testing.T) {
 		manager := NewAgentManager(cfg)
 
 		req := dto.ConfigRequest{Config: map[string]interface{}{"k": "v"}}
-		err := manager.PushToWorker(req)
+		err := manager.PushToWorker(context.Background(), cfg.WorkerURL, req)
 		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrPushRejected)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error":"egress denied: address 10.0.0.1 is not allowed","code":422}`))
+		}))
+		defer ts.Close()
+
+		manager := NewAgentManager(&configs.Config{})
+		err := manager.PushToWorker(context.Background(), ts.URL, dto.ConfigRequest{})
+		assert.ErrorIs(t, err, ErrPushRejected)
+		assert.EqualError(t, err, "worker rejected config, status: 422: egress denied: address 10.0.0.1 is not allowed")
+	})
+
+	t.Run("Throttled Is Retried", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer ts.Close()
+
+		err := NewAgentManager(&configs.Config{}).PushToWorker(context.Background(), ts.URL, dto.ConfigRequest{})
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrPushRejected)
 	})
 
 	t.Run("InvalidURL", func(t *testing.T) {
@@ -109,7 +138,160 @@ func TestAgentManager_PushToWorker(t *testing.T) {
 		manager.(*agentManager).httpClient.Timeout = 10 * time.Millisecond
 
 		req := dto.ConfigRequest{Config: map[string]interface{}{"k": "v"}}
-		err := manager.PushToWorker(req)
+		err := manager.PushToWorker(context.Background(), cfg.WorkerURL, req)
+		assert.Error(t, err)
+	})
+}
+
+func TestAgentManager_Heartbeat(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/heartbeat", r.URL.Path)
+			assert.Equal(t, "secret-token", r.Header.Get("Authorization"))
+			var req dto.AgentHeartbeatRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			assert.Equal(t, "agent-1", req.AgentID)
+			assert.Equal(t, "v1", req.AppliedVersion)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		cfg := &configs.Config{ControllerURL: ts.URL, AgentAuthToken: "secret-token"}
+		manager := NewAgentManager(cfg)
+
+		err := manager.Heartbeat(context.Background(), dto.AgentHeartbeatRequest{AgentID: "agent-1", AppliedVersion: "v1"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer ts.Close()
+
+		cfg := &configs.Config{ControllerURL: ts.URL}
+		manager := NewAgentManager(cfg)
+
+		err := manager.Heartbeat(context.Background(), dto.AgentHeartbeatRequest{AgentID: "agent-1"})
+		assert.Error(t, err)
+	})
+
+	t.Run("InvalidURL", func(t *testing.T) {
+		cfg := &configs.Config{ControllerURL: "http://\x00invalid"}
+		manager := NewAgentManager(cfg)
+
+		err := manager.Heartbeat(context.Background(), dto.AgentHeartbeatRequest{AgentID: "agent-1"})
+		assert.Error(t, err)
+	})
+}
+
+func TestAgentManager_GetWorkerConfig(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodGet, r.Method)
+			assert.Equal(t, "/v1/config", r.URL.Path)
+			w.Write([]byte(`{"version":"v1","digest":"abc","code":200}`))
+		}))
+		defer ts.Close()
+
+		manager := NewAgentManager(&configs.Config{})
+
+		res, err := manager.GetWorkerConfig(context.Background(), ts.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", res.Version)
+		assert.Equal(t, "abc", res.Digest)
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer ts.Close()
+
+		_, err := NewAgentManager(&configs.Config{}).GetWorkerConfig(context.Background(), ts.URL)
 		assert.Error(t, err)
 	})
+
+	t.Run("Invalid Body", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("invalid json"))
+		}))
+		defer ts.Close()
+
+		_, err := NewAgentManager(&configs.Config{}).GetWorkerConfig(context.Background(), ts.URL)
+		assert.Error(t, err)
+	})
+}
+
+func TestAgentManager_ValidateOnWorker(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/v1/config/validate", r.URL.Path)
+			w.Write([]byte(`{"valid":false,"issues":[{"action":"admin","error":"egress denied"}],"code":200}`))
+		}))
+		defer ts.Close()
+
+		res, err := NewAgentManager(&configs.Config{}).ValidateOnWorker(context.Background(), ts.URL, dto.ConfigRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, &dto.ConfigValidation{Issues: []dto.ConfigIssue{{Action: "admin", Error: "egress denied"}}}, res)
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer ts.Close()
+
+		_, err := NewAgentManager(&configs.Config{}).ValidateOnWorker(context.Background(), ts.URL, dto.ConfigRequest{})
+		assert.EqualError(t, err, "failed to validate config on worker, status: 404")
+	})
+}
+
+func TestAgentManager_ReportEvent(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/events", r.URL.Path)
+			assert.Equal(t, "secret-token", r.Header.Get("Authorization"))
+			var req dto.AgentEventRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			assert.Equal(t, "drift", req.Type)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		manager := NewAgentManager(&configs.Config{ControllerURL: ts.URL, AgentAuthToken: "secret-token"})
+
+		err := manager.ReportEvent(context.Background(), dto.AgentEventRequest{AgentID: "agent-1", Type: "drift"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer ts.Close()
+
+		err := NewAgentManager(&configs.Config{ControllerURL: ts.URL}).ReportEvent(context.Background(), dto.AgentEventRequest{AgentID: "agent-1"})
+		assert.Error(t, err)
+	})
+}
+
+func TestAgentManager_Cancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("cancelled requests must not reach the server")
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	manager := NewAgentManager(&configs.Config{ControllerURL: ts.URL})
+
+	_, err := manager.Register(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, manager.PushToWorker(ctx, ts.URL, dto.ConfigRequest{}), context.Canceled)
+	assert.ErrorIs(t, manager.Heartbeat(ctx, dto.AgentHeartbeatRequest{}), context.Canceled)
+	_, err = manager.GetWorkerConfig(ctx, ts.URL)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, manager.ReportEvent(ctx, dto.AgentEventRequest{}), context.Canceled)
 }
@@ -4,6 +4,7 @@ import (
 	"config-manager/internal/domain"
 	"config-manager/internal/dto"
 	"config-manager/internal/repository"
+	"crypto/rand"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,6 +12,11 @@ import (
 
 type AgentUsecase interface {
 	Register(req dto.AgentRegisterRequest) (*dto.AgentRegisterResponse, error)
+	Heartbeat(req dto.AgentHeartbeatRequest) error
+	ReportEvent(req dto.AgentEventRequest) error
+	ListEvents(agentID string, limit int) ([]dto.AgentEvent, error)
+	ListWorkers(agentID string) ([]dto.AgentWorker, error)
+	CountByAppliedVersion() (map[string]int, error)
 }
 
 type agentUsecase struct {
@@ -31,8 +37,14 @@ func (u *agentUsecase) Register(req dto.AgentRegisterRequest) (*dto.AgentRegiste
 	agent := &domain.Agent{
 		ID:        uuid.New().String(),
 		Name:      req.Name,
+		URL:       req.URL,
 		CreatedAt: time.Now(),
 	}
+	if agent.URL != "" {
+		// Only valid for this agent, so an agent advertising someone else's URL
+		// only ever gets its own token sent there
+		agent.ValidateToken = rand.Text()
+	}
 
 	if err := u.agentRepo.Create(agent); err != nil {
 		return nil, err
@@ -42,5 +54,87 @@ func (u *agentUsecase) Register(req dto.AgentRegisterRequest) (*dto.AgentRegiste
 		AgentID:             agent.ID,
 		PollURL:             u.pollURL,
 		PollIntervalSeconds: u.pollInterval,
+		ValidateToken:       agent.ValidateToken,
 	}, nil
 }
+
+func (u *agentUsecase) Heartbeat(req dto.AgentHeartbeatRequest) error {
+	var workers []domain.AgentWorker
+	if req.Workers != nil {
+		workers = make([]domain.AgentWorker, 0, len(req.Workers))
+		for _, w := range req.Workers {
+			workers = append(workers, domain.AgentWorker{
+				URL:            w.URL,
+				AppliedVersion: w.AppliedVersion,
+				PendingVersion: w.PendingVersion,
+				LastError:      w.LastError,
+			})
+		}
+	}
+	return u.agentRepo.UpdateHeartbeat(req.AgentID, req.AppliedVersion, time.Now(), workers)
+}
+
+// ReportEvent records an event for a registered agent. It returns
+// gorm.ErrRecordNotFound when the agent is unknown.
+func (u *agentUsecase) ReportEvent(req dto.AgentEventRequest) error {
+	if _, err := u.agentRepo.GetByID(req.AgentID); err != nil {
+		return err
+	}
+
+	return u.agentRepo.CreateEvent(&domain.AgentEvent{
+		AgentID:        req.AgentID,
+		WorkerURL:      req.WorkerURL,
+		Type:           req.Type,
+		DesiredVersion: req.DesiredVersion,
+		ActualVersion:  req.ActualVersion,
+		Message:        req.Message,
+		CreatedAt:      time.Now(),
+	})
+}
+
+func (u *agentUsecase) ListEvents(agentID string, limit int) ([]dto.AgentEvent, error) {
+	events, err := u.agentRepo.ListEvents(agentID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.AgentEvent, 0, len(events))
+	for _, e := range events {
+		res = append(res, dto.AgentEvent{
+			ID:             e.ID,
+			AgentID:        e.AgentID,
+			WorkerURL:      e.WorkerURL,
+			Type:           e.Type,
+			DesiredVersion: e.DesiredVersion,
+			ActualVersion:  e.ActualVersion,
+			Message:        e.Message,
+			CreatedAt:      e.CreatedAt,
+		})
+	}
+	return res, nil
+}
+
+func (u *agentUsecase) ListWorkers(agentID string) ([]dto.AgentWorker, error) {
+	workers, err := u.agentRepo.ListWorkers(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.AgentWorker, 0, len(workers))
+	for _, w := range workers {
+		res = append(res, dto.AgentWorker{
+			URL:            w.URL,
+			AppliedVersion: w.AppliedVersion,
+			PendingVersion: w.PendingVersion,
+			LastError:      w.LastError,
+			UpdatedAt:      w.UpdatedAt,
+		})
+	}
+	return res, nil
+}
+
+// CountByAppliedVersion returns the number of registered agents per applied
+// version, with agents that have not applied a config yet under "".
+func (u *agentUsecase) CountByAppliedVersion() (map[string]int, error) {
+	return u.agentRepo.CountByAppliedVersion()
+}
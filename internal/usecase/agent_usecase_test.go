@@ -1,14 +1,17 @@
 package usecase
 
 import (
+	"config-manager/internal/domain"
 	"config-manager/internal/repository/mocks"
 	"errors"
 
 	"config-manager/internal/dto"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 func TestAgentUsecase_Register(t *testing.T) {
@@ -16,9 +19,13 @@ func TestAgentUsecase_Register(t *testing.T) {
 	uc := NewAgentUsecase(mockRepo, "/config", 30)
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("Create", mock.AnythingOfType("*domain.Agent")).Return(nil).Once()
+		var created *domain.Agent
+		mockRepo.On("Create", mock.MatchedBy(func(agent *domain.Agent) bool {
+			created = agent
+			return agent.URL == "http://agent-1:8081"
+		})).Return(nil).Once()
 
-		req := dto.AgentRegisterRequest{Name: "TestAgent"}
+		req := dto.AgentRegisterRequest{Name: "TestAgent", URL: "http://agent-1:8081"}
 		res, err := uc.Register(req)
 
 		assert.NoError(t, err)
@@ -26,7 +33,21 @@ func TestAgentUsecase_Register(t *testing.T) {
 		assert.NotEmpty(t, res.AgentID)
 		assert.Equal(t, "/config", res.PollURL)
 		assert.Equal(t, 30, res.PollIntervalSeconds)
+		assert.NotEmpty(t, res.ValidateToken)
+		assert.Equal(t, created.ValidateToken, res.ValidateToken)
+
+		mockRepo.AssertExpectations(t)
+	})
 
+	t.Run("Without URL", func(t *testing.T) {
+		mockRepo.On("Create", mock.MatchedBy(func(agent *domain.Agent) bool {
+			return agent.ValidateToken == ""
+		})).Return(nil).Once()
+
+		res, err := uc.Register(dto.AgentRegisterRequest{Name: "TestAgent"})
+
+		assert.NoError(t, err)
+		assert.Empty(t, res.ValidateToken, "the controller cannot reach it anyway")
 		mockRepo.AssertExpectations(t)
 	})
 
@@ -42,3 +63,108 @@ func TestAgentUsecase_Register(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestAgentUsecase_Heartbeat(t *testing.T) {
+	mockRepo := new(mocks.MockAgentRepository)
+	uc := NewAgentUsecase(mockRepo, "/config", 30)
+
+	t.Run("Without Workers", func(t *testing.T) {
+		mockRepo.On("UpdateHeartbeat", "agent-1", "v1", mock.AnythingOfType("time.Time"), []domain.AgentWorker(nil)).Return(nil).Once()
+
+		err := uc.Heartbeat(dto.AgentHeartbeatRequest{AgentID: "agent-1", AppliedVersion: "v1"})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("With Workers", func(t *testing.T) {
+		mockRepo.On("UpdateHeartbeat", "agent-1", "v2", mock.AnythingOfType("time.Time"), mock.MatchedBy(func(workers []domain.AgentWorker) bool {
+			return len(workers) == 1 && workers[0].URL == "http://worker-a:8082" &&
+				workers[0].AppliedVersion == "v1" && workers[0].PendingVersion == "v2"
+		})).Return(nil).Once()
+
+		err := uc.Heartbeat(dto.AgentHeartbeatRequest{
+			AgentID:        "agent-1",
+			AppliedVersion: "v2",
+			Workers:        []dto.WorkerHeartbeat{{URL: "http://worker-a:8082", AppliedVersion: "v1", PendingVersion: "v2"}},
+		})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestAgentUsecase_ListWorkers(t *testing.T) {
+	mockRepo := new(mocks.MockAgentRepository)
+	uc := NewAgentUsecase(mockRepo, "/config", 30)
+
+	mockRepo.On("ListWorkers", "agent-1").Return([]domain.AgentWorker{
+		{AgentID: "agent-1", URL: "http://worker-a:8082", AppliedVersion: "v1", LastError: "timeout"},
+	}, nil).Once()
+
+	workers, err := uc.ListWorkers("agent-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.AgentWorker{{URL: "http://worker-a:8082", AppliedVersion: "v1", LastError: "timeout"}}, workers)
+}
+
+func TestAgentUsecase_ReportEvent(t *testing.T) {
+	mockRepo := new(mocks.MockAgentRepository)
+	uc := NewAgentUsecase(mockRepo, "/config", 30)
+	req := dto.AgentEventRequest{AgentID: "agent-1", Type: domain.AgentEventDrift, DesiredVersion: "v2", ActualVersion: "v1"}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo.On("GetByID", "agent-1").Return(&domain.Agent{ID: "agent-1"}, nil).Once()
+		mockRepo.On("CreateEvent", mock.MatchedBy(func(e *domain.AgentEvent) bool {
+			return e.AgentID == "agent-1" && e.Type == domain.AgentEventDrift &&
+				e.DesiredVersion == "v2" && e.ActualVersion == "v1" && !e.CreatedAt.IsZero()
+		})).Return(nil).Once()
+
+		err := uc.ReportEvent(req)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown Agent", func(t *testing.T) {
+		mockRepo := new(mocks.MockAgentRepository)
+		uc := NewAgentUsecase(mockRepo, "/config", 30)
+		mockRepo.On("GetByID", "agent-1").Return(nil, gorm.ErrRecordNotFound).Once()
+
+		err := uc.ReportEvent(req)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		mockRepo.AssertNotCalled(t, "CreateEvent", mock.Anything)
+	})
+}
+
+func TestAgentUsecase_ListEvents(t *testing.T) {
+	mockRepo := new(mocks.MockAgentRepository)
+	uc := NewAgentUsecase(mockRepo, "/config", 30)
+
+	t.Run("Success", func(t *testing.T) {
+		now := time.Now()
+		mockRepo.On("ListEvents", "agent-1", 10).Return([]domain.AgentEvent{
+			{ID: 1, AgentID: "agent-1", Type: domain.AgentEventDrift, DesiredVersion: "v2", CreatedAt: now},
+		}, nil).Once()
+
+		events, err := uc.ListEvents("agent-1", 10)
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+		assert.Equal(t, "v2", events[0].DesiredVersion)
+		assert.Equal(t, now, events[0].CreatedAt)
+	})
+
+	t.Run("DB Error", func(t *testing.T) {
+		mockRepo.On("ListEvents", "agent-1", 10).Return(nil, errors.New("db error")).Once()
+
+		events, err := uc.ListEvents("agent-1", 10)
+		assert.Error(t, err)
+		assert.Nil(t, events)
+	})
+}
+
+func TestAgentUsecase_CountByAppliedVersion(t *testing.T) {
+	mockRepo := new(mocks.MockAgentRepository)
+	uc := NewAgentUsecase(mockRepo, "/config", 30)
+
+	mockRepo.On("CountByAppliedVersion").Return(map[string]int{"v1": 2}, nil).Once()
+	counts, err := uc.CountByAppliedVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"v1": 2}, counts)
+}
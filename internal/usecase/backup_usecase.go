@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"compress/gzip"
+	"config-manager/internal/domain"
+	"config-manager/internal/dto"
+	"config-manager/internal/repository"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type BackupUsecase interface {
+	Export(w io.Writer) error
+	Import(r io.Reader, overwrite bool) (*dto.BackupArchive, error)
+}
+
+type backupUsecase struct {
+	backupRepo repository.BackupRepository
+}
+
+func NewBackupUsecase(backupRepo repository.BackupRepository) BackupUsecase {
+	return &backupUsecase{backupRepo: backupRepo}
+}
+
+// Export writes a gzip-compressed JSON archive of a consistent snapshot to w.
+func (u *backupUsecase) Export(w io.Writer) error {
+	snapshot, err := u.backupRepo.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	archive := dto.BackupArchive{
+		Format:        dto.BackupFormat,
+		FormatVersion: dto.BackupFormatVersion,
+		SchemaVersion: snapshot.SchemaVersion,
+		CreatedAt:     snapshot.TakenAt.UTC(),
+		Agents:        make([]dto.BackupAgent, 0, len(snapshot.Agents)),
+		Workers:       make([]dto.BackupWorker, 0, len(snapshot.Workers)),
+		Configs:       make([]dto.BackupConfig, 0, len(snapshot.Configs)),
+		Events:        make([]dto.BackupEvent, 0, len(snapshot.Events)),
+	}
+	for _, a := range snapshot.Agents {
+		archive.Agents = append(archive.Agents, dto.BackupAgent{
+			ID:             a.ID,
+			Name:           a.Name,
+			URL:            a.URL,
+			ValidateToken:  a.ValidateToken,
+			AppliedVersion: a.AppliedVersion,
+			LastSeenAt:     a.LastSeenAt,
+			CreatedAt:      a.CreatedAt,
+		})
+	}
+	for _, w := range snapshot.Workers {
+		archive.Workers = append(archive.Workers, dto.BackupWorker{
+			AgentID:        w.AgentID,
+			URL:            w.URL,
+			AppliedVersion: w.AppliedVersion,
+			PendingVersion: w.PendingVersion,
+			LastError:      w.LastError,
+			UpdatedAt:      w.UpdatedAt,
+		})
+	}
+	for _, c := range snapshot.Configs {
+		archive.Configs = append(archive.Configs, dto.BackupConfig{
+			ID:        c.ID,
+			Version:   c.Version,
+			Config:    json.RawMessage(c.Config),
+			Tag:       c.Tag,
+			Pinned:    c.Pinned,
+			CreatedAt: c.CreatedAt,
+		})
+	}
+
+	for _, e := range snapshot.Events {
+		archive.Events = append(archive.Events, dto.BackupEvent{
+			ID:             e.ID,
+			AgentID:        e.AgentID,
+			WorkerURL:      e.WorkerURL,
+			Type:           e.Type,
+			DesiredVersion: e.DesiredVersion,
+			ActualVersion:  e.ActualVersion,
+			Message:        e.Message,
+			CreatedAt:      e.CreatedAt,
+		})
+	}
+
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(archive); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
+
+// Import reads an archive produced by Export and restores it into the repository.
+func (u *backupUsecase) Import(r io.Reader, overwrite bool) (*dto.BackupArchive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	var archive dto.BackupArchive
+	if err := json.NewDecoder(gz).Decode(&archive); err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %w", err)
+	}
+	if archive.Format != dto.BackupFormat {
+		return nil, fmt.Errorf("invalid backup archive: unexpected format %q", archive.Format)
+	}
+	if archive.FormatVersion > dto.BackupFormatVersion {
+		return nil, fmt.Errorf("backup format version %d is newer than supported version %d", archive.FormatVersion, dto.BackupFormatVersion)
+	}
+
+	schemaVersion, err := u.backupRepo.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if archive.SchemaVersion > schemaVersion {
+		return nil, fmt.Errorf("backup was taken at schema version %d but the database is at %d, run `config-manager migrate up` with a newer binary", archive.SchemaVersion, schemaVersion)
+	}
+
+	snapshot := &domain.Snapshot{
+		SchemaVersion: archive.SchemaVersion,
+		TakenAt:       archive.CreatedAt,
+		Agents:        make([]domain.Agent, 0, len(archive.Agents)),
+		Workers:       make([]domain.AgentWorker, 0, len(archive.Workers)),
+		Configs:       make([]domain.GlobalConfig, 0, len(archive.Configs)),
+		Events:        make([]domain.AgentEvent, 0, len(archive.Events)),
+	}
+	for _, a := range archive.Agents {
+		snapshot.Agents = append(snapshot.Agents, domain.Agent{
+			ID:             a.ID,
+			Name:           a.Name,
+			URL:            a.URL,
+			ValidateToken:  a.ValidateToken,
+			AppliedVersion: a.AppliedVersion,
+			LastSeenAt:     a.LastSeenAt,
+			CreatedAt:      a.CreatedAt,
+		})
+	}
+	for _, w := range archive.Workers {
+		snapshot.Workers = append(snapshot.Workers, domain.AgentWorker{
+			AgentID:        w.AgentID,
+			URL:            w.URL,
+			AppliedVersion: w.AppliedVersion,
+			PendingVersion: w.PendingVersion,
+			LastError:      w.LastError,
+			UpdatedAt:      w.UpdatedAt,
+		})
+	}
+	for _, c := range archive.Configs {
+		snapshot.Configs = append(snapshot.Configs, domain.GlobalConfig{
+			ID:        c.ID,
+			Version:   c.Version,
+			Config:    string(c.Config),
+			Tag:       c.Tag,
+			Pinned:    c.Pinned,
+			CreatedAt: c.CreatedAt,
+		})
+	}
+
+	for _, e := range archive.Events {
+		snapshot.Events = append(snapshot.Events, domain.AgentEvent{
+			ID:             e.ID,
+			AgentID:        e.AgentID,
+			WorkerURL:      e.WorkerURL,
+			Type:           e.Type,
+			DesiredVersion: e.DesiredVersion,
+			ActualVersion:  e.ActualVersion,
+			Message:        e.Message,
+			CreatedAt:      e.CreatedAt,
+		})
+	}
+
+	if err := u.backupRepo.Restore(snapshot, overwrite); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// BackupFileName returns the suggested file name for an archive taken at t.
+func BackupFileName(t time.Time) string {
+	return fmt.Sprintf("config-manager-%s.json.gz", t.UTC().Format("20060102T150405Z"))
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"bytes"
+	"compress/gzip"
+	"config-manager/internal/domain"
+	"config-manager/internal/dto"
+	"config-manager/internal/repository/mocks"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBackupUsecase_ExportImport(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	snapshot := &domain.Snapshot{
+		SchemaVersion: 1,
+		TakenAt:       now,
+		Agents:        []domain.Agent{{ID: "agent-1", Name: "a", URL: "http://agent-1:8081", ValidateToken: "agent-1-token", CreatedAt: now}},
+		Workers: []domain.AgentWorker{
+			{AgentID: "agent-1", URL: "http://worker-a:8082", AppliedVersion: "v1", UpdatedAt: now},
+		},
+		Configs: []domain.GlobalConfig{
+			{ID: 1, Version: "v1", Config: `{"url":"http://example.com"}`, CreatedAt: now},
+		},
+		Events: []domain.AgentEvent{
+			{ID: 1, AgentID: "agent-1", Type: domain.AgentEventDrift, DesiredVersion: "v1", CreatedAt: now},
+		},
+	}
+
+	var archive bytes.Buffer
+
+	t.Run("Export Success", func(t *testing.T) {
+		mockRepo := new(mocks.MockBackupRepository)
+		uc := NewBackupUsecase(mockRepo)
+		mockRepo.On("Snapshot").Return(snapshot, nil).Once()
+
+		err := uc.Export(&archive)
+		assert.NoError(t, err)
+
+		gz, err := gzip.NewReader(bytes.NewReader(archive.Bytes()))
+		assert.NoError(t, err)
+		var decoded dto.BackupArchive
+		assert.NoError(t, json.NewDecoder(gz).Decode(&decoded))
+		assert.Equal(t, dto.BackupFormat, decoded.Format)
+		assert.Equal(t, 1, decoded.SchemaVersion)
+		assert.JSONEq(t, `{"url":"http://example.com"}`, string(decoded.Configs[0].Config))
+		assert.Equal(t, "http://agent-1:8081", decoded.Agents[0].URL)
+		assert.Equal(t, "agent-1-token", decoded.Agents[0].ValidateToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Export Snapshot Error", func(t *testing.T) {
+		mockRepo := new(mocks.MockBackupRepository)
+		uc := NewBackupUsecase(mockRepo)
+		mockRepo.On("Snapshot").Return(nil, errors.New("db error")).Once()
+
+		err := uc.Export(&bytes.Buffer{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Import Success", func(t *testing.T) {
+		mockRepo := new(mocks.MockBackupRepository)
+		uc := NewBackupUsecase(mockRepo)
+		mockRepo.On("SchemaVersion").Return(1, nil).Once()
+		mockRepo.On("Restore", mock.MatchedBy(func(s *domain.Snapshot) bool {
+			return len(s.Agents) == 1 && len(s.Configs) == 1 && len(s.Events) == 1 &&
+				assert.ObjectsAreEqual(snapshot.Agents, s.Agents) &&
+				assert.ObjectsAreEqual(snapshot.Workers, s.Workers) &&
+				s.Configs[0].Config == `{"url":"http://example.com"}` &&
+				s.Events[0].DesiredVersion == "v1"
+		}), false).Return(nil).Once()
+
+		res, err := uc.Import(bytes.NewReader(archive.Bytes()), false)
+		assert.NoError(t, err)
+		assert.Len(t, res.Agents, 1)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Import Schema Newer Than Database", func(t *testing.T) {
+		mockRepo := new(mocks.MockBackupRepository)
+		uc := NewBackupUsecase(mockRepo)
+		mockRepo.On("SchemaVersion").Return(0, nil).Once()
+
+		_, err := uc.Import(bytes.NewReader(archive.Bytes()), false)
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Import Not Gzip", func(t *testing.T) {
+		uc := NewBackupUsecase(new(mocks.MockBackupRepository))
+		_, err := uc.Import(bytes.NewBufferString("plain text"), false)
+		assert.Error(t, err)
+	})
+
+	t.Run("Import Wrong Format", func(t *testing.T) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(`{"format":"something-else"}`))
+		gz.Close()
+
+		uc := NewBackupUsecase(new(mocks.MockBackupRepository))
+		_, err := uc.Import(&buf, false)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states, as reported by ListActions.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// ErrCircuitOpen is returned without calling the upstream while an action's
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitBreakerPolicy stops calling an upstream after FailureThreshold
+// consecutive failures. After OpenDuration, HalfOpenProbes calls are let
+// through; the circuit closes once they all succeed and opens again on the
+// first failure.
+type CircuitBreakerPolicy struct {
+	FailureThreshold int
+	OpenDuration     time.Duration
+	HalfOpenProbes   int
+}
+
+// circuitBreaker guards the upstream of a single action. A nil *circuitBreaker
+// lets every call through.
+type circuitBreaker struct {
+	policy CircuitBreakerPolicy
+	now    func() time.Time
+
+	mu        sync.Mutex
+	state     string
+	failures  int // consecutive, while closed
+	openedAt  time.Time
+	probes    int // in flight, while half-open
+	successes int // of probes, while half-open
+}
+
+func newCircuitBreaker(policy CircuitBreakerPolicy) *circuitBreaker {
+	return &circuitBreaker{policy: policy, now: time.Now, state: CircuitClosed}
+}
+
+// Allow returns ErrCircuitOpen when the call must not reach the upstream.
+// Every allowed call must be followed by Record or Release.
+func (b *circuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.halfOpenIfDue()
+	switch b.state {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.probes+b.successes >= b.policy.HalfOpenProbes {
+			return ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Record reports the outcome of an allowed call.
+func (b *circuitBreaker) Record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.policy.FailureThreshold {
+			b.open()
+		}
+	case CircuitHalfOpen:
+		b.probes--
+		if failed {
+			b.open()
+			return
+		}
+		b.successes++
+		if b.successes >= b.policy.HalfOpenProbes {
+			b.state = CircuitClosed
+			b.failures = 0
+		}
+	}
+	// Calls allowed before the circuit opened are ignored
+}
+
+// Release gives back an allowed call that says nothing about the upstream,
+// e.g. one cancelled by the caller, freeing its half-open probe.
+func (b *circuitBreaker) Release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitHalfOpen {
+		b.probes--
+	}
+}
+
+// State returns the current state, empty for a nil breaker.
+func (b *circuitBreaker) State() string {
+	if b == nil {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.halfOpenIfDue()
+	return b.state
+}
+
+func (b *circuitBreaker) open() {
+	b.state = CircuitOpen
+	b.openedAt = b.now()
+}
+
+func (b *circuitBreaker) halfOpenIfDue() {
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.policy.OpenDuration {
+		b.state = CircuitHalfOpen
+		b.probes = 0
+		b.successes = 0
+	}
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	b := newCircuitBreaker(CircuitBreakerPolicy{FailureThreshold: 2, OpenDuration: time.Minute, HalfOpenProbes: 2})
+	b.now = clock.Now
+
+	t.Run("Opens After Consecutive Failures", func(t *testing.T) {
+		for _, failed := range []bool{true, false, true} {
+			assert.NoError(t, b.Allow())
+			b.Record(failed)
+		}
+		assert.Equal(t, CircuitClosed, b.State(), "a success resets the failure count")
+
+		assert.NoError(t, b.Allow())
+		b.Record(true)
+		assert.Equal(t, CircuitOpen, b.State())
+		assert.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+	})
+
+	t.Run("Failed Probe Opens Again", func(t *testing.T) {
+		clock.Advance(time.Minute)
+		assert.Equal(t, CircuitHalfOpen, b.State())
+
+		assert.NoError(t, b.Allow())
+		b.Record(true)
+		assert.Equal(t, CircuitOpen, b.State())
+	})
+
+	t.Run("Successful Probes Close", func(t *testing.T) {
+		clock.Advance(time.Minute)
+
+		assert.NoError(t, b.Allow())
+		assert.NoError(t, b.Allow())
+		assert.ErrorIs(t, b.Allow(), ErrCircuitOpen, "only HalfOpenProbes calls are let through")
+
+		b.Record(false)
+		assert.Equal(t, CircuitHalfOpen, b.State())
+		b.Record(false)
+		assert.Equal(t, CircuitClosed, b.State())
+		assert.NoError(t, b.Allow())
+	})
+
+	t.Run("Released Probe Is Not A Success", func(t *testing.T) {
+		b.Record(true)
+		b.Record(true)
+		assert.Equal(t, CircuitOpen, b.State())
+		clock.Advance(time.Minute)
+
+		assert.NoError(t, b.Allow())
+		assert.NoError(t, b.Allow())
+		b.Release()
+		b.Release()
+		assert.Equal(t, CircuitHalfOpen, b.State())
+
+		assert.NoError(t, b.Allow(), "released probes free their slot")
+		b.Record(true)
+		assert.Equal(t, CircuitOpen, b.State())
+	})
+
+	t.Run("Nil Breaker", func(t *testing.T) {
+		var b *circuitBreaker
+		assert.NoError(t, b.Allow())
+		b.Record(true)
+		b.Release()
+		assert.Empty(t, b.State())
+	})
+}
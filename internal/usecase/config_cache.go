@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EncodedConfig is a config version whose response body is serialized once and
+// then shared, read-only, by every request that serves it.
+type EncodedConfig struct {
+	Version string
+	Config  json.RawMessage
+	// prefix holds the dto.ConfigResponse body up to the request ID value
+	prefix []byte
+}
+
+func NewEncodedConfig(version string, config json.RawMessage) *EncodedConfig {
+	versionJSON, _ := json.Marshal(version)
+
+	prefix := make([]byte, 0, len(config)+len(versionJSON)+64)
+	prefix = append(prefix, `{"config":`...)
+	prefix = append(prefix, config...)
+	prefix = append(prefix, `,"version":`...)
+	prefix = append(prefix, versionJSON...)
+	prefix = append(prefix, `,"code":200,"request_id":`...)
+
+	return &EncodedConfig{
+		Version: version,
+		Config:  config,
+		prefix:  prefix,
+	}
+}
+
+// ResponseBody returns the JSON encoding of a successful dto.ConfigResponse.
+func (e *EncodedConfig) ResponseBody(requestID string) []byte {
+	body := make([]byte, 0, len(e.prefix)+len(requestID)+4)
+	body = append(body, e.prefix...)
+	body = strconv.AppendQuote(body, requestID)
+	return append(body, '}')
+}
+
+// Response decodes the config into a dto.ConfigResponse.
+func (e *EncodedConfig) Response() (*dto.ConfigResponse, error) {
+	var configMap map[string]interface{}
+	if err := json.Unmarshal(e.Config, &configMap); err != nil {
+		return nil, err
+	}
+	return &dto.ConfigResponse{Config: configMap, Version: e.Version}, nil
+}
+
+type cachedConfigUsecase struct {
+	ConfigUsecase
+	ttl time.Duration
+	now func() time.Time
+
+	// mu is held for writing while the cache is refreshed, so concurrent misses
+	// trigger a single repository read and an invalidation always runs after it
+	mu        sync.RWMutex
+	latest    *EncodedConfig
+	fetchedAt time.Time
+}
+
+// NewCachedConfigUsecase wraps a ConfigUsecase with an in-memory cache of the
+// latest config. Saves through this usecase invalidate the cache immediately;
+// the ttl bounds staleness for writes made by other controller replicas.
+func NewCachedConfigUsecase(inner ConfigUsecase, ttl time.Duration) ConfigUsecase {
+	return &cachedConfigUsecase{
+		ConfigUsecase: inner,
+		ttl:           ttl,
+		now:           time.Now,
+	}
+}
+
+func (u *cachedConfigUsecase) Save(req dto.ConfigRequest) (string, error) {
+	version, err := u.ConfigUsecase.Save(req)
+	u.invalidate()
+	return version, err
+}
+
+func (u *cachedConfigUsecase) GetLatest() (*dto.ConfigResponse, error) {
+	encoded, err := u.GetLatestEncoded()
+	if err != nil {
+		return nil, err
+	}
+	return encoded.Response()
+}
+
+func (u *cachedConfigUsecase) GetLatestEncoded() (*EncodedConfig, error) {
+	if latest := u.cached(); latest != nil {
+		return latest, nil
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	// Another request may have refreshed the cache while we waited for the lock
+	if u.latest != nil && u.now().Sub(u.fetchedAt) < u.ttl {
+		return u.latest, nil
+	}
+
+	latest, err := u.ConfigUsecase.GetLatestEncoded()
+	if err != nil {
+		return nil, err
+	}
+	u.latest = latest
+	u.fetchedAt = u.now()
+	return latest, nil
+}
+
+func (u *cachedConfigUsecase) cached() *EncodedConfig {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	if u.latest != nil && u.now().Sub(u.fetchedAt) < u.ttl {
+		return u.latest
+	}
+	return nil
+}
+
+func (u *cachedConfigUsecase) invalidate() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.latest = nil
+}
@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"config-manager/internal/domain"
+	"config-manager/internal/dto"
+	"config-manager/internal/repository/mocks"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEncodedConfig_ResponseBody(t *testing.T) {
+	encoded := NewEncodedConfig(`v"1`, json.RawMessage(`{"url":"http://example.com"}`))
+
+	var res dto.ConfigResponse
+	assert.NoError(t, json.Unmarshal(encoded.ResponseBody(`req"1`), &res))
+	assert.Equal(t, `v"1`, res.Version)
+	assert.Equal(t, "http://example.com", res.Config["url"])
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, `req"1`, res.RequestID)
+
+	decoded, err := encoded.Response()
+	assert.NoError(t, err)
+	assert.Equal(t, `v"1`, decoded.Version)
+
+	_, err = NewEncodedConfig("v1", json.RawMessage(`invalid`)).Response()
+	assert.Error(t, err)
+}
+
+func TestCachedConfigUsecase(t *testing.T) {
+	latest := &domain.GlobalConfig{Version: "v1", Config: `{"url":"http://example.com"}`}
+
+	newCached := func(mockRepo *mocks.MockConfigRepository) (*cachedConfigUsecase, *time.Time) {
+		now := time.Now()
+		uc := NewCachedConfigUsecase(NewConfigUsecase(mockRepo), time.Minute).(*cachedConfigUsecase)
+		uc.now = func() time.Time { return now }
+		return uc, &now
+	}
+
+	t.Run("Serves From Cache", func(t *testing.T) {
+		mockRepo := new(mocks.MockConfigRepository)
+		uc, _ := newCached(mockRepo)
+		mockRepo.On("GetLatest").Return(latest, nil).Once()
+
+		for i := 0; i < 3; i++ {
+			res, err := uc.GetLatestEncoded()
+			assert.NoError(t, err)
+			assert.Equal(t, "v1", res.Version)
+		}
+
+		res, err := uc.GetLatest()
+		assert.NoError(t, err)
+		assert.Equal(t, "http://example.com", res.Config["url"])
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Save Invalidates", func(t *testing.T) {
+		mockRepo := new(mocks.MockConfigRepository)
+		uc, _ := newCached(mockRepo)
+		mockRepo.On("GetLatest").Return(latest, nil).Once()
+		mockRepo.On("Save", mock.AnythingOfType("*domain.GlobalConfig")).Return(nil).Once()
+		mockRepo.On("GetLatest").Return(&domain.GlobalConfig{Version: "v2", Config: `{}`}, nil).Once()
+
+		res, _ := uc.GetLatestEncoded()
+		assert.Equal(t, "v1", res.Version)
+
+		_, err := uc.Save(dto.ConfigRequest{Config: map[string]interface{}{}})
+		assert.NoError(t, err)
+
+		res, _ = uc.GetLatestEncoded()
+		assert.Equal(t, "v2", res.Version)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Expires After TTL", func(t *testing.T) {
+		mockRepo := new(mocks.MockConfigRepository)
+		uc, now := newCached(mockRepo)
+		mockRepo.On("GetLatest").Return(latest, nil).Twice()
+
+		uc.GetLatestEncoded()
+		*now = now.Add(2 * time.Minute)
+		uc.GetLatestEncoded()
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Errors Are Not Cached", func(t *testing.T) {
+		mockRepo := new(mocks.MockConfigRepository)
+		uc, _ := newCached(mockRepo)
+		mockRepo.On("GetLatest").Return(nil, errors.New("db error")).Once()
+		mockRepo.On("GetLatest").Return(latest, nil).Once()
+
+		_, err := uc.GetLatestEncoded()
+		assert.Error(t, err)
+		res, err := uc.GetLatestEncoded()
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", res.Version)
+		mockRepo.AssertExpectations(t)
+	})
+}
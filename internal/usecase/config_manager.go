@@ -2,63 +2,435 @@ package usecase
 
 import (
 	"config-manager/internal/dto"
+	"config-manager/pkg/shared/tracing"
+	"config-manager/pkg/shared/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"maps"
 	"net/http"
+	"os"
+	"slices"
 	"sync"
 	"time"
 )
 
 type ConfigManager interface {
 	UpdateConfig(req dto.ConfigRequest) error
-	ExecuteHit() (string, error)
+	GetConfig() dto.WorkerState
+	ExecuteHit(ctx context.Context, req dto.HitRequest) (*dto.HitResult, error)
+	ListActions() []dto.HitActionInfo
+	ValidateConfig(config map[string]interface{}) dto.ConfigValidation
 }
 
 type configManager struct {
 	mu         sync.RWMutex
 	config     map[string]interface{}
+	version    string
+	updatedAt  time.Time
+	actions    map[string]parsedAction // parsed from config, the default action under ""
+	actionsErr error                   // set when the named actions are malformed
+	limiter    *hitRateLimiter
+	egress     *EgressPolicy // nil allows any upstream
+	statePath  string        // empty keeps the config in memory only
 	httpClient *http.Client
 }
 
-func NewConfigManager() ConfigManager {
-	return &configManager{
-		config:     make(map[string]interface{}),
-		httpClient: &http.Client{Timeout: 10 * time.Second},
+// NewConfigManager returns a ConfigManager calling only the upstreams egress
+// allows, or any upstream when egress is nil.
+func NewConfigManager(egress *EgressPolicy) ConfigManager {
+	m := &configManager{
+		// Requests are bounded by the timeout of their action
+		httpClient: &http.Client{Transport: tracing.NewTransport(nil)},
+		limiter:    newHitRateLimiter(),
+		egress:     egress,
 	}
+	if egress != nil {
+		m.httpClient = &http.Client{Transport: tracing.NewTransport(egress.transport()), CheckRedirect: egress.checkRedirect}
+	}
+	m.setConfig(make(map[string]interface{}))
+	return m
+}
+
+// NewPersistentConfigManager returns a ConfigManager that saves every accepted
+// config to statePath and starts from the state saved there by a previous run.
+// A missing state file is not an error. A saved config the egress policy no
+// longer allows is restored, but its denied actions fail.
+func NewPersistentConfigManager(statePath string, egress *EgressPolicy) (ConfigManager, error) {
+	m := NewConfigManager(egress).(*configManager)
+	m.statePath = statePath
+
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state dto.WorkerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid worker state file %s: %w", statePath, err)
+	}
+	if state.Config != nil {
+		m.setConfig(state.Config)
+	}
+	m.version = state.Version
+	m.updatedAt = state.UpdatedAt
+	return m, nil
 }
 
+// UpdateConfig applies req, unless one of its actions calls an upstream the
+// egress policy denies.
 func (m *configManager) UpdateConfig(req dto.ConfigRequest) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	actions, actionsErr := parseHitActions(req.Config, m.actions, m.egress)
+	for _, name := range slices.Sorted(maps.Keys(actions)) {
+		if err := actions[name].err; errors.Is(err, ErrEgressDenied) {
+			if name == "" {
+				return err
+			}
+			return fmt.Errorf("action %s: %w", name, err)
+		}
+	}
+
+	updatedAt := time.Now().UTC()
+	if m.statePath != "" {
+		// Persist first so the worker never runs a config it would lose on restart
+		data, err := json.Marshal(dto.WorkerState{
+			Version:   req.Version,
+			Config:    req.Config,
+			UpdatedAt: updatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if err := utils.WriteFileAtomic(m.statePath, data, 0o600); err != nil {
+			return fmt.Errorf("failed to persist worker state: %w", err)
+		}
+	}
+
 	m.config = req.Config
+	m.actions, m.actionsErr = actions, actionsErr
+	m.limiter.Update(ParseRateLimitPolicy(req.Config))
+	m.version = req.Version
+	m.updatedAt = updatedAt
 	return nil
 }
 
-func (m *configManager) ExecuteHit() (string, error) {
+// setConfig replaces the config, its parsed actions and rate limits. Invalid
+// actions and limits are still accepted and only fail /hit, so the rest of the
+// config keeps applying.
+func (m *configManager) setConfig(config map[string]interface{}) {
+	m.config = config
+	m.actions, m.actionsErr = parseHitActions(config, m.actions, m.egress)
+	m.limiter.Update(ParseRateLimitPolicy(config))
+}
+
+// ValidateConfig checks config as UpdateConfig would, without applying it.
+func (m *configManager) ValidateConfig(config map[string]interface{}) dto.ConfigValidation {
+	return ValidateWorkerConfig(config, m.egress)
+}
+
+// ValidateWorkerConfig returns what a worker would find wrong in config: its
+// invalid actions, a malformed "actions" object and invalid rate limits. With
+// an egress policy, the upstreams it denies are reported too.
+func ValidateWorkerConfig(config map[string]interface{}, egress *EgressPolicy) dto.ConfigValidation {
+	issues := []dto.ConfigIssue{}
+	actions, err := parseHitActions(config, nil, egress)
+	if err != nil {
+		issues = append(issues, dto.ConfigIssue{Error: err.Error()})
+	}
+	for _, name := range slices.Sorted(maps.Keys(actions)) {
+		err := actions[name].err
+		// A config may only have named actions
+		if err == nil || name == "" && errors.Is(err, errURLNotConfigured) {
+			continue
+		}
+		issues = append(issues, dto.ConfigIssue{Action: name, Error: err.Error()})
+	}
+	if _, err := ParseRateLimitPolicy(config); err != nil {
+		issues = append(issues, dto.ConfigIssue{Error: err.Error()})
+	}
+	return dto.ConfigValidation{Valid: len(issues) == 0, Issues: issues}
+}
+
+func (m *configManager) GetConfig() dto.WorkerState {
 	m.mu.RLock()
-	urlInter, ok := m.config["url"]
-	m.mu.RUnlock()
+	defer m.mu.RUnlock()
 
-	if !ok {
-		return "", fmt.Errorf("URL not configured")
+	return dto.WorkerState{
+		Version:   m.version,
+		Config:    m.config,
+		UpdatedAt: m.updatedAt,
 	}
+}
 
-	urlStr, ok := urlInter.(string)
-	if !ok {
-		return "", fmt.Errorf("configured URL is not a string")
+// ConfigDigest returns a hex sha256 of the canonical JSON encoding of config, so
+// agents and workers can compare configs without comparing versions.
+func ConfigDigest(config map[string]interface{}) string {
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	// encoding/json sorts map keys, so equal configs always encode identically
+	body, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *configManager) ExecuteHit(ctx context.Context, req dto.HitRequest) (*dto.HitResult, error) {
+	if err := m.limiter.Allow(req); err != nil {
+		return nil, err
+	}
+	parsed, version, err := m.lookupAction(req.Action)
+	if version != "" {
+		// Ties the /hit span and the upstream calls to the config that served them
+		tracing.SetAttributes(ctx, tracing.AttrConfigVersion.String(version))
+		ctx = tracing.WithClientAttributes(ctx, tracing.AttrConfigVersion.String(version))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := parsed.action.RenderBody(req, version)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.cache == nil {
+		return m.hit(ctx, parsed, body)
+	}
+	return parsed.cache.Get(ctx, body, func(ctx context.Context) (*dto.HitResult, error) {
+		return m.hit(ctx, parsed, body)
+	})
+}
+
+// hit calls the upstream of an action, retrying as its policy allows within
+// the action's timeout. The timeout of a streamed response runs until its body
+// is closed.
+func (m *configManager) hit(ctx context.Context, parsed parsedAction, body string) (result *dto.HitResult, err error) {
+	action := parsed.action
+	ctx, cancel := context.WithTimeout(ctx, action.Timeout)
+	defer func() {
+		if result != nil && result.Stream != nil {
+			result.Stream = onClose(result.Stream, cancel)
+		} else {
+			cancel()
+		}
+	}()
+
+	attempts := 1
+	if action.Retry != nil {
+		attempts = action.Retry.Attempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		if breakerErr := parsed.breaker.Allow(); breakerErr != nil {
+			if attempt == 1 {
+				return nil, breakerErr
+			}
+			return result, err
+		}
+		// The previous attempt is being retried, its response is dropped
+		if result != nil && result.Stream != nil {
+			result.Stream.Close()
+		}
+
+		upstream := parsed.balancer.Pick()
+		result, err = m.attempt(ctx, action, action.Upstreams[upstream].URL, body)
+		failed, cancelled := upstreamOutcome(result, err)
+		done := func() { parsed.balancer.Done(upstream, failed) }
+		if cancelled {
+			parsed.breaker.Release()
+			done = func() { parsed.balancer.Release(upstream) }
+		} else {
+			parsed.breaker.Record(failed)
+		}
+		if result != nil && result.Stream != nil {
+			// Still outstanding until the body is streamed
+			result.Stream = onClose(result.Stream, done)
+		} else {
+			done()
+		}
+
+		if attempt >= attempts || !action.Retry.retryable(result, err) {
+			return result, err
+		}
+		timer := time.NewTimer(backoffDelay(action.Retry.Backoff, action.Retry.MaxBackoff, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
+
+func (m *configManager) attempt(ctx context.Context, action *HitAction, upstreamURL, body string) (*dto.HitResult, error) {
+	cancel := context.CancelFunc(func() {})
+	if action.AttemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, action.AttemptTimeout)
 	}
 
-	resp, err := m.httpClient.Get(urlStr)
+	httpReq, err := action.NewRequest(ctx, upstreamURL, body)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp, err := m.httpClient.Do(httpReq)
 	if err != nil {
-		return "", err
+		cancel()
+		return nil, err
+	}
+	result := &dto.HitResult{StatusCode: resp.StatusCode, Headers: resp.Header}
+	if action.Passthrough {
+		return result, stream(resp, result, action, cancel)
 	}
+	defer cancel()
 	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	// Read one byte past the limit to tell a full body from a truncated one
+	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, action.MaxResponseBytes+1))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if int64(len(bodyBytes)) > action.MaxResponseBytes {
+		return result, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, action.MaxResponseBytes)
 	}
+	result.Body = string(bodyBytes)
+
+	if !action.Expects(resp.StatusCode) {
+		return result, fmt.Errorf("%w %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+	return result, nil
+}
 
-	return string(bodyBytes), nil
+// stream hands the body of resp to the caller as result.Stream, capped to the
+// action's max_response_bytes. Responses the action rejects are closed.
+func stream(resp *http.Response, result *dto.HitResult, action *HitAction, cancel context.CancelFunc) error {
+	var err error
+	switch {
+	case resp.ContentLength > action.MaxResponseBytes:
+		err = fmt.Errorf("%w: %d bytes", ErrResponseTooLarge, resp.ContentLength)
+	case !action.Expects(resp.StatusCode):
+		err = fmt.Errorf("%w %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+	if err != nil {
+		resp.Body.Close()
+		cancel()
+		return err
+	}
+
+	result.Stream = onClose(struct {
+		io.Reader
+		io.Closer
+	}{&cappedReader{r: resp.Body, remaining: action.MaxResponseBytes}, resp.Body}, cancel)
+	return nil
+}
+
+// cappedReader fails with ErrResponseTooLarge past remaining bytes, where
+// io.LimitReader would silently truncate.
+type cappedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > c.remaining+1 {
+		p = p[:c.remaining+1]
+	}
+	n, err := c.r.Read(p)
+	if int64(n) > c.remaining {
+		n = int(c.remaining)
+		c.remaining = 0
+		return n, ErrResponseTooLarge
+	}
+	c.remaining -= int64(n)
+	return n, err
+}
+
+// onClose returns rc calling fn once, after rc is closed.
+func onClose(rc io.ReadCloser, fn func()) io.ReadCloser {
+	return &closeHook{ReadCloser: rc, fn: fn}
+}
+
+type closeHook struct {
+	io.ReadCloser
+	fn   func()
+	once sync.Once
+}
+
+func (c *closeHook) Close() error {
+	err := c.ReadCloser.Close()
+	c.once.Do(c.fn)
+	return err
+}
+
+// upstreamOutcome reports whether an attempt failed, counting against the
+// circuit breaker and the upstream's ejection: the upstream did not answer in
+// time or answered with a server error. Calls cancelled by the caller count
+// neither as a failure nor as a success.
+func upstreamOutcome(result *dto.HitResult, err error) (failed, cancelled bool) {
+	if result != nil {
+		return result.StatusCode >= http.StatusInternalServerError, false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false, true
+	}
+	return err != nil, false
+}
+
+func (m *configManager) lookupAction(name string) (parsedAction, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if name != "" && m.actionsErr != nil {
+		return parsedAction{}, "", m.actionsErr
+	}
+	parsed, ok := m.actions[name]
+	if !ok {
+		return parsedAction{}, "", fmt.Errorf("%w: %s", ErrActionNotFound, name)
+	}
+	return parsed, m.version, parsed.err
+}
+
+// ListActions returns the configured actions sorted by name, the default action
+// first when the config has a top-level url.
+func (m *configManager) ListActions() []dto.HitActionInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := slices.Sorted(maps.Keys(m.actions))
+	infos := make([]dto.HitActionInfo, 0, len(names))
+	for _, name := range names {
+		parsed := m.actions[name]
+		if name == "" && errors.Is(parsed.err, errURLNotConfigured) {
+			continue
+		}
+		info := dto.HitActionInfo{Name: name, Path: "/hit"}
+		if name != "" {
+			info.Path += "/" + name
+		}
+		if parsed.err != nil {
+			info.Error = parsed.err.Error()
+		} else {
+			info.Method = parsed.action.Method
+			info.URL = parsed.action.URL
+			info.Circuit = parsed.breaker.State()
+			if parsed.action.URL == "" {
+				info.Upstreams = parsed.balancer.Upstreams()
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
 }
@@ -2,12 +2,22 @@ package usecase
 
 import (
 	"config-manager/internal/dto"
+	"config-manager/pkg/shared/tracing"
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestConfigManager_UpdateAndHit(t *testing.T) {
@@ -18,10 +28,10 @@ func TestConfigManager_UpdateAndHit(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	cm := NewConfigManager()
+	cm := NewConfigManager(nil)
 
 	t.Run("Hit Without Config", func(t *testing.T) {
-		_, err := cm.ExecuteHit()
+		_, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
 		assert.Error(t, err)
 		assert.Equal(t, "URL not configured", err.Error())
 	})
@@ -35,7 +45,7 @@ func TestConfigManager_UpdateAndHit(t *testing.T) {
 		err := cm.UpdateConfig(req)
 		assert.NoError(t, err)
 
-		_, err = cm.ExecuteHit()
+		_, err = cm.ExecuteHit(t.Context(), dto.HitRequest{})
 		assert.Error(t, err)
 		assert.Equal(t, "configured URL is not a string", err.Error())
 	})
@@ -50,7 +60,7 @@ func TestConfigManager_UpdateAndHit(t *testing.T) {
 		assert.NoError(t, err)
 
 		cm.(*configManager).httpClient.Timeout = 50 * time.Millisecond // fast fail
-		_, err = cm.ExecuteHit()
+		_, err = cm.ExecuteHit(t.Context(), dto.HitRequest{})
 		assert.Error(t, err)
 	})
 
@@ -63,8 +73,677 @@ func TestConfigManager_UpdateAndHit(t *testing.T) {
 		err := cm.UpdateConfig(req)
 		assert.NoError(t, err)
 
-		res, err := cm.ExecuteHit()
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
 		assert.NoError(t, err)
-		assert.Equal(t, "mocked response", res)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "mocked response", res.Body)
 	})
 }
+
+func TestConfigManager_ExecuteHitAction(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/items":
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("X-Upstream", r.Method+" "+r.URL.RawQuery+" "+r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 64)))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	cm := NewConfigManager(nil)
+	update := func(t *testing.T, config map[string]interface{}) {
+		assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Version: "v1", Config: config}))
+	}
+
+	t.Run("Honours The Action", func(t *testing.T) {
+		update(t, map[string]interface{}{
+			"url":             ts.URL + "/items",
+			"method":          "POST",
+			"headers":         map[string]interface{}{"Authorization": "Bearer secret"},
+			"query":           map[string]interface{}{"limit": "10"},
+			"body":            `{"name":"{{.Query.name}}"}`,
+			"expected_status": []interface{}{float64(201)},
+		})
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{Query: map[string]string{"name": "widget"}})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, "POST limit=10 Bearer secret", res.Headers.Get("X-Upstream"))
+		assert.Equal(t, `{"name":"widget"}`, res.Body)
+	})
+
+	t.Run("Unexpected Status", func(t *testing.T) {
+		update(t, map[string]interface{}{"url": ts.URL + "/missing", "expected_status": []interface{}{float64(200)}})
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.ErrorIs(t, err, ErrUnexpectedStatus)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, "not found", res.Body)
+	})
+
+	t.Run("Response Too Large", func(t *testing.T) {
+		update(t, map[string]interface{}{"url": ts.URL + "/large", "max_response_bytes": float64(16)})
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.ErrorIs(t, err, ErrResponseTooLarge)
+		assert.Empty(t, res.Body)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		update(t, map[string]interface{}{"url": ts.URL + "/slow", "timeout": "20ms"})
+
+		_, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Invalid Action", func(t *testing.T) {
+		update(t, map[string]interface{}{"url": ts.URL, "timeout": 5})
+
+		_, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.EqualError(t, err, `timeout must be a duration string, e.g. "5s"`)
+	})
+}
+
+func TestConfigManager_PassthroughAction(t *testing.T) {
+	binary := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write(binary)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/large":
+			w.Header().Set("Content-Length", "64")
+			w.Write([]byte(strings.Repeat("x", 64)))
+		case "/chunked":
+			for range 4 {
+				w.Write([]byte(strings.Repeat("x", 16)))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer ts.Close()
+
+	cm := NewConfigManager(nil)
+	update := func(t *testing.T, path string, extra map[string]interface{}) {
+		config := map[string]interface{}{"url": ts.URL + path, "response": "passthrough"}
+		for k, v := range extra {
+			config[k] = v
+		}
+		assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Version: "v1", Config: config}))
+	}
+
+	t.Run("Streams The Response", func(t *testing.T) {
+		update(t, "/image", nil)
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.NoError(t, err)
+		defer res.Stream.Close()
+		assert.Equal(t, http.StatusAccepted, res.StatusCode)
+		assert.Equal(t, "image/png", res.Headers.Get("Content-Type"))
+		assert.Empty(t, res.Body)
+		body, err := io.ReadAll(res.Stream)
+		assert.NoError(t, err)
+		assert.Equal(t, binary, body)
+	})
+
+	t.Run("Unexpected Status", func(t *testing.T) {
+		update(t, "/missing", map[string]interface{}{"expected_status": []interface{}{float64(200)}})
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.ErrorIs(t, err, ErrUnexpectedStatus)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Nil(t, res.Stream)
+	})
+
+	t.Run("Declared Length Too Large", func(t *testing.T) {
+		update(t, "/large", map[string]interface{}{"max_response_bytes": float64(16)})
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.ErrorIs(t, err, ErrResponseTooLarge)
+		assert.Nil(t, res.Stream)
+	})
+
+	t.Run("Streamed Body Too Large", func(t *testing.T) {
+		update(t, "/chunked", map[string]interface{}{"max_response_bytes": float64(40)})
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.NoError(t, err)
+		defer res.Stream.Close()
+		body, err := io.ReadAll(res.Stream)
+		assert.ErrorIs(t, err, ErrResponseTooLarge)
+		assert.Len(t, body, 40)
+	})
+
+	t.Run("Upstream Outstanding Until Closed", func(t *testing.T) {
+		assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Version: "v2", Config: map[string]interface{}{
+			"upstreams": []interface{}{map[string]interface{}{"url": ts.URL + "/image"}},
+			"response":  "passthrough",
+		}}))
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, cm.ListActions()[0].Upstreams[0].Outstanding)
+		res.Stream.Close()
+		assert.Equal(t, 0, cm.ListActions()[0].Upstreams[0].Outstanding)
+	})
+}
+
+func TestConfigManager_HitTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	cm := NewConfigManager(nil)
+	assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Config: map[string]interface{}{"url": ts.URL}, Version: "v3"}))
+
+	ctx, server := tracing.Tracer().Start(t.Context(), "GET /hit")
+	_, err := cm.ExecuteHit(ctx, dto.HitRequest{})
+	assert.NoError(t, err)
+	server.End()
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		client := spans[0]
+		assert.Equal(t, "client", client.SpanKind().String())
+		assert.Equal(t, server.SpanContext().SpanID(), client.Parent().SpanID())
+		assert.Contains(t, client.Attributes(), tracing.AttrConfigVersion.String("v3"))
+		assert.Contains(t, spans[1].Attributes(), tracing.AttrConfigVersion.String("v3"))
+	}
+}
+
+func TestConfigManager_NamedActions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.URL.Path))
+	}))
+	defer ts.Close()
+
+	cm := NewConfigManager(nil)
+	err := cm.UpdateConfig(dto.ConfigRequest{Version: "v1", Config: map[string]interface{}{
+		"url": ts.URL + "/default",
+		"actions": map[string]interface{}{
+			"create": map[string]interface{}{"url": ts.URL + "/items", "method": "POST", "body": "{{.Body}}"},
+			"broken": map[string]interface{}{"method": "GET"},
+			"a/b":    map[string]interface{}{"url": ts.URL},
+		},
+	}})
+	assert.NoError(t, err)
+
+	t.Run("Routes By Name", func(t *testing.T) {
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{Action: "create", Body: "{}"})
+		assert.NoError(t, err)
+		assert.Equal(t, "POST /items", res.Body)
+
+		res, err = cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, "GET /default", res.Body)
+	})
+
+	t.Run("Unknown Action", func(t *testing.T) {
+		_, err := cm.ExecuteHit(t.Context(), dto.HitRequest{Action: "delete"})
+		assert.ErrorIs(t, err, ErrActionNotFound)
+	})
+
+	t.Run("Invalid Action", func(t *testing.T) {
+		_, err := cm.ExecuteHit(t.Context(), dto.HitRequest{Action: "broken"})
+		assert.EqualError(t, err, "URL not configured")
+	})
+
+	t.Run("Lists Actions", func(t *testing.T) {
+		assert.Equal(t, []dto.HitActionInfo{
+			{Name: "", Path: "/hit", Method: http.MethodGet, URL: ts.URL + "/default"},
+			{Name: "a/b", Path: "/hit/a/b", Error: `invalid action name "a/b"`},
+			{Name: "broken", Path: "/hit/broken", Error: "URL not configured"},
+			{Name: "create", Path: "/hit/create", Method: http.MethodPost, URL: ts.URL + "/items"},
+		}, cm.ListActions())
+	})
+
+	t.Run("Without Default Action", func(t *testing.T) {
+		err := cm.UpdateConfig(dto.ConfigRequest{Config: map[string]interface{}{"actions": "create"}})
+		assert.NoError(t, err)
+
+		assert.Empty(t, cm.ListActions())
+		_, err = cm.ExecuteHit(t.Context(), dto.HitRequest{Action: "create"})
+		assert.EqualError(t, err, "actions must be an object of named actions")
+	})
+}
+
+func TestConfigManager_CachedAction(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(r.URL.Query().Get("id")))
+	}))
+	defer ts.Close()
+
+	cm := NewConfigManager(nil)
+	config := map[string]interface{}{
+		"url":   ts.URL,
+		"query": map[string]interface{}{"id": "1"},
+		"body":  "{{.Query.q}}",
+		"cache": map[string]interface{}{"ttl": "1m"},
+	}
+	assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Version: "v1", Config: config}))
+
+	res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{Query: map[string]string{"q": "a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, CacheMiss, res.Cache)
+	res, err = cm.ExecuteHit(t.Context(), dto.HitRequest{Query: map[string]string{"q": "a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, CacheHit, res.Cache)
+
+	// A different rendered request is cached separately
+	res, err = cm.ExecuteHit(t.Context(), dto.HitRequest{Query: map[string]string{"q": "b"}})
+	assert.NoError(t, err)
+	assert.Equal(t, CacheMiss, res.Cache)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Applying a config flushes the cache
+	config["query"] = map[string]interface{}{"id": "2"}
+	assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Version: "v2", Config: config}))
+	res, err = cm.ExecuteHit(t.Context(), dto.HitRequest{Query: map[string]string{"q": "a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, CacheMiss, res.Cache)
+	assert.Equal(t, "2", res.Body)
+}
+
+func TestConfigManager_RetryAndCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var failUntil atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		switch {
+		case r.URL.Path == "/slow" && n == 1:
+			time.Sleep(200 * time.Millisecond)
+		case n <= failUntil.Load():
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	cm := NewConfigManager(nil)
+	update := func(t *testing.T, config map[string]interface{}) {
+		calls.Store(0)
+		assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Version: "v1", Config: config}))
+	}
+	retry := map[string]interface{}{"attempts": float64(3), "backoff": "1ms"}
+
+	t.Run("Retries Chosen Statuses", func(t *testing.T) {
+		update(t, map[string]interface{}{"url": ts.URL, "retry": retry})
+		failUntil.Store(2)
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", res.Body)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("Gives Up After Attempts", func(t *testing.T) {
+		update(t, map[string]interface{}{"url": ts.URL, "retry": retry, "expected_status": []interface{}{float64(200)}})
+		failUntil.Store(5)
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.ErrorIs(t, err, ErrUnexpectedStatus)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("Other Statuses Are Not Retried", func(t *testing.T) {
+		update(t, map[string]interface{}{"url": ts.URL, "retry": map[string]interface{}{
+			"attempts": float64(3), "backoff": "1ms", "on_status": []interface{}{float64(429)},
+		}})
+		failUntil.Store(5)
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Per Attempt Timeout", func(t *testing.T) {
+		update(t, map[string]interface{}{"url": ts.URL + "/slow", "retry": retry, "attempt_timeout": "50ms"})
+		failUntil.Store(0)
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", res.Body)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("Circuit Breaker Opens", func(t *testing.T) {
+		update(t, map[string]interface{}{
+			"url":             ts.URL,
+			"circuit_breaker": map[string]interface{}{"failure_threshold": float64(2), "open_duration": "1m"},
+		})
+		failUntil.Store(5)
+
+		for range 2 {
+			_, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+			assert.NoError(t, err)
+		}
+		_, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.Equal(t, int32(2), calls.Load())
+		assert.Equal(t, CircuitOpen, cm.ListActions()[0].Circuit)
+	})
+
+	t.Run("Cancelled Probe Does Not Close", func(t *testing.T) {
+		update(t, map[string]interface{}{
+			"url":             ts.URL,
+			"circuit_breaker": map[string]interface{}{"failure_threshold": float64(1), "open_duration": "1m"},
+		})
+		clock := &fakeClock{now: time.Now()}
+		cm.(*configManager).actions[""].breaker.now = clock.Now
+		failUntil.Store(1)
+
+		_, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, CircuitOpen, cm.ListActions()[0].Circuit)
+		clock.Advance(time.Minute)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		_, err = cm.ExecuteHit(ctx, dto.HitRequest{})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, CircuitHalfOpen, cm.ListActions()[0].Circuit)
+
+		// The probe slot was given back, a real probe decides
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", res.Body)
+		assert.Equal(t, CircuitClosed, cm.ListActions()[0].Circuit)
+	})
+
+	t.Run("Open Circuit Survives Unrelated Pushes", func(t *testing.T) {
+		breaker := map[string]interface{}{"failure_threshold": float64(1), "open_duration": "1m"}
+		update(t, map[string]interface{}{"url": ts.URL, "circuit_breaker": breaker})
+		failUntil.Store(1)
+		_, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, CircuitOpen, cm.ListActions()[0].Circuit)
+
+		update(t, map[string]interface{}{
+			"url":             ts.URL,
+			"circuit_breaker": breaker,
+			"actions":         map[string]interface{}{"other": map[string]interface{}{"url": ts.URL}},
+		})
+		assert.Equal(t, CircuitOpen, cm.ListActions()[0].Circuit)
+		_, err = cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+
+		// A new policy or upstream starts over
+		update(t, map[string]interface{}{
+			"url":             ts.URL,
+			"circuit_breaker": map[string]interface{}{"failure_threshold": float64(2), "open_duration": "1m"},
+		})
+		assert.Equal(t, CircuitClosed, cm.ListActions()[0].Circuit)
+	})
+}
+
+func TestConfigManager_Upstreams(t *testing.T) {
+	release := make(chan struct{})
+	newUpstream := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("wait") != "" {
+				<-release
+			}
+			w.Write([]byte(name))
+		}))
+	}
+	a, b := newUpstream("a"), newUpstream("b")
+	defer a.Close()
+	defer b.Close()
+
+	cm := NewConfigManager(nil)
+	config := func(upstreams ...string) map[string]interface{} {
+		list := make([]interface{}, len(upstreams))
+		for i, u := range upstreams {
+			list[i] = map[string]interface{}{"url": u}
+		}
+		return map[string]interface{}{
+			"actions": map[string]interface{}{
+				"spread": map[string]interface{}{"upstreams": list},
+				"slow":   map[string]interface{}{"url": a.URL, "query": map[string]interface{}{"wait": "1"}},
+			},
+		}
+	}
+	assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Version: "v1", Config: config(a.URL, b.URL)}))
+
+	t.Run("Spreads Requests", func(t *testing.T) {
+		var bodies []string
+		for range 4 {
+			res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{Action: "spread"})
+			assert.NoError(t, err)
+			bodies = append(bodies, res.Body)
+		}
+		assert.ElementsMatch(t, []string{"a", "a", "b", "b"}, bodies)
+	})
+
+	t.Run("Reconfigured Without Dropping In-Flight Requests", func(t *testing.T) {
+		done := make(chan error, 1)
+		go func() {
+			_, err := cm.ExecuteHit(t.Context(), dto.HitRequest{Action: "slow"})
+			done <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Version: "v2", Config: config(b.URL)}))
+		close(release)
+		assert.NoError(t, <-done)
+
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{Action: "spread"})
+		assert.NoError(t, err)
+		assert.Equal(t, "b", res.Body)
+		actions := cm.ListActions()
+		assert.Equal(t, []dto.HitUpstreamInfo{{URL: b.URL, Weight: 1}}, actions[1].Upstreams)
+	})
+}
+
+func TestConfigManager_RateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	cm := NewConfigManager(nil)
+	err := cm.UpdateConfig(dto.ConfigRequest{Version: "v1", Config: map[string]interface{}{
+		"url":        ts.URL,
+		"rate_limit": map[string]interface{}{"per_client": map[string]interface{}{"rate": 0.1, "burst": float64(1)}},
+	}})
+	assert.NoError(t, err)
+
+	_, err = cm.ExecuteHit(t.Context(), dto.HitRequest{ClientIP: "10.0.0.1"})
+	assert.NoError(t, err)
+	_, err = cm.ExecuteHit(t.Context(), dto.HitRequest{ClientIP: "10.0.0.1"})
+	var limitErr *RateLimitError
+	assert.ErrorAs(t, err, &limitErr)
+	assert.InDelta(t, 10*time.Second, limitErr.RetryAfter, float64(time.Second))
+
+	// Unknown actions are limited too, so they cannot be used to flood the worker
+	_, err = cm.ExecuteHit(t.Context(), dto.HitRequest{Action: "missing", ClientIP: "10.0.0.1"})
+	assert.ErrorAs(t, err, &limitErr)
+}
+
+func TestConfigManager_EgressPolicy(t *testing.T) {
+	var redirect string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, redirect, http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	loopback, err := ParseEgressPolicy(nil, nil, []string{"127.0.0.0/8"})
+	assert.NoError(t, err)
+	cm := NewConfigManager(loopback)
+	assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Version: "v1", Config: map[string]interface{}{"url": ts.URL}}))
+
+	t.Run("Allowed Upstream", func(t *testing.T) {
+		res, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", res.Body)
+	})
+
+	t.Run("Denied Config Is Rejected", func(t *testing.T) {
+		err := cm.UpdateConfig(dto.ConfigRequest{Version: "v2", Config: map[string]interface{}{"url": "http://169.254.169.254/latest/meta-data/"}})
+		assert.ErrorIs(t, err, ErrEgressDenied)
+
+		err = cm.UpdateConfig(dto.ConfigRequest{Version: "v2", Config: map[string]interface{}{
+			"url":     ts.URL,
+			"actions": map[string]interface{}{"admin": map[string]interface{}{"url": "gopher://127.0.0.1:8082/"}},
+		}})
+		assert.ErrorIs(t, err, ErrEgressDenied)
+		assert.ErrorContains(t, err, "action admin")
+		assert.Equal(t, "v1", cm.GetConfig().Version)
+	})
+
+	t.Run("Denied Redirect", func(t *testing.T) {
+		redirect = "http://169.254.169.254/latest/meta-data/"
+		assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Version: "v3", Config: map[string]interface{}{"url": ts.URL + "/redirect"}}))
+
+		_, err := cm.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.ErrorIs(t, err, ErrEgressDenied)
+	})
+
+	t.Run("Denied After DNS Resolution", func(t *testing.T) {
+		// localhost passes the URL check as a name, its address is refused when dialing
+		strict := NewConfigManager(&EgressPolicy{Schemes: []string{"http"}})
+		port := ts.URL[strings.LastIndex(ts.URL, ":"):]
+		assert.NoError(t, strict.UpdateConfig(dto.ConfigRequest{Version: "v1", Config: map[string]interface{}{"url": "http://localhost" + port}}))
+
+		_, err := strict.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.ErrorIs(t, err, ErrEgressDenied)
+	})
+
+	t.Run("Restored Denied Config Fails Its Actions", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "worker-state.json")
+		state := `{"version":"v1","config":{"url":"http://169.254.169.254/"}}`
+		assert.NoError(t, os.WriteFile(path, []byte(state), 0o600))
+
+		restored, err := NewPersistentConfigManager(path, loopback)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", restored.GetConfig().Version)
+		_, err = restored.ExecuteHit(t.Context(), dto.HitRequest{})
+		assert.ErrorIs(t, err, ErrEgressDenied)
+	})
+}
+
+func TestValidateWorkerConfig(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		res := ValidateWorkerConfig(map[string]interface{}{
+			"actions": map[string]interface{}{"status": map[string]interface{}{"url": "http://example.com/status"}},
+		}, nil)
+		assert.Equal(t, dto.ConfigValidation{Valid: true, Issues: []dto.ConfigIssue{}}, res)
+	})
+
+	t.Run("Every Issue", func(t *testing.T) {
+		egress, _ := ParseEgressPolicy(nil, nil, nil)
+		res := ValidateWorkerConfig(map[string]interface{}{
+			"url": "http://example.com",
+			"actions": map[string]interface{}{
+				"admin":  map[string]interface{}{"url": "http://127.0.0.1:8082/v1/config"},
+				"broken": map[string]interface{}{"url": "http://example.com", "method": 1},
+			},
+			"rate_limit": map[string]interface{}{"global": map[string]interface{}{"rate": float64(0)}},
+		}, egress)
+		assert.False(t, res.Valid)
+		assert.Equal(t, []dto.ConfigIssue{
+			{Action: "admin", Error: "egress denied: address 127.0.0.1 is not allowed"},
+			{Action: "broken", Error: "method must be a non-empty string"},
+			{Error: "rate_limit.global.rate must be a positive number"},
+		}, res.Issues)
+	})
+
+	t.Run("Does Not Apply", func(t *testing.T) {
+		cm := NewConfigManager(nil)
+		assert.True(t, cm.ValidateConfig(map[string]interface{}{"url": "http://example.com"}).Valid)
+		assert.Empty(t, cm.GetConfig().Config)
+	})
+}
+
+func TestConfigManager_GetConfig(t *testing.T) {
+	cm := NewConfigManager(nil)
+
+	state := cm.GetConfig()
+	assert.Empty(t, state.Version)
+	assert.Empty(t, state.Config)
+	assert.True(t, state.UpdatedAt.IsZero())
+
+	err := cm.UpdateConfig(dto.ConfigRequest{Version: "v1", Config: map[string]interface{}{"url": "http://example.com"}})
+	assert.NoError(t, err)
+
+	state = cm.GetConfig()
+	assert.Equal(t, "v1", state.Version)
+	assert.Equal(t, "http://example.com", state.Config["url"])
+	assert.False(t, state.UpdatedAt.IsZero())
+}
+
+func TestPersistentConfigManager(t *testing.T) {
+	t.Run("Restores Last Accepted Config", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "worker-state.json")
+
+		cm, err := NewPersistentConfigManager(path, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, cm.GetConfig().Version)
+
+		err = cm.UpdateConfig(dto.ConfigRequest{Version: "v2", Config: map[string]interface{}{"url": "http://example.com"}})
+		assert.NoError(t, err)
+
+		restarted, err := NewPersistentConfigManager(path, nil)
+		assert.NoError(t, err)
+		state := restarted.GetConfig()
+		assert.Equal(t, "v2", state.Version)
+		assert.Equal(t, "http://example.com", state.Config["url"])
+		assert.Equal(t, cm.GetConfig().UpdatedAt, state.UpdatedAt)
+	})
+
+	t.Run("Invalid State File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "worker-state.json")
+		assert.NoError(t, os.WriteFile(path, []byte("invalid json"), 0o600))
+
+		cm, err := NewPersistentConfigManager(path, nil)
+		assert.Error(t, err)
+		assert.Nil(t, cm)
+	})
+
+	t.Run("Write Failure Keeps Previous Config", func(t *testing.T) {
+		dir := t.TempDir()
+		cm, err := NewPersistentConfigManager(filepath.Join(dir, "worker-state.json"), nil)
+		assert.NoError(t, err)
+		assert.NoError(t, cm.UpdateConfig(dto.ConfigRequest{Version: "v1", Config: map[string]interface{}{}}))
+
+		cm.(*configManager).statePath = filepath.Join(dir, "missing", "worker-state.json")
+		err = cm.UpdateConfig(dto.ConfigRequest{Version: "v2", Config: map[string]interface{}{}})
+		assert.Error(t, err)
+		assert.Equal(t, "v1", cm.GetConfig().Version)
+	})
+}
+
+func TestConfigDigest(t *testing.T) {
+	a := ConfigDigest(map[string]interface{}{"url": "http://example.com", "retries": 3})
+	b := ConfigDigest(map[string]interface{}{"retries": 3, "url": "http://example.com"})
+	assert.Equal(t, a, b)
+	assert.Len(t, a, 64)
+
+	assert.NotEqual(t, a, ConfigDigest(map[string]interface{}{"url": "http://other.example"}))
+	assert.Equal(t, ConfigDigest(nil), ConfigDigest(map[string]interface{}{}))
+}
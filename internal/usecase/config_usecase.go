@@ -13,8 +13,10 @@ import (
 )
 
 type ConfigUsecase interface {
-	Save(req dto.ConfigRequest) error
+	Save(req dto.ConfigRequest) (string, error) // returns the new version
 	GetLatest() (*dto.ConfigResponse, error)
+	GetLatestEncoded() (*EncodedConfig, error)
+	SetPinned(version string, pinned bool) error
 }
 
 type configUsecase struct {
@@ -25,19 +27,23 @@ func NewConfigUsecase(configRepo repository.ConfigRepository) ConfigUsecase {
 	return &configUsecase{configRepo: configRepo}
 }
 
-func (u *configUsecase) Save(req dto.ConfigRequest) error {
+func (u *configUsecase) Save(req dto.ConfigRequest) (string, error) {
 	configBytes, err := json.Marshal(req.Config)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	newConfig := &domain.GlobalConfig{
 		Config:    string(configBytes),
 		Version:   uuid.New().String(),
+		Tag:       req.Tag,
 		CreatedAt: time.Now(),
 	}
 
-	return u.configRepo.Save(newConfig)
+	if err := u.configRepo.Save(newConfig); err != nil {
+		return "", err
+	}
+	return newConfig.Version, nil
 }
 
 func (u *configUsecase) GetLatest() (*dto.ConfigResponse, error) {
@@ -63,3 +69,20 @@ func (u *configUsecase) GetLatest() (*dto.ConfigResponse, error) {
 		Version: config.Version,
 	}, nil
 }
+
+// GetLatestEncoded returns the latest config without decoding it, the stored
+// JSON is used as-is in the response body.
+func (u *configUsecase) GetLatestEncoded() (*EncodedConfig, error) {
+	config, err := u.configRepo.GetLatest()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return NewEncodedConfig("0", json.RawMessage(`{}`)), nil
+		}
+		return nil, err
+	}
+	return NewEncodedConfig(config.Version, json.RawMessage(config.Config)), nil
+}
+
+func (u *configUsecase) SetPinned(version string, pinned bool) error {
+	return u.configRepo.SetPinned(version, pinned)
+}
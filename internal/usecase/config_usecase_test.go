@@ -23,8 +23,9 @@ func TestConfigUsecase_Save(t *testing.T) {
 		}
 		mockRepo.On("Save", mock.AnythingOfType("*domain.GlobalConfig")).Return(nil).Once()
 
-		err := uc.Save(req)
+		version, err := uc.Save(req)
 		assert.NoError(t, err)
+		assert.NotEmpty(t, version)
 		mockRepo.AssertExpectations(t)
 	})
 
@@ -33,7 +34,7 @@ func TestConfigUsecase_Save(t *testing.T) {
 		req := dto.ConfigRequest{
 			Config: map[string]interface{}{"invalid": make(chan int)},
 		}
-		err := uc.Save(req)
+		_, err := uc.Save(req)
 		assert.Error(t, err)
 	})
 
@@ -43,7 +44,7 @@ func TestConfigUsecase_Save(t *testing.T) {
 		}
 		mockRepo.On("Save", mock.AnythingOfType("*domain.GlobalConfig")).Return(errors.New("db error")).Once()
 
-		err := uc.Save(req)
+		_, err := uc.Save(req)
 		assert.Error(t, err)
 		mockRepo.AssertExpectations(t)
 	})
@@ -101,3 +102,45 @@ func TestConfigUsecase_GetLatest(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestConfigUsecase_GetLatestEncoded(t *testing.T) {
+	mockRepo := new(mocks.MockConfigRepository)
+	uc := NewConfigUsecase(mockRepo)
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo.On("GetLatest").Return(&domain.GlobalConfig{Version: "v1.0", Config: `{"url":"http://example.com"}`}, nil).Once()
+
+		res, err := uc.GetLatestEncoded()
+		assert.NoError(t, err)
+		assert.Equal(t, "v1.0", res.Version)
+		assert.JSONEq(t, `{"url":"http://example.com"}`, string(res.Config))
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockRepo.On("GetLatest").Return(nil, gorm.ErrRecordNotFound).Once()
+
+		res, err := uc.GetLatestEncoded()
+		assert.NoError(t, err)
+		assert.Equal(t, "0", res.Version)
+		assert.JSONEq(t, `{}`, string(res.Config))
+	})
+
+	t.Run("DB Error", func(t *testing.T) {
+		mockRepo.On("GetLatest").Return(nil, errors.New("db error")).Once()
+
+		res, err := uc.GetLatestEncoded()
+		assert.Error(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+func TestConfigUsecase_SetPinned(t *testing.T) {
+	mockRepo := new(mocks.MockConfigRepository)
+	uc := NewConfigUsecase(mockRepo)
+
+	mockRepo.On("SetPinned", "v1", true).Return(nil).Once()
+
+	err := uc.SetPinned("v1", true)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
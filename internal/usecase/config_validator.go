@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"bytes"
+	"config-manager/internal/domain"
+	"config-manager/internal/dto"
+	"config-manager/internal/repository"
+	"config-manager/pkg/shared/tracing"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ConfigValidator dry-runs a candidate config before it is saved.
+type ConfigValidator interface {
+	Validate(ctx context.Context, req dto.ConfigValidateRequest) (*dto.ConfigValidateResponse, error)
+}
+
+type ConfigValidatorConfig struct {
+	ActiveWithin time.Duration // only agents seen this recently are sampled
+	Timeout      time.Duration // for each sampled agent, workers included
+}
+
+type configValidator struct {
+	agentRepo  repository.AgentRepository
+	cfg        ConfigValidatorConfig
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+func NewConfigValidator(agentRepo repository.AgentRepository, cfg ConfigValidatorConfig) ConfigValidator {
+	return &configValidator{
+		agentRepo:  agentRepo,
+		cfg:        cfg,
+		httpClient: &http.Client{Transport: tracing.NewTransport(nil)},
+		now:        time.Now,
+	}
+}
+
+// Validate runs the checks of a worker without its egress policy, then asks up
+// to req.Agents reachable agents, picked at random, to have their workers
+// check the config too. A config is only valid once every check asked for ran.
+func (v *configValidator) Validate(ctx context.Context, req dto.ConfigValidateRequest) (*dto.ConfigValidateResponse, error) {
+	if req.Config == nil {
+		return &dto.ConfigValidateResponse{ConfigValidation: dto.ConfigValidation{
+			Issues: []dto.ConfigIssue{{Error: "config is required"}},
+		}}, nil
+	}
+	res := &dto.ConfigValidateResponse{ConfigValidation: ValidateWorkerConfig(req.Config, nil), Complete: true}
+	if req.Agents <= 0 {
+		return res, nil
+	}
+
+	agents, err := v.agentRepo.ListReachable(v.now().Add(-v.cfg.ActiveWithin))
+	if err != nil {
+		return nil, err
+	}
+	rand.Shuffle(len(agents), func(i, j int) { agents[i], agents[j] = agents[j], agents[i] })
+	agents = agents[:min(req.Agents, len(agents))]
+
+	res.Agents = make([]dto.AgentConfigValidation, len(agents))
+	var g errgroup.Group
+	for i, agent := range agents {
+		g.Go(func() error {
+			res.Agents[i] = v.validateOnAgent(ctx, agent, req.Config)
+			return nil
+		})
+	}
+	g.Wait()
+
+	// No agent, an agent or worker that could not be asked, or an agent without
+	// workers leaves the config unchecked where it would run.
+	checked := 0
+	for _, agent := range res.Agents {
+		if agent.Error != "" {
+			res.Complete = false
+		}
+		for _, worker := range agent.Workers {
+			if worker.Error != "" {
+				res.Complete = false
+				continue
+			}
+			checked++
+			if !worker.Valid {
+				res.Valid = false
+			}
+		}
+	}
+	if checked == 0 {
+		res.Complete = false
+	}
+	res.Valid = res.Valid && res.Complete
+	return res, nil
+}
+
+func (v *configValidator) validateOnAgent(ctx context.Context, agent domain.Agent, config map[string]interface{}) dto.AgentConfigValidation {
+	result := dto.AgentConfigValidation{AgentID: agent.ID, URL: agent.URL, Workers: []dto.WorkerConfigValidation{}}
+	workers, err := v.askAgent(ctx, agent, config)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Workers = workers
+	return result
+}
+
+// askAgent has agent forward config to each of its workers. The agent chose its
+// URL, so it is only sent the token it was issued when registering.
+func (v *configValidator) askAgent(ctx context.Context, agent domain.Agent, config map[string]interface{}) ([]dto.WorkerConfigValidation, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.cfg.Timeout)
+	defer cancel()
+
+	reqBody, err := json.Marshal(dto.ConfigRequest{Config: config})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, agent.URL+"/v1/admin/config/validate", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", agent.ValidateToken)
+
+	resp, err := v.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to validate config on agent, status: %d", resp.StatusCode)
+	}
+	var agentResp dto.AgentConfigValidateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&agentResp); err != nil {
+		return nil, err
+	}
+	if agentResp.Workers == nil {
+		agentResp.Workers = []dto.WorkerConfigValidation{}
+	}
+	return agentResp.Workers, nil
+}
@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"config-manager/internal/domain"
+	"config-manager/internal/dto"
+	"config-manager/internal/repository/mocks"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestConfigValidator(t *testing.T) {
+	config := map[string]interface{}{"url": "http://example.com", "actions": map[string]interface{}{"admin": map[string]interface{}{"url": "http://10.0.0.1/"}}}
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/admin/config/validate", r.URL.Path)
+		assert.Equal(t, "agent-1-token", r.Header.Get("Authorization"), "only the agent's own token")
+		var req dto.ConfigRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, config, req.Config)
+
+		json.NewEncoder(w).Encode(dto.AgentConfigValidateResponse{Workers: []dto.WorkerConfigValidation{
+			{URL: "http://worker-a:8082", ConfigValidation: dto.ConfigValidation{Valid: true, Issues: []dto.ConfigIssue{}}},
+			{URL: "http://worker-b:8082", ConfigValidation: dto.ConfigValidation{Issues: []dto.ConfigIssue{{Action: "admin", Error: "egress denied"}}}},
+		}})
+	}))
+	defer agent.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer down.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(dto.AgentConfigValidateResponse{Workers: []dto.WorkerConfigValidation{
+			{URL: "http://worker-c:8082", ConfigValidation: dto.ConfigValidation{Valid: true, Issues: []dto.ConfigIssue{}}},
+		}})
+	}))
+	defer healthy.Close()
+
+	now := time.Now()
+	newValidator := func(repo *mocks.MockAgentRepository) ConfigValidator {
+		v := NewConfigValidator(repo, ConfigValidatorConfig{ActiveWithin: time.Minute, Timeout: time.Second})
+		v.(*configValidator).now = func() time.Time { return now }
+		return v
+	}
+
+	t.Run("Controller Checks Only", func(t *testing.T) {
+		repo := mocks.NewMockAgentRepository(t)
+		res, err := newValidator(repo).Validate(t.Context(), dto.ConfigValidateRequest{Config: map[string]interface{}{
+			"url":        "http://example.com",
+			"timeout":    "soon",
+			"rate_limit": "none",
+		}})
+		assert.NoError(t, err)
+		assert.False(t, res.Valid)
+		assert.Equal(t, []dto.ConfigIssue{
+			{Error: `invalid timeout "soon"`},
+			{Error: "rate_limit must be an object"},
+		}, res.Issues)
+		assert.True(t, res.Complete)
+		assert.Nil(t, res.Agents)
+	})
+
+	t.Run("Missing Config", func(t *testing.T) {
+		// No agent is asked about a config that is not there
+		res, err := newValidator(mocks.NewMockAgentRepository(t)).Validate(t.Context(), dto.ConfigValidateRequest{Agents: 1})
+		assert.NoError(t, err)
+		assert.False(t, res.Valid)
+		assert.Equal(t, []dto.ConfigIssue{{Error: "config is required"}}, res.Issues)
+		assert.Nil(t, res.Agents)
+	})
+
+	t.Run("Checked On Every Sampled Agent", func(t *testing.T) {
+		repo := mocks.NewMockAgentRepository(t)
+		repo.EXPECT().ListReachable(mock.Anything).Return([]domain.Agent{{ID: "agent-3", URL: healthy.URL}}, nil).Once()
+
+		res, err := newValidator(repo).Validate(t.Context(), dto.ConfigValidateRequest{
+			Config: map[string]interface{}{"url": "http://example.com"},
+			Agents: 1,
+		})
+		assert.NoError(t, err)
+		assert.True(t, res.Complete)
+		assert.True(t, res.Valid)
+	})
+
+	t.Run("Sampled Agents", func(t *testing.T) {
+		repo := mocks.NewMockAgentRepository(t)
+		repo.EXPECT().ListReachable(now.Add(-time.Minute)).Return([]domain.Agent{
+			{ID: "agent-1", URL: agent.URL, ValidateToken: "agent-1-token"},
+			{ID: "agent-2", URL: down.URL, ValidateToken: "agent-2-token"},
+		}, nil).Once()
+
+		res, err := newValidator(repo).Validate(t.Context(), dto.ConfigValidateRequest{Config: config, Agents: 5})
+		assert.NoError(t, err)
+		assert.Empty(t, res.Issues, "the controller has no egress policy")
+		assert.False(t, res.Valid, "a worker found an issue")
+		assert.False(t, res.Complete, "agent-2 could not be asked")
+		assert.Len(t, res.Agents, 2)
+		for _, a := range res.Agents {
+			switch a.AgentID {
+			case "agent-1":
+				assert.Empty(t, a.Error)
+				assert.Len(t, a.Workers, 2)
+			case "agent-2":
+				assert.Equal(t, "failed to validate config on agent, status: 401", a.Error)
+				assert.Empty(t, a.Workers)
+			}
+		}
+	})
+
+	t.Run("Samples At Most The Requested Agents", func(t *testing.T) {
+		repo := mocks.NewMockAgentRepository(t)
+		repo.EXPECT().ListReachable(mock.Anything).Return([]domain.Agent{
+			{ID: "agent-1", URL: down.URL},
+			{ID: "agent-2", URL: down.URL},
+			{ID: "agent-3", URL: down.URL},
+		}, nil).Once()
+
+		res, err := newValidator(repo).Validate(t.Context(), dto.ConfigValidateRequest{Config: config, Agents: 2})
+		assert.NoError(t, err)
+		assert.False(t, res.Valid, "no worker checked the config")
+		assert.False(t, res.Complete)
+		assert.Len(t, res.Agents, 2)
+	})
+
+	t.Run("No Reachable Agents", func(t *testing.T) {
+		repo := mocks.NewMockAgentRepository(t)
+		repo.EXPECT().ListReachable(mock.Anything).Return(nil, nil).Once()
+
+		res, err := newValidator(repo).Validate(t.Context(), dto.ConfigValidateRequest{
+			Config: map[string]interface{}{"url": "http://example.com"},
+			Agents: 1,
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, res.Issues)
+		assert.False(t, res.Valid)
+		assert.False(t, res.Complete)
+	})
+
+	t.Run("Repository Error", func(t *testing.T) {
+		repo := mocks.NewMockAgentRepository(t)
+		repo.EXPECT().ListReachable(mock.Anything).Return(nil, errors.New("db error")).Once()
+
+		_, err := newValidator(repo).Validate(t.Context(), dto.ConfigValidateRequest{Config: config, Agents: 1})
+		assert.EqualError(t, err, "db error")
+	})
+}
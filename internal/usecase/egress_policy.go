@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrEgressDenied is returned for an upstream the worker's egress policy does
+// not allow, whether it is configured or reached through DNS or a redirect.
+var ErrEgressDenied = errors.New("egress denied")
+
+const maxHitRedirects = 10
+
+// blockedPrefixes are not covered by the netip.Addr predicates checked in
+// allowsAddr but only reach the local network or the machine itself.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network", routed to the host on Linux
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT, also some cloud metadata services
+}
+
+// EgressPolicy restricts the upstreams a worker calls. It is set on the worker
+// itself, pushed configs cannot relax it. Addresses are checked after DNS
+// resolution, when connecting, so a host name cannot be pointed at a private
+// address once the config is accepted.
+type EgressPolicy struct {
+	Schemes []string       // allowed URL schemes
+	Hosts   []string       // allowed host names, "*.example.com" matches its subdomains; empty allows any
+	CIDRs   []netip.Prefix // addresses allowed even when private, loopback or link-local
+}
+
+// ParseEgressPolicy returns the policy allowing schemes, http and https when
+// empty, hosts, and the addresses in cidrs on top of public ones.
+func ParseEgressPolicy(schemes, hosts, cidrs []string) (*EgressPolicy, error) {
+	policy := &EgressPolicy{}
+	for _, scheme := range schemes {
+		if scheme = strings.ToLower(strings.TrimSpace(scheme)); scheme != "" {
+			policy.Schemes = append(policy.Schemes, scheme)
+		}
+	}
+	if len(policy.Schemes) == 0 {
+		policy.Schemes = []string{"http", "https"}
+	}
+	for _, host := range hosts {
+		if host = normalizeHost(host); host != "" {
+			policy.Hosts = append(policy.Hosts, host)
+		}
+	}
+	for _, cidr := range cidrs {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid egress CIDR %q: %w", cidr, err)
+		}
+		policy.CIDRs = append(policy.CIDRs, prefix.Masked())
+	}
+	return policy, nil
+}
+
+// CheckURL returns an ErrEgressDenied error when rawURL has a scheme or host the
+// policy does not allow, or is an address it does not allow. A nil policy
+// allows every URL.
+func (p *EgressPolicy) CheckURL(rawURL string) error {
+	if p == nil {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid URL %q", ErrEgressDenied, rawURL)
+	}
+	if !slices.Contains(p.Schemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrEgressDenied, u.Scheme)
+	}
+
+	host := normalizeHost(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("%w: %q has no host", ErrEgressDenied, rawURL)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !p.allowsAddr(addr) {
+			return fmt.Errorf("%w: address %s is not allowed", ErrEgressDenied, addr)
+		}
+		if len(p.Hosts) > 0 && !slices.Contains(p.Hosts, host) && !p.inCIDRs(addr) {
+			return fmt.Errorf("%w: host %s is not allowed", ErrEgressDenied, host)
+		}
+		return nil
+	}
+	if len(p.Hosts) > 0 && !slices.ContainsFunc(p.Hosts, func(pattern string) bool { return matchHost(pattern, host) }) {
+		return fmt.Errorf("%w: host %s is not allowed", ErrEgressDenied, host)
+	}
+	return nil
+}
+
+// CheckAction checks every upstream of action.
+func (p *EgressPolicy) CheckAction(action *HitAction) error {
+	for _, upstream := range action.Upstreams {
+		if err := p.CheckURL(upstream.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allowsAddr reports whether the worker may connect to addr: public addresses
+// and those in the policy's CIDRs.
+func (p *EgressPolicy) allowsAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if p.inCIDRs(addr) {
+		return true
+	}
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	return !slices.ContainsFunc(blockedPrefixes, func(prefix netip.Prefix) bool { return prefix.Contains(addr) })
+}
+
+func (p *EgressPolicy) inCIDRs(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return slices.ContainsFunc(p.CIDRs, func(prefix netip.Prefix) bool { return prefix.Contains(addr) })
+}
+
+// control is a net.Dialer Control function refusing connections to addresses
+// the policy does not allow. It runs for every address DNS returned, right
+// before connecting to it.
+func (p *EgressPolicy) control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: invalid address %q", ErrEgressDenied, address)
+	}
+	if !p.allowsAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: address %s is not allowed", ErrEgressDenied, addrPort.Addr())
+	}
+	return nil
+}
+
+// checkRedirect applies the policy to every redirect the client follows.
+func (p *EgressPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxHitRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxHitRedirects)
+	}
+	return p.CheckURL(req.URL.String())
+}
+
+// transport returns a transport dialing through the policy. Proxies are not
+// used, the policy could only check the address of the proxy.
+func (p *EgressPolicy) transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: p.control}
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// matchHost reports whether host is pattern or, for "*.example.com", one of
+// its subdomains.
+func matchHost(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return host == pattern
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEgressPolicy(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		policy, err := ParseEgressPolicy(nil, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, &EgressPolicy{Schemes: []string{"http", "https"}}, policy)
+	})
+
+	t.Run("Normalized", func(t *testing.T) {
+		policy, err := ParseEgressPolicy([]string{"HTTPS"}, []string{" API.example.com. ", ""}, []string{"10.1.2.3/16"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"https"}, policy.Schemes)
+		assert.Equal(t, []string{"api.example.com"}, policy.Hosts)
+		assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}, policy.CIDRs)
+	})
+
+	t.Run("Invalid CIDR", func(t *testing.T) {
+		_, err := ParseEgressPolicy(nil, nil, []string{"10.0.0.0"})
+		assert.ErrorContains(t, err, `invalid egress CIDR "10.0.0.0"`)
+	})
+}
+
+func TestEgressPolicy_CheckURL(t *testing.T) {
+	open, _ := ParseEgressPolicy(nil, nil, []string{"10.1.0.0/16"})
+	restricted, _ := ParseEgressPolicy([]string{"https"}, []string{"api.example.com", "*.svc.example.com"}, []string{"10.1.0.0/16"})
+
+	tests := []struct {
+		name    string
+		policy  *EgressPolicy
+		url     string
+		allowed bool
+	}{
+		{"Public Host", open, "http://example.com/items", true},
+		{"Public Address", open, "http://93.184.215.14/", true},
+		{"Scheme", open, "file:///etc/passwd", false},
+		{"Metadata Service", open, "http://169.254.169.254/latest/meta-data/", false},
+		{"Loopback", open, "http://127.0.0.1:8082/v1/config", false},
+		{"IPv6 Loopback", open, "http://[::1]/", false},
+		{"IPv4-Mapped Loopback", open, "http://[::ffff:127.0.0.1]/", false},
+		{"Private", open, "http://192.168.1.1/", false},
+		{"Carrier-Grade NAT", open, "http://100.100.100.200/", false},
+		{"Unspecified", open, "http://0.0.0.0:8080/", false},
+		{"Allowed CIDR", open, "http://10.1.2.3/", true},
+		{"Listed Host", restricted, "https://API.example.com./v1", true},
+		{"Subdomain", restricted, "https://orders.svc.example.com/", true},
+		{"Wildcard Apex", restricted, "https://svc.example.com/", false},
+		{"Unlisted Host", restricted, "https://evil.example.org/", false},
+		{"Unlisted Scheme", restricted, "http://api.example.com/", false},
+		{"Address In CIDRs", restricted, "https://10.1.2.3/", true},
+		{"Public Address Not Listed", restricted, "https://93.184.215.14/", false},
+		{"Nil Policy", nil, "http://127.0.0.1/", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.CheckURL(tc.url)
+			if tc.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrEgressDenied)
+			}
+		})
+	}
+}
+
+func TestEgressPolicy_Control(t *testing.T) {
+	policy, _ := ParseEgressPolicy(nil, nil, []string{"127.0.0.0/8"})
+
+	assert.NoError(t, policy.control("tcp4", "127.0.0.1:8080", nil))
+	assert.NoError(t, policy.control("tcp4", "93.184.215.14:443", nil))
+	assert.ErrorIs(t, policy.control("tcp4", "169.254.169.254:80", nil), ErrEgressDenied)
+	assert.ErrorIs(t, policy.control("tcp6", "[fe80::1]:80", nil), ErrEgressDenied)
+}
@@ -0,0 +1,624 @@
+package usecase
+
+import (
+	"bytes"
+	"config-manager/internal/dto"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultHitTimeout          = 10 * time.Second
+	defaultMaxHitResponseBytes = 10 << 20
+	defaultHitCacheEntries     = 100
+	defaultHitRetryBackoff     = 100 * time.Millisecond
+	defaultHitRetryMaxBackoff  = 2 * time.Second
+	defaultHalfOpenProbes      = 1
+	defaultEjectionFailures    = 5
+	defaultEjectionCooldown    = 30 * time.Second
+)
+
+// Response modes of an action.
+const (
+	HitResponseJSON        = "json"        // the upstream response is wrapped in the /hit JSON response
+	HitResponsePassthrough = "passthrough" // status, headers and body are streamed as received
+)
+
+// defaultRetryStatus are the statuses retried when retry.on_status is not set.
+var defaultRetryStatus = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+var (
+	// ErrUnexpectedStatus is returned, along with the response, when the upstream
+	// answers with a status the action does not expect.
+	ErrUnexpectedStatus = errors.New("unexpected upstream status")
+	// ErrResponseTooLarge is returned when the upstream body exceeds the action's
+	// max_response_bytes.
+	ErrResponseTooLarge = errors.New("upstream response too large")
+	// ErrActionNotFound is returned for an action name the config does not define.
+	ErrActionNotFound = errors.New("action not found")
+
+	errURLNotConfigured = errors.New("URL not configured")
+)
+
+// actionNamePattern keeps action names usable as a single path segment.
+var actionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// HitAction is the upstream request a worker's /hit proxy performs, read from
+// the worker's config:
+//
+//	{
+//	  "url": "https://api.example.com/v1/items",
+//	  "upstreams": [{"url": "https://a.example.com/v1/items", "weight": 3}, {"url": "https://b.example.com/v1/items"}],
+//	  "balancer": "round_robin",
+//	  "ejection": {"consecutive_failures": 5, "cooldown": "30s"},
+//	  "method": "POST",
+//	  "headers": {"Authorization": "Bearer ..."},
+//	  "query": {"limit": "10"},
+//	  "body": "{\"name\": \"{{.Query.name}}\"}",
+//	  "timeout": "5s",
+//	  "attempt_timeout": "2s",
+//	  "expected_status": [200, 201],
+//	  "max_response_bytes": 1048576,
+//	  "response": "json",
+//	  "cache": {"ttl": "30s", "stale_while_revalidate": "10s", "max_entries": 100, "respect_cache_control": true},
+//	  "retry": {"attempts": 3, "backoff": "100ms", "max_backoff": "2s", "on_status": [502, 503, 504], "on_error": true},
+//	  "circuit_breaker": {"failure_threshold": 5, "open_duration": "30s", "half_open_probes": 1}
+//	}
+//
+// Either url or upstreams is required. The body is a text/template executed with the /hit
+// query parameters as .Query, the /hit request body as .Body and the config
+// version as .Version.
+type HitAction struct {
+	URL              string        // with the configured query parameters applied, empty when upstreams are listed
+	Upstreams        []HitUpstream // a single one of weight 1 for url, with the query parameters applied
+	Balancer         string
+	Ejection         EjectionPolicy
+	Method           string
+	Headers          map[string]string
+	Body             *template.Template // nil sends no body
+	Timeout          time.Duration      // for all attempts together
+	AttemptTimeout   time.Duration      // for a single attempt, 0 leaves only Timeout
+	ExpectedStatus   []int              // empty accepts any status
+	MaxResponseBytes int64
+	Passthrough      bool                  // stream the upstream response instead of wrapping it in JSON
+	Cache            *HitCachePolicy       // nil calls the upstream on every hit
+	Retry            *HitRetryPolicy       // nil makes a single attempt
+	CircuitBreaker   *CircuitBreakerPolicy // nil never stops calling the upstream
+}
+
+// HitRetryPolicy retries failed attempts of an action, with the delay doubled
+// after every attempt and jittered like agent pushes.
+type HitRetryPolicy struct {
+	Attempts   int // including the first one
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	OnStatus   []int // upstream statuses retried
+	OnError    bool  // whether errors without a response, e.g. timeouts, are retried
+}
+
+// retryable reports whether an attempt ending with result and err is retried.
+func (p *HitRetryPolicy) retryable(result *dto.HitResult, err error) bool {
+	if result != nil {
+		return slices.Contains(p.OnStatus, result.StatusCode)
+	}
+	return err != nil && p.OnError
+}
+
+// HitCachePolicy caches the successful responses of an action, keyed by the
+// rendered request body.
+type HitCachePolicy struct {
+	TTL                  time.Duration
+	StaleWhileRevalidate time.Duration // served while a single refresh runs in the background
+	MaxEntries           int           // least recently used entries are evicted first
+	RespectCacheControl  bool          // upstream max-age, stale-while-revalidate and no-store override the policy
+}
+
+// ParseHitAction reads the action described by config.
+func ParseHitAction(config map[string]interface{}) (*HitAction, error) {
+	action := &HitAction{
+		Balancer:         BalancerRoundRobin,
+		Ejection:         EjectionPolicy{ConsecutiveFailures: defaultEjectionFailures, Cooldown: defaultEjectionCooldown},
+		Method:           http.MethodGet,
+		Timeout:          defaultHitTimeout,
+		MaxResponseBytes: defaultMaxHitResponseBytes,
+	}
+
+	query, err := stringMap(config, "query")
+	if err != nil {
+		return nil, err
+	}
+	rawURL, hasURL := config["url"]
+	rawUpstreams, hasUpstreams := config["upstreams"]
+	switch {
+	case hasURL && hasUpstreams:
+		return nil, errors.New("url and upstreams cannot both be set")
+	case hasUpstreams:
+		if action.Upstreams, err = parseUpstreams(rawUpstreams, query); err != nil {
+			return nil, err
+		}
+	case hasURL:
+		urlStr, ok := rawURL.(string)
+		if !ok {
+			return nil, errors.New("configured URL is not a string")
+		}
+		if action.URL, err = withQuery(urlStr, query); err != nil {
+			return nil, err
+		}
+		action.Upstreams = []HitUpstream{{URL: action.URL, Weight: 1}}
+	default:
+		return nil, errURLNotConfigured
+	}
+
+	if v, ok := config["balancer"]; ok {
+		balancer, _ := v.(string)
+		switch balancer {
+		case BalancerRoundRobin, BalancerWeightedRandom, BalancerLeastOutstanding:
+			action.Balancer = balancer
+		default:
+			return nil, fmt.Errorf("unknown balancer %v", v)
+		}
+	}
+
+	if v, ok := config["ejection"]; ok {
+		ejectionConfig, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("ejection must be an object")
+		}
+		if v, ok := ejectionConfig["consecutive_failures"]; ok {
+			n, ok := intValue(v)
+			if !ok || n < 1 {
+				return nil, errors.New("ejection.consecutive_failures must be a positive integer")
+			}
+			action.Ejection.ConsecutiveFailures = int(n)
+		}
+		if v, ok := ejectionConfig["cooldown"]; ok {
+			if action.Ejection.Cooldown, err = durationValue("ejection.cooldown", v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if v, ok := config["method"]; ok {
+		method, ok := v.(string)
+		if !ok || method == "" {
+			return nil, errors.New("method must be a non-empty string")
+		}
+		action.Method = strings.ToUpper(method)
+	}
+
+	if action.Headers, err = stringMap(config, "headers"); err != nil {
+		return nil, err
+	}
+
+	if v, ok := config["body"]; ok {
+		body, ok := v.(string)
+		if !ok {
+			return nil, errors.New("body must be a string")
+		}
+		if action.Body, err = template.New("body").Option("missingkey=zero").Parse(body); err != nil {
+			return nil, fmt.Errorf("invalid body template: %w", err)
+		}
+	}
+
+	if v, ok := config["timeout"]; ok {
+		if action.Timeout, err = durationValue("timeout", v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := config["attempt_timeout"]; ok {
+		if action.AttemptTimeout, err = durationValue("attempt_timeout", v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := config["expected_status"]; ok {
+		if action.ExpectedStatus, err = statusList("expected_status", v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := config["max_response_bytes"]; ok {
+		limit, ok := intValue(v)
+		if !ok || limit <= 0 {
+			return nil, errors.New("max_response_bytes must be a positive integer")
+		}
+		action.MaxResponseBytes = limit
+	}
+
+	if v, ok := config["response"]; ok {
+		switch v {
+		case HitResponseJSON:
+		case HitResponsePassthrough:
+			action.Passthrough = true
+		default:
+			return nil, fmt.Errorf(`response must be "json" or "passthrough", got %v`, v)
+		}
+	}
+
+	if v, ok := config["cache"]; ok {
+		if action.Passthrough {
+			return nil, errors.New("cache cannot be used with passthrough responses")
+		}
+		cacheConfig, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("cache must be an object")
+		}
+		if action.Cache, err = parseHitCachePolicy(cacheConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := config["retry"]; ok {
+		retryConfig, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("retry must be an object")
+		}
+		if action.Retry, err = parseHitRetryPolicy(retryConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := config["circuit_breaker"]; ok {
+		breakerConfig, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("circuit_breaker must be an object")
+		}
+		if action.CircuitBreaker, err = parseCircuitBreakerPolicy(breakerConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	return action, nil
+}
+
+func parseUpstreams(v interface{}, query map[string]string) ([]HitUpstream, error) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, errors.New("upstreams must be a non-empty list")
+	}
+	upstreams := make([]HitUpstream, 0, len(list))
+	for i, item := range list {
+		upstreamConfig, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("upstreams[%d] must be an object", i)
+		}
+		urlStr, ok := upstreamConfig["url"].(string)
+		if !ok {
+			return nil, fmt.Errorf("upstreams[%d].url must be a string", i)
+		}
+		upstreamURL, err := withQuery(urlStr, query)
+		if err != nil {
+			return nil, err
+		}
+		upstream := HitUpstream{URL: upstreamURL, Weight: 1}
+		if v, ok := upstreamConfig["weight"]; ok {
+			weight, ok := intValue(v)
+			if !ok || weight < 1 {
+				return nil, fmt.Errorf("upstreams[%d].weight must be a positive integer", i)
+			}
+			upstream.Weight = int(weight)
+		}
+		upstreams = append(upstreams, upstream)
+	}
+	return upstreams, nil
+}
+
+// withQuery returns rawURL with the query parameters added to its own.
+func withQuery(rawURL string, query map[string]string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if len(query) > 0 {
+		values := u.Query()
+		for k, v := range query {
+			values.Set(k, v)
+		}
+		u.RawQuery = values.Encode()
+	}
+	return u.String(), nil
+}
+
+func parseHitRetryPolicy(config map[string]interface{}) (*HitRetryPolicy, error) {
+	policy := &HitRetryPolicy{
+		Backoff:    defaultHitRetryBackoff,
+		MaxBackoff: defaultHitRetryMaxBackoff,
+		OnStatus:   defaultRetryStatus,
+		OnError:    true,
+	}
+	var err error
+
+	n, ok := intValue(config["attempts"])
+	if !ok || n < 1 {
+		return nil, errors.New("retry.attempts must be a positive integer")
+	}
+	policy.Attempts = int(n)
+	if v, ok := config["backoff"]; ok {
+		if policy.Backoff, err = durationValue("retry.backoff", v); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := config["max_backoff"]; ok {
+		if policy.MaxBackoff, err = durationValue("retry.max_backoff", v); err != nil {
+			return nil, err
+		}
+	}
+	if policy.MaxBackoff < policy.Backoff {
+		policy.MaxBackoff = policy.Backoff
+	}
+	if v, ok := config["on_status"]; ok {
+		if policy.OnStatus, err = statusList("retry.on_status", v); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := config["on_error"]; ok {
+		if policy.OnError, ok = v.(bool); !ok {
+			return nil, errors.New("retry.on_error must be a boolean")
+		}
+	}
+	return policy, nil
+}
+
+func parseCircuitBreakerPolicy(config map[string]interface{}) (*CircuitBreakerPolicy, error) {
+	policy := &CircuitBreakerPolicy{HalfOpenProbes: defaultHalfOpenProbes}
+	var err error
+
+	n, ok := intValue(config["failure_threshold"])
+	if !ok || n < 1 {
+		return nil, errors.New("circuit_breaker.failure_threshold must be a positive integer")
+	}
+	policy.FailureThreshold = int(n)
+	v, ok := config["open_duration"]
+	if !ok {
+		return nil, errors.New("circuit_breaker.open_duration is required")
+	}
+	if policy.OpenDuration, err = durationValue("circuit_breaker.open_duration", v); err != nil {
+		return nil, err
+	}
+	if v, ok := config["half_open_probes"]; ok {
+		n, ok := intValue(v)
+		if !ok || n < 1 {
+			return nil, errors.New("circuit_breaker.half_open_probes must be a positive integer")
+		}
+		policy.HalfOpenProbes = int(n)
+	}
+	return policy, nil
+}
+
+func parseHitCachePolicy(config map[string]interface{}) (*HitCachePolicy, error) {
+	policy := &HitCachePolicy{MaxEntries: defaultHitCacheEntries}
+	var err error
+
+	v, ok := config["ttl"]
+	if !ok {
+		return nil, errors.New("cache.ttl is required")
+	}
+	if policy.TTL, err = durationValue("cache.ttl", v); err != nil {
+		return nil, err
+	}
+	if v, ok := config["stale_while_revalidate"]; ok {
+		if policy.StaleWhileRevalidate, err = durationValue("cache.stale_while_revalidate", v); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := config["max_entries"]; ok {
+		n, ok := intValue(v)
+		if !ok || n <= 0 {
+			return nil, errors.New("cache.max_entries must be a positive integer")
+		}
+		policy.MaxEntries = int(n)
+	}
+	if v, ok := config["respect_cache_control"]; ok {
+		if policy.RespectCacheControl, ok = v.(bool); !ok {
+			return nil, errors.New("cache.respect_cache_control must be a boolean")
+		}
+	}
+	return policy, nil
+}
+
+// parsedAction is an action of a config, or why it is unusable.
+type parsedAction struct {
+	action   *HitAction
+	balancer *loadBalancer
+	cache    *hitCache       // nil unless the action has a cache policy
+	breaker  *circuitBreaker // nil unless the action has a circuit breaker
+	err      error
+}
+
+// newParsedAction parses an action, its upstreams keeping the state they had
+// in previous, the action of the same name in the config being replaced. So
+// does its circuit breaker while its policy and upstreams are unchanged. An
+// action calling upstreams egress denies is unusable.
+func newParsedAction(config map[string]interface{}, previous parsedAction, egress *EgressPolicy) parsedAction {
+	action, err := ParseHitAction(config)
+	if err != nil {
+		return parsedAction{err: err}
+	}
+	if err := egress.CheckAction(action); err != nil {
+		return parsedAction{err: err}
+	}
+	parsed := parsedAction{action: action, balancer: newLoadBalancer(action, previous.balancer)}
+	if action.Cache != nil {
+		parsed.cache = newHitCache(*action.Cache, action.Caches)
+	}
+	if action.CircuitBreaker != nil {
+		if previous.breaker != nil && previous.breaker.policy == *action.CircuitBreaker && sameUpstreams(previous.action, action) {
+			parsed.breaker = previous.breaker
+		} else {
+			parsed.breaker = newCircuitBreaker(*action.CircuitBreaker)
+		}
+	}
+	return parsed
+}
+
+// sameUpstreams reports whether a and b call the same URLs, weights aside.
+func sameUpstreams(a, b *HitAction) bool {
+	return slices.EqualFunc(a.Upstreams, b.Upstreams, func(x, y HitUpstream) bool { return x.URL == y.URL })
+}
+
+// parseHitActions reads the default action from the top level of config and the
+// named ones from its "actions" object, keyed by name with the default under "".
+// Every invalid action keeps its own error so the others still run; the error
+// is only returned when "actions" itself is malformed. previous are the actions
+// being replaced.
+func parseHitActions(config map[string]interface{}, previous map[string]parsedAction, egress *EgressPolicy) (map[string]parsedAction, error) {
+	actions := map[string]parsedAction{"": newParsedAction(config, previous[""], egress)}
+
+	v, ok := config["actions"]
+	if !ok {
+		return actions, nil
+	}
+	named, ok := v.(map[string]interface{})
+	if !ok {
+		return actions, errors.New("actions must be an object of named actions")
+	}
+	for name, v := range named {
+		if !actionNamePattern.MatchString(name) {
+			actions[name] = parsedAction{err: fmt.Errorf("invalid action name %q", name)}
+			continue
+		}
+		actionConfig, ok := v.(map[string]interface{})
+		if !ok {
+			actions[name] = parsedAction{err: fmt.Errorf("action %s must be an object", name)}
+			continue
+		}
+		actions[name] = newParsedAction(actionConfig, previous[name], egress)
+	}
+	return actions, nil
+}
+
+// RenderBody executes the body template, it returns an empty body when the
+// action has none.
+func (a *HitAction) RenderBody(req dto.HitRequest, version string) (string, error) {
+	if a.Body == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	data := struct {
+		Query   map[string]string
+		Body    string
+		Version string
+	}{req.Query, req.Body, version}
+	if err := a.Body.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render body: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// NewRequest builds the request of an attempt on upstreamURL, with the body
+// returned by RenderBody.
+func (a *HitAction) NewRequest(ctx context.Context, upstreamURL, body string) (*http.Request, error) {
+	var bodyReader io.Reader
+	if a.Body != nil {
+		bodyReader = strings.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, a.Method, upstreamURL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range a.Headers {
+		if strings.EqualFold(k, "Host") {
+			httpReq.Host = v
+			continue
+		}
+		httpReq.Header.Set(k, v)
+	}
+	return httpReq, nil
+}
+
+// Expects reports whether the action accepts statusCode.
+func (a *HitAction) Expects(statusCode int) bool {
+	return len(a.ExpectedStatus) == 0 || slices.Contains(a.ExpectedStatus, statusCode)
+}
+
+// Caches reports whether a response with statusCode is successful enough to
+// cache: one of expected_status when set, any 2xx otherwise.
+func (a *HitAction) Caches(statusCode int) bool {
+	if len(a.ExpectedStatus) > 0 {
+		return a.Expects(statusCode)
+	}
+	return statusCode >= 200 && statusCode < 300
+}
+
+func statusList(field string, v interface{}) ([]int, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of status codes", field)
+	}
+	codes := make([]int, 0, len(list))
+	for _, item := range list {
+		code, ok := intValue(item)
+		if !ok || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid %s %v", field, item)
+		}
+		codes = append(codes, int(code))
+	}
+	return codes, nil
+}
+
+func stringMap(config map[string]interface{}, key string) (map[string]string, error) {
+	v, ok := config[key]
+	if !ok {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object of strings", key)
+	}
+	out := make(map[string]string, len(m))
+	for k, item := range m {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be a string", key, k)
+		}
+		out[k] = s
+	}
+	return out, nil
+}
+
+func durationValue(field string, v interface{}) (time.Duration, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf(`%s must be a duration string, e.g. "5s"`, field)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", field, s)
+	}
+	return d, nil
+}
+
+// numberValue accepts JSON numbers as well as Go ints.
+func numberValue(v interface{}) (float64, bool) {
+	if n, ok := v.(float64); ok {
+		return n, true
+	}
+	n, ok := intValue(v)
+	return float64(n), ok
+}
+
+// intValue accepts JSON numbers, which decode as float64, as well as Go ints.
+func intValue(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		if n != float64(int64(n)) {
+			return 0, false
+		}
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHitAction(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		action, err := ParseHitAction(map[string]interface{}{"url": "http://upstream/items"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodGet, action.Method)
+		assert.Equal(t, defaultHitTimeout, action.Timeout)
+		assert.Equal(t, int64(defaultMaxHitResponseBytes), action.MaxResponseBytes)
+		assert.Nil(t, action.Body)
+		assert.True(t, action.Expects(http.StatusTeapot))
+	})
+
+	t.Run("Full Action", func(t *testing.T) {
+		action, err := ParseHitAction(map[string]interface{}{
+			"url":                "http://upstream/items?sort=asc",
+			"method":             "post",
+			"headers":            map[string]interface{}{"Authorization": "Bearer token", "Host": "api.internal"},
+			"query":              map[string]interface{}{"limit": "10"},
+			"body":               `{"name":"{{.Query.name}}","version":"{{.Version}}"}`,
+			"timeout":            "2s",
+			"expected_status":    []interface{}{float64(200), float64(201)},
+			"max_response_bytes": float64(1024),
+			"cache":              map[string]interface{}{"ttl": "30s", "stale_while_revalidate": "10s", "respect_cache_control": true},
+			"attempt_timeout":    "500ms",
+			"retry":              map[string]interface{}{"attempts": float64(3), "max_backoff": "1s", "on_error": false},
+			"circuit_breaker":    map[string]interface{}{"failure_threshold": float64(5), "open_duration": "30s"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodPost, action.Method)
+		assert.Equal(t, "http://upstream/items?limit=10&sort=asc", action.URL)
+		assert.Equal(t, 2*time.Second, action.Timeout)
+		assert.Equal(t, []int{200, 201}, action.ExpectedStatus)
+		assert.Equal(t, int64(1024), action.MaxResponseBytes)
+		assert.False(t, action.Expects(http.StatusNoContent))
+		assert.Equal(t, &HitCachePolicy{
+			TTL:                  30 * time.Second,
+			StaleWhileRevalidate: 10 * time.Second,
+			MaxEntries:           defaultHitCacheEntries,
+			RespectCacheControl:  true,
+		}, action.Cache)
+		assert.Equal(t, 500*time.Millisecond, action.AttemptTimeout)
+		assert.Equal(t, &HitRetryPolicy{
+			Attempts:   3,
+			Backoff:    defaultHitRetryBackoff,
+			MaxBackoff: time.Second,
+			OnStatus:   defaultRetryStatus,
+		}, action.Retry)
+		assert.Equal(t, &CircuitBreakerPolicy{FailureThreshold: 5, OpenDuration: 30 * time.Second, HalfOpenProbes: 1}, action.CircuitBreaker)
+
+		body, err := action.RenderBody(dto.HitRequest{Query: map[string]string{"name": "widget"}}, "v3")
+		assert.NoError(t, err)
+		req, err := action.NewRequest(t.Context(), action.URL, body)
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		assert.Equal(t, "api.internal", req.Host)
+		sent, _ := io.ReadAll(req.Body)
+		assert.Equal(t, `{"name":"widget","version":"v3"}`, string(sent))
+	})
+
+	t.Run("Upstreams", func(t *testing.T) {
+		action, err := ParseHitAction(map[string]interface{}{
+			"upstreams": []interface{}{
+				map[string]interface{}{"url": "http://a/items", "weight": float64(3)},
+				map[string]interface{}{"url": "http://b/items"},
+			},
+			"query":    map[string]interface{}{"limit": "10"},
+			"balancer": "least_outstanding",
+			"ejection": map[string]interface{}{"consecutive_failures": float64(2), "cooldown": "1m"},
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, action.URL)
+		assert.Equal(t, []HitUpstream{{URL: "http://a/items?limit=10", Weight: 3}, {URL: "http://b/items?limit=10", Weight: 1}}, action.Upstreams)
+		assert.Equal(t, BalancerLeastOutstanding, action.Balancer)
+		assert.Equal(t, EjectionPolicy{ConsecutiveFailures: 2, Cooldown: time.Minute}, action.Ejection)
+	})
+
+	invalid := []struct {
+		name   string
+		config map[string]interface{}
+		err    string
+	}{
+		{"Missing URL", map[string]interface{}{}, "URL not configured"},
+		{"Method Type", map[string]interface{}{"url": "http://u", "method": 1}, "method must be a non-empty string"},
+		{"Header Value", map[string]interface{}{"url": "http://u", "headers": map[string]interface{}{"X-Retry": 3}}, "headers.X-Retry must be a string"},
+		{"Query Type", map[string]interface{}{"url": "http://u", "query": "a=b"}, "query must be an object of strings"},
+		{"Body Template", map[string]interface{}{"url": "http://u", "body": "{{.Query"}, "invalid body template"},
+		{"Timeout", map[string]interface{}{"url": "http://u", "timeout": "soon"}, `invalid timeout "soon"`},
+		{"Expected Status", map[string]interface{}{"url": "http://u", "expected_status": []interface{}{float64(42)}}, "invalid expected_status 42"},
+		{"Cache TTL", map[string]interface{}{"url": "http://u", "cache": map[string]interface{}{}}, "cache.ttl is required"},
+		{"Cache Entries", map[string]interface{}{"url": "http://u", "cache": map[string]interface{}{"ttl": "1m", "max_entries": float64(0)}}, "cache.max_entries must be a positive integer"},
+		{"Retry Attempts", map[string]interface{}{"url": "http://u", "retry": map[string]interface{}{}}, "retry.attempts must be a positive integer"},
+		{"Retry Status", map[string]interface{}{"url": "http://u", "retry": map[string]interface{}{"attempts": float64(2), "on_status": []interface{}{"503"}}}, `invalid retry.on_status 503`},
+		{"Breaker Duration", map[string]interface{}{"url": "http://u", "circuit_breaker": map[string]interface{}{"failure_threshold": float64(5)}}, "circuit_breaker.open_duration is required"},
+		{"URL And Upstreams", map[string]interface{}{"url": "http://u", "upstreams": []interface{}{}}, "url and upstreams cannot both be set"},
+		{"Empty Upstreams", map[string]interface{}{"upstreams": []interface{}{}}, "upstreams must be a non-empty list"},
+		{"Upstream Weight", map[string]interface{}{"upstreams": []interface{}{map[string]interface{}{"url": "http://u", "weight": float64(0)}}}, "upstreams[0].weight must be a positive integer"},
+		{"Balancer", map[string]interface{}{"url": "http://u", "balancer": "random"}, "unknown balancer random"},
+		{"Response Mode", map[string]interface{}{"url": "http://u", "response": "raw"}, `response must be "json" or "passthrough", got raw`},
+		{"Cached Passthrough", map[string]interface{}{"url": "http://u", "response": "passthrough", "cache": map[string]interface{}{"ttl": "1m"}}, "cache cannot be used with passthrough responses"},
+		{"Size Limit", map[string]interface{}{"url": "http://u", "max_response_bytes": float64(-1)}, "max_response_bytes must be a positive integer"},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseHitAction(tc.config)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"container/list"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache statuses reported in dto.HitResult.Cache.
+const (
+	CacheHit   = "HIT"
+	CacheMiss  = "MISS"
+	CacheStale = "STALE"
+)
+
+type hitCacheEntry struct {
+	key        string
+	result     *dto.HitResult
+	freshUntil time.Time
+	staleUntil time.Time // served while revalidating until then
+}
+
+// hitCache is the response cache of a single action. Concurrent misses and
+// background revalidations of the same key share one upstream call. A cache
+// lives as long as the config that defines its action, so applying a new
+// config starts with empty caches.
+type hitCache struct {
+	policy    HitCachePolicy
+	cacheable func(statusCode int) bool
+	now       func() time.Time
+	group     singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // of *hitCacheEntry, most recently used first
+}
+
+// newHitCache returns an empty cache storing the responses cacheable accepts.
+func newHitCache(policy HitCachePolicy, cacheable func(statusCode int) bool) *hitCache {
+	return &hitCache{
+		policy:    policy,
+		cacheable: cacheable,
+		now:       time.Now,
+		entries:   make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// Get returns the cached response for key, calling fetch when there is none.
+// A stale response is returned as is while fetch refreshes it in the background.
+func (c *hitCache) Get(ctx context.Context, key string, fetch func(context.Context) (*dto.HitResult, error)) (*dto.HitResult, error) {
+	if entry := c.lookup(key); entry != nil {
+		if c.now().Before(entry.freshUntil) {
+			return withCacheStatus(entry.result, CacheHit), nil
+		}
+		c.group.DoChan(key, c.fill(context.WithoutCancel(ctx), key, fetch))
+		return withCacheStatus(entry.result, CacheStale), nil
+	}
+
+	// Waiters give up with their own context, the upstream call carries on for the others
+	ch := c.group.DoChan(key, c.fill(context.WithoutCancel(ctx), key, fetch))
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return withCacheStatus(res.Val.(*dto.HitResult), CacheMiss), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *hitCache) fill(ctx context.Context, key string, fetch func(context.Context) (*dto.HitResult, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		result, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.store(key, result)
+		return result, nil
+	}
+}
+
+// lookup returns the entry for key unless it is past its stale window.
+func (c *hitCache) lookup(key string) *hitCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*hitCacheEntry)
+	if !c.now().Before(entry.staleUntil) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return entry
+}
+
+func (c *hitCache) store(key string, result *dto.HitResult) {
+	if !c.cacheable(result.StatusCode) {
+		return
+	}
+	ttl, swr := c.policy.TTL, c.policy.StaleWhileRevalidate
+	if c.policy.RespectCacheControl {
+		var cacheable bool
+		if ttl, swr, cacheable = cacheControl(result.Headers, ttl, swr); !cacheable {
+			return
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	now := c.now()
+	entry := &hitCacheEntry{key: key, result: result, freshUntil: now.Add(ttl), staleUntil: now.Add(ttl + swr)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.policy.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*hitCacheEntry).key)
+	}
+}
+
+// cacheControl applies the upstream Cache-Control header to the configured ttl
+// and stale window. no-store, no-cache and private responses are not cached.
+func cacheControl(header http.Header, ttl, swr time.Duration) (time.Duration, time.Duration, bool) {
+	sharedMaxAge := false
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache", "private":
+			return 0, 0, false
+		case "s-maxage":
+			if err == nil {
+				ttl, sharedMaxAge = time.Duration(seconds)*time.Second, true
+			}
+		case "max-age":
+			if err == nil && !sharedMaxAge {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		case "stale-while-revalidate":
+			if err == nil {
+				swr = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return ttl, swr, true
+}
+
+// withCacheStatus returns a copy of result, cached results are shared.
+func withCacheStatus(result *dto.HitResult, status string) *dto.HitResult {
+	res := *result
+	res.Cache = status
+	return &res
+}
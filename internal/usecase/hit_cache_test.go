@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHitCache(policy HitCachePolicy) (*hitCache, *fakeClock) {
+	clock := &fakeClock{now: time.Now()}
+	c := newHitCache(policy, (&HitAction{}).Caches)
+	c.now = clock.Now
+	return c, clock
+}
+
+// countingFetch returns a new body on every call so tests can tell cached responses apart.
+func countingFetch(header http.Header) (func(context.Context) (*dto.HitResult, error), *atomic.Int32) {
+	var calls atomic.Int32
+	return func(context.Context) (*dto.HitResult, error) {
+		n := calls.Add(1)
+		return &dto.HitResult{StatusCode: http.StatusOK, Headers: header, Body: fmt.Sprintf("response %d", n)}, nil
+	}, &calls
+}
+
+func TestHitCache_TTL(t *testing.T) {
+	c, clock := newTestHitCache(HitCachePolicy{TTL: time.Minute, MaxEntries: 10})
+	fetch, calls := countingFetch(nil)
+
+	res, err := c.Get(t.Context(), "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, CacheMiss, res.Cache)
+
+	res, err = c.Get(t.Context(), "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, CacheHit, res.Cache)
+	assert.Equal(t, "response 1", res.Body)
+
+	clock.Advance(time.Minute)
+	res, err = c.Get(t.Context(), "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, CacheMiss, res.Cache)
+	assert.Equal(t, "response 2", res.Body)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestHitCache_StaleWhileRevalidate(t *testing.T) {
+	c, clock := newTestHitCache(HitCachePolicy{TTL: time.Minute, StaleWhileRevalidate: time.Minute, MaxEntries: 10})
+	fetch, calls := countingFetch(nil)
+
+	_, err := c.Get(t.Context(), "k", fetch)
+	assert.NoError(t, err)
+
+	clock.Advance(90 * time.Second)
+	res, err := c.Get(t.Context(), "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, CacheStale, res.Cache)
+	assert.Equal(t, "response 1", res.Body)
+
+	// The refresh runs in the background and replaces the stale entry
+	assert.Eventually(t, func() bool {
+		res, _ := c.Get(t.Context(), "k", fetch)
+		return res.Cache == CacheHit && res.Body == "response 2"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestHitCache_Eviction(t *testing.T) {
+	c, _ := newTestHitCache(HitCachePolicy{TTL: time.Minute, MaxEntries: 2})
+	fetch, calls := countingFetch(nil)
+
+	for _, key := range []string{"a", "b", "a", "c"} {
+		_, err := c.Get(t.Context(), key, fetch)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(3), calls.Load())
+
+	// b was the least recently used
+	res, _ := c.Get(t.Context(), "a", fetch)
+	assert.Equal(t, CacheHit, res.Cache)
+	res, _ = c.Get(t.Context(), "b", fetch)
+	assert.Equal(t, CacheMiss, res.Cache)
+}
+
+func TestHitCache_SingleFlight(t *testing.T) {
+	c, _ := newTestHitCache(HitCachePolicy{TTL: time.Minute, MaxEntries: 10})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	fetch := func(context.Context) (*dto.HitResult, error) {
+		calls.Add(1)
+		<-release
+		return &dto.HitResult{StatusCode: http.StatusOK}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Get(t.Context(), "k", fetch)
+			assert.NoError(t, err)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestHitCache_Errors(t *testing.T) {
+	c, _ := newTestHitCache(HitCachePolicy{TTL: time.Minute, MaxEntries: 10})
+	var calls atomic.Int32
+	fetch := func(context.Context) (*dto.HitResult, error) {
+		calls.Add(1)
+		return nil, errors.New("connection refused")
+	}
+
+	for range 2 {
+		_, err := c.Get(t.Context(), "k", fetch)
+		assert.EqualError(t, err, "connection refused")
+	}
+	assert.Equal(t, int32(2), calls.Load(), "errors are not cached")
+}
+
+func TestHitCache_UnsuccessfulResponses(t *testing.T) {
+	c, _ := newTestHitCache(HitCachePolicy{TTL: time.Minute, StaleWhileRevalidate: time.Minute, MaxEntries: 10})
+	var calls atomic.Int32
+	fetch := func(context.Context) (*dto.HitResult, error) {
+		calls.Add(1)
+		return &dto.HitResult{StatusCode: http.StatusServiceUnavailable, Body: "down"}, nil
+	}
+
+	for range 2 {
+		res, err := c.Get(t.Context(), "k", fetch)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.Equal(t, CacheMiss, res.Cache)
+	}
+	assert.Equal(t, int32(2), calls.Load(), "a 503 is not cached")
+}
+
+func TestHitAction_Caches(t *testing.T) {
+	action := &HitAction{}
+	assert.True(t, action.Caches(http.StatusOK))
+	assert.True(t, action.Caches(http.StatusNoContent))
+	assert.False(t, action.Caches(http.StatusNotFound))
+	assert.False(t, action.Caches(http.StatusServiceUnavailable))
+
+	action.ExpectedStatus = []int{http.StatusOK, http.StatusNotFound}
+	assert.True(t, action.Caches(http.StatusNotFound), "an expected 404 is a result worth caching")
+	assert.False(t, action.Caches(http.StatusCreated))
+}
+
+func TestHitCache_CacheControl(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		ttl, swr     time.Duration
+		cacheable    bool
+	}{
+		{"Absent", "", time.Minute, 0, true},
+		{"Max Age", "public, max-age=5", 5 * time.Second, 0, true},
+		{"Shared Max Age Wins", "s-maxage=30, max-age=5", 30 * time.Second, 0, true},
+		{"Stale While Revalidate", "max-age=5, stale-while-revalidate=20", 5 * time.Second, 20 * time.Second, true},
+		{"No Store", "no-store", 0, 0, false},
+		{"Private", "private, max-age=60", 0, 0, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if tc.cacheControl != "" {
+				header.Set("Cache-Control", tc.cacheControl)
+			}
+			ttl, swr, cacheable := cacheControl(header, time.Minute, 0)
+			assert.Equal(t, tc.cacheable, cacheable)
+			if tc.cacheable {
+				assert.Equal(t, tc.ttl, ttl)
+				assert.Equal(t, tc.swr, swr)
+			}
+		})
+	}
+
+	t.Run("Ignored Unless Configured", func(t *testing.T) {
+		c, _ := newTestHitCache(HitCachePolicy{TTL: time.Minute, MaxEntries: 10})
+		fetch, _ := countingFetch(http.Header{"Cache-Control": {"no-store"}})
+		c.Get(t.Context(), "k", fetch)
+		res, _ := c.Get(t.Context(), "k", fetch)
+		assert.Equal(t, CacheHit, res.Cache)
+
+		c, _ = newTestHitCache(HitCachePolicy{TTL: time.Minute, MaxEntries: 10, RespectCacheControl: true})
+		c.Get(t.Context(), "k", fetch)
+		res, _ = c.Get(t.Context(), "k", fetch)
+		assert.Equal(t, CacheMiss, res.Cache)
+	})
+}
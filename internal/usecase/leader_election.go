@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"config-manager/internal/repository"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LeaderElector tells a controller replica whether it may serve config writes.
+type LeaderElector interface {
+	IsLeader() bool
+	// LeaderURL returns the advertised URL of the current leader, or "" if unknown.
+	LeaderURL() string
+	Run(ctx context.Context)
+}
+
+type staticElector struct {
+	url string
+}
+
+// NewStaticElector returns an elector for single-replica deployments that is always the leader.
+func NewStaticElector(url string) LeaderElector {
+	return &staticElector{url: url}
+}
+
+func (e *staticElector) IsLeader() bool {
+	return true
+}
+
+func (e *staticElector) LeaderURL() string {
+	return e.url
+}
+
+func (e *staticElector) Run(ctx context.Context) {}
+
+// LeaseElectorConfig identifies a replica taking part in a lease election.
+type LeaseElectorConfig struct {
+	Name string        // lease name shared by all replicas
+	ID   string        // unique replica ID
+	URL  string        // URL other replicas forward writes to
+	TTL  time.Duration // how long a lease is valid without renewal
+}
+
+type leaseElector struct {
+	leaseRepo repository.LeaseRepository
+	cfg       LeaseElectorConfig
+	now       func() time.Time
+
+	mu          sync.RWMutex
+	leader      bool
+	leaseExpiry time.Time
+	leaderURL   string
+}
+
+// NewLeaseElector returns an elector backed by a database lease. The replica that
+// holds an unexpired lease is the leader and renews it every TTL/3.
+func NewLeaseElector(leaseRepo repository.LeaseRepository, cfg LeaseElectorConfig) LeaderElector {
+	return &leaseElector{
+		leaseRepo: leaseRepo,
+		cfg:       cfg,
+		now:       time.Now,
+	}
+}
+
+func (e *leaseElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	// Stop acting as leader once the lease may have been taken over, even if a
+	// renewal is late.
+	return e.leader && e.now().Before(e.leaseExpiry)
+}
+
+func (e *leaseElector) LeaderURL() string {
+	if e.IsLeader() {
+		return e.cfg.URL
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leaderURL
+}
+
+func (e *leaseElector) Run(ctx context.Context) {
+	interval := e.cfg.TTL / 3
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_ = e.step()
+
+		select {
+		case <-ctx.Done():
+			e.resign()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// step tries to acquire or renew the lease once and refreshes the known leader.
+func (e *leaseElector) step() error {
+	now := e.now()
+	acquired, err := e.leaseRepo.TryAcquire(e.cfg.Name, e.cfg.ID, e.cfg.URL, e.cfg.TTL, now)
+	if err != nil {
+		// Without a successful renewal we cannot prove we still hold the lease
+		e.setState(false, time.Time{}, "")
+		return err
+	}
+	if acquired {
+		e.setState(true, now.Add(e.cfg.TTL), e.cfg.URL)
+		return nil
+	}
+
+	lease, err := e.leaseRepo.Get(e.cfg.Name)
+	if err != nil {
+		e.setState(false, time.Time{}, "")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	leaderURL := ""
+	if lease.ExpiresAt.After(now) {
+		leaderURL = lease.HolderURL
+	}
+	e.setState(false, time.Time{}, leaderURL)
+	return nil
+}
+
+func (e *leaseElector) resign() {
+	e.mu.Lock()
+	wasLeader := e.leader
+	e.leader = false
+	e.leaderURL = ""
+	e.mu.Unlock()
+
+	if wasLeader {
+		_ = e.leaseRepo.Release(e.cfg.Name, e.cfg.ID)
+	}
+}
+
+func (e *leaseElector) setState(leader bool, expiry time.Time, leaderURL string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leader = leader
+	e.leaseExpiry = expiry
+	e.leaderURL = leaderURL
+}
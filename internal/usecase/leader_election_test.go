@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"config-manager/internal/migration"
+	"config-manager/internal/repository"
+	"config-manager/internal/repository/mocks"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestStaticElector(t *testing.T) {
+	e := NewStaticElector("http://controller")
+	assert.True(t, e.IsLeader())
+	assert.Equal(t, "http://controller", e.LeaderURL())
+	e.Run(context.Background())
+}
+
+// fakeClock is shared by every elector in a test so lease expiry is deterministic.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestLeaseElector_SeveralControllers(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	migrator, err := migration.NewMigrator(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	clock := &fakeClock{now: time.Now()}
+	ttl := 15 * time.Second
+	var electors []*leaseElector
+	for _, id := range []string{"a", "b", "c"} {
+		e := NewLeaseElector(repository.NewLeaseRepository(db), LeaseElectorConfig{
+			Name: "controller",
+			ID:   id,
+			URL:  "http://" + id,
+			TTL:  ttl,
+		}).(*leaseElector)
+		e.now = clock.Now
+		electors = append(electors, e)
+	}
+
+	stepAll := func() {
+		for _, e := range electors {
+			assert.NoError(t, e.step())
+		}
+	}
+	leaders := func() []*leaseElector {
+		var ls []*leaseElector
+		for _, e := range electors {
+			if e.IsLeader() {
+				ls = append(ls, e)
+			}
+		}
+		return ls
+	}
+
+	t.Run("Exactly One Leader", func(t *testing.T) {
+		stepAll()
+		assert.Len(t, leaders(), 1)
+		assert.Equal(t, "a", leaders()[0].cfg.ID)
+		for _, e := range electors {
+			assert.Equal(t, "http://a", e.LeaderURL())
+		}
+	})
+
+	t.Run("Leader Keeps Lease While Renewing", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			clock.Advance(ttl / 3)
+			stepAll()
+			assert.Len(t, leaders(), 1)
+			assert.Equal(t, "a", leaders()[0].cfg.ID)
+		}
+	})
+
+	t.Run("Failover When Leader Stops Renewing", func(t *testing.T) {
+		crashed := electors[0]
+		electors = electors[1:]
+
+		clock.Advance(ttl + time.Second)
+		assert.False(t, crashed.IsLeader(), "a stale leader must step down once its lease expires")
+
+		stepAll()
+		assert.Len(t, leaders(), 1)
+		assert.Equal(t, "b", leaders()[0].cfg.ID)
+		assert.Equal(t, "http://b", electors[1].LeaderURL())
+	})
+
+	t.Run("Resign Hands Over Immediately", func(t *testing.T) {
+		electors[0].resign()
+		assert.False(t, electors[0].IsLeader())
+
+		assert.NoError(t, electors[1].step())
+		assert.True(t, electors[1].IsLeader())
+	})
+}
+
+func TestLeaseElector_RepositoryErrors(t *testing.T) {
+	mockRepo := new(mocks.MockLeaseRepository)
+	e := NewLeaseElector(mockRepo, LeaseElectorConfig{Name: "controller", ID: "a", URL: "http://a", TTL: time.Second}).(*leaseElector)
+
+	anyTime := mock.AnythingOfType("time.Time")
+
+	t.Run("Acquire Error Steps Down", func(t *testing.T) {
+		e.setState(true, time.Now().Add(time.Hour), "http://a")
+		mockRepo.On("TryAcquire", "controller", "a", "http://a", time.Second, anyTime).Return(false, errors.New("db down")).Once()
+
+		assert.Error(t, e.step())
+		assert.False(t, e.IsLeader())
+		assert.Empty(t, e.LeaderURL())
+	})
+
+	t.Run("No Lease Yet", func(t *testing.T) {
+		mockRepo.On("TryAcquire", "controller", "a", "http://a", time.Second, anyTime).Return(false, nil).Once()
+		mockRepo.On("Get", "controller").Return(nil, gorm.ErrRecordNotFound).Once()
+
+		assert.NoError(t, e.step())
+		assert.False(t, e.IsLeader())
+	})
+
+	t.Run("Run Stops And Releases On Cancel", func(t *testing.T) {
+		mockRepo.On("TryAcquire", "controller", "a", "http://a", time.Second, anyTime).Return(true, nil)
+		mockRepo.On("Release", "controller", "a").Return(nil).Once()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		e.Run(ctx)
+
+		assert.False(t, e.IsLeader())
+		mockRepo.AssertExpectations(t)
+	})
+}
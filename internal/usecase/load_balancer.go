@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Strategies picking the upstream of an action for every attempt.
+const (
+	BalancerRoundRobin       = "round_robin" // smooth weighted round-robin
+	BalancerWeightedRandom   = "weighted_random"
+	BalancerLeastOutstanding = "least_outstanding" // fewest in-flight requests per unit of weight
+)
+
+// HitUpstream is one of the URLs an action spreads its requests over.
+type HitUpstream struct {
+	URL    string
+	Weight int
+}
+
+// EjectionPolicy takes an upstream out of rotation for Cooldown after
+// ConsecutiveFailures attempts that failed as defined by upstreamOutcome.
+type EjectionPolicy struct {
+	ConsecutiveFailures int
+	Cooldown            time.Duration
+}
+
+// upstreamState outlives a config: a new config listing the same URL keeps its
+// in-flight count and ejection, so requests started under the previous config
+// are still accounted for. It has its own lock as balancers of both configs use
+// it until those requests are done.
+type upstreamState struct {
+	mu           sync.Mutex
+	outstanding  int
+	failures     int
+	ejectedUntil time.Time
+}
+
+func (s *upstreamState) load() (outstanding int, ejectedUntil time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.outstanding, s.ejectedUntil
+}
+
+type loadBalancer struct {
+	strategy  string
+	ejection  EjectionPolicy
+	upstreams []HitUpstream
+	states    []*upstreamState
+	now       func() time.Time
+
+	mu      sync.Mutex
+	current []int // smooth weighted round-robin counters
+	next    int   // where least_outstanding starts looking, to spread ties
+}
+
+// newLoadBalancer returns the balancer of action, taking over the state of the
+// upstreams it shares with previous, which may be nil.
+func newLoadBalancer(action *HitAction, previous *loadBalancer) *loadBalancer {
+	b := &loadBalancer{
+		strategy:  action.Balancer,
+		ejection:  action.Ejection,
+		upstreams: action.Upstreams,
+		now:       time.Now,
+		states:    make([]*upstreamState, len(action.Upstreams)),
+		current:   make([]int, len(action.Upstreams)),
+	}
+
+	previousStates := make(map[string]*upstreamState)
+	if previous != nil {
+		for i, upstream := range previous.upstreams {
+			previousStates[upstream.URL] = previous.states[i]
+		}
+	}
+	for i, upstream := range action.Upstreams {
+		if state, ok := previousStates[upstream.URL]; ok {
+			b.states[i] = state
+		} else {
+			b.states[i] = &upstreamState{}
+		}
+	}
+	return b
+}
+
+// Pick returns the index of the upstream for the next attempt, which must be
+// reported with Done or Release. Ejected upstreams are skipped unless all of them are.
+func (b *loadBalancer) Pick() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	outstanding := make([]int, len(b.upstreams))
+	candidates := make([]int, 0, len(b.upstreams))
+	for i, state := range b.states {
+		var ejectedUntil time.Time
+		outstanding[i], ejectedUntil = state.load()
+		if !now.Before(ejectedUntil) {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		for i := range b.upstreams {
+			candidates = append(candidates, i)
+		}
+	}
+
+	var picked int
+	switch b.strategy {
+	case BalancerWeightedRandom:
+		picked = b.weightedRandom(candidates)
+	case BalancerLeastOutstanding:
+		picked = b.leastOutstanding(candidates, outstanding)
+	default:
+		picked = b.roundRobin(candidates)
+	}
+
+	state := b.states[picked]
+	state.mu.Lock()
+	state.outstanding++
+	state.mu.Unlock()
+	return picked
+}
+
+// Done reports the outcome of an attempt on upstream i.
+func (b *loadBalancer) Done(i int, failed bool) {
+	state := b.states[i]
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.outstanding--
+	if !failed {
+		state.failures = 0
+		return
+	}
+	state.failures++
+	if state.failures >= b.ejection.ConsecutiveFailures {
+		state.failures = 0
+		state.ejectedUntil = b.now().Add(b.ejection.Cooldown)
+	}
+}
+
+// Release ends an attempt on upstream i that says nothing about its health,
+// e.g. one cancelled by the caller.
+func (b *loadBalancer) Release(i int) {
+	state := b.states[i]
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.outstanding--
+}
+
+// Upstreams describes every upstream with its current load and health.
+func (b *loadBalancer) Upstreams() []dto.HitUpstreamInfo {
+	now := b.now()
+	infos := make([]dto.HitUpstreamInfo, len(b.upstreams))
+	for i, upstream := range b.upstreams {
+		outstanding, ejectedUntil := b.states[i].load()
+		infos[i] = dto.HitUpstreamInfo{
+			URL:         upstream.URL,
+			Weight:      upstream.Weight,
+			Outstanding: outstanding,
+			Ejected:     now.Before(ejectedUntil),
+		}
+	}
+	return infos
+}
+
+// roundRobin is nginx's smooth weighted round-robin: heavier upstreams are
+// picked more often without being picked in bursts.
+func (b *loadBalancer) roundRobin(candidates []int) int {
+	total, picked := 0, candidates[0]
+	for _, i := range candidates {
+		b.current[i] += b.upstreams[i].Weight
+		total += b.upstreams[i].Weight
+		if b.current[i] > b.current[picked] {
+			picked = i
+		}
+	}
+	b.current[picked] -= total
+	return picked
+}
+
+func (b *loadBalancer) weightedRandom(candidates []int) int {
+	total := 0
+	for _, i := range candidates {
+		total += b.upstreams[i].Weight
+	}
+	n := rand.N(total)
+	for _, i := range candidates {
+		if n < b.upstreams[i].Weight {
+			return i
+		}
+		n -= b.upstreams[i].Weight
+	}
+	return candidates[len(candidates)-1]
+}
+
+func (b *loadBalancer) leastOutstanding(candidates, outstanding []int) int {
+	b.next++
+	picked := -1
+	for k := range candidates {
+		i := candidates[(b.next+k)%len(candidates)]
+		// outstanding/weight compared without dividing
+		if picked < 0 || outstanding[i]*b.upstreams[picked].Weight < outstanding[picked]*b.upstreams[i].Weight {
+			picked = i
+		}
+	}
+	return picked
+}
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLoadBalancer(strategy string, previous *loadBalancer, upstreams ...HitUpstream) (*loadBalancer, *fakeClock) {
+	clock := &fakeClock{now: time.Now()}
+	b := newLoadBalancer(&HitAction{
+		Upstreams: upstreams,
+		Balancer:  strategy,
+		Ejection:  EjectionPolicy{ConsecutiveFailures: 2, Cooldown: time.Minute},
+	}, previous)
+	b.now = clock.Now
+	return b, clock
+}
+
+func TestLoadBalancer_RoundRobin(t *testing.T) {
+	b, _ := newTestLoadBalancer(BalancerRoundRobin, nil, HitUpstream{"http://a", 3}, HitUpstream{"http://b", 1})
+
+	var picks []int
+	for range 8 {
+		i := b.Pick()
+		b.Done(i, false)
+		picks = append(picks, i)
+	}
+	// Smooth: b is spread out instead of following three a's in a row
+	assert.Equal(t, []int{0, 0, 1, 0, 0, 0, 1, 0}, picks)
+}
+
+func TestLoadBalancer_WeightedRandom(t *testing.T) {
+	b, _ := newTestLoadBalancer(BalancerWeightedRandom, nil, HitUpstream{"http://a", 9}, HitUpstream{"http://b", 1})
+
+	counts := make([]int, 2)
+	for range 1000 {
+		i := b.Pick()
+		b.Done(i, false)
+		counts[i]++
+	}
+	assert.InDelta(t, 900, counts[0], 60)
+}
+
+func TestLoadBalancer_LeastOutstanding(t *testing.T) {
+	b, _ := newTestLoadBalancer(BalancerLeastOutstanding, nil, HitUpstream{"http://a", 2}, HitUpstream{"http://b", 1})
+
+	// a takes two in-flight requests for every one on b
+	picks := []int{b.Pick(), b.Pick(), b.Pick()}
+	assert.ElementsMatch(t, []int{0, 0, 1}, picks)
+
+	b.Done(0, false)
+	b.Done(0, false)
+	assert.Equal(t, 0, b.Pick())
+}
+
+func TestLoadBalancer_Ejection(t *testing.T) {
+	b, clock := newTestLoadBalancer(BalancerRoundRobin, nil, HitUpstream{"http://a", 1}, HitUpstream{"http://b", 1})
+
+	for range 2 {
+		b.Pick()
+		b.Done(0, true)
+	}
+	assert.True(t, b.Upstreams()[0].Ejected)
+	for range 4 {
+		i := b.Pick()
+		assert.Equal(t, 1, i)
+		b.Done(i, false)
+	}
+
+	t.Run("All Ejected Keeps Serving", func(t *testing.T) {
+		for range 2 {
+			b.Pick()
+			b.Done(1, true)
+		}
+		assert.True(t, b.Upstreams()[1].Ejected)
+		i := b.Pick()
+		b.Done(i, false)
+	})
+
+	t.Run("Back After Cooldown", func(t *testing.T) {
+		clock.Advance(time.Minute)
+		assert.False(t, b.Upstreams()[0].Ejected)
+
+		seen := map[int]bool{}
+		for range 4 {
+			i := b.Pick()
+			b.Done(i, false)
+			seen[i] = true
+		}
+		assert.Len(t, seen, 2)
+	})
+}
+
+func TestLoadBalancer_Release(t *testing.T) {
+	b, _ := newTestLoadBalancer(BalancerRoundRobin, nil, HitUpstream{"http://a", 1})
+
+	b.Done(b.Pick(), true)
+	b.Release(b.Pick())
+	assert.Zero(t, b.Upstreams()[0].Outstanding)
+	assert.False(t, b.Upstreams()[0].Ejected)
+
+	b.Done(b.Pick(), true)
+	assert.True(t, b.Upstreams()[0].Ejected, "a released attempt does not reset the failure count")
+}
+
+func TestLoadBalancer_Reconfigure(t *testing.T) {
+	old, _ := newTestLoadBalancer(BalancerLeastOutstanding, nil, HitUpstream{"http://a", 1}, HitUpstream{"http://b", 1})
+	inFlight := old.Pick()
+	kept := old.upstreams[inFlight].URL
+
+	b, _ := newTestLoadBalancer(BalancerLeastOutstanding, old, HitUpstream{kept, 1}, HitUpstream{"http://c", 1})
+	assert.Equal(t, 1, b.Upstreams()[0].Outstanding, "in-flight requests of kept upstreams are carried over")
+	assert.Equal(t, 1, b.Pick(), "the new upstream has less load")
+
+	// The request started under the old config completes against its state
+	old.Done(inFlight, false)
+	assert.Equal(t, 0, b.Upstreams()[0].Outstanding)
+}
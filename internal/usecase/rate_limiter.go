@@ -0,0 +1,253 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	ClientKeyIP           = "ip"
+	clientKeyHeaderPrefix = "header:"
+	defaultMaxClients     = 10000
+)
+
+// RateLimit is a token bucket refilled with Rate requests per second, holding
+// up to Burst of them.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitPolicy limits the requests to /hit, across all clients and per
+// client. Clients are told apart by IP or, with "header:<name>", by the value
+// of a request header, falling back to the IP when it is missing.
+type RateLimitPolicy struct {
+	Global     *RateLimit // nil is unlimited
+	PerClient  *RateLimit // nil is unlimited
+	ClientKey  string
+	MaxClients int // idle clients are forgotten beyond it
+}
+
+// RateLimitError is returned for a request over a limit.
+type RateLimitError struct {
+	RetryAfter time.Duration // until the request would have been allowed
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+// ParseRateLimitPolicy reads the "rate_limit" object of a worker config:
+//
+//	"rate_limit": {
+//	  "global": {"rate": 100, "burst": 200},
+//	  "per_client": {"rate": 5, "burst": 10},
+//	  "client_key": "header:X-API-Key",
+//	  "max_clients": 10000
+//	}
+//
+// burst defaults to the rate rounded up and client_key to "ip". It returns nil
+// when the config has no limits.
+func ParseRateLimitPolicy(config map[string]interface{}) (*RateLimitPolicy, error) {
+	v, ok := config["rate_limit"]
+	if !ok {
+		return nil, nil
+	}
+	limitConfig, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("rate_limit must be an object")
+	}
+
+	policy := &RateLimitPolicy{ClientKey: ClientKeyIP, MaxClients: defaultMaxClients}
+	var err error
+	if policy.Global, err = parseRateLimit(limitConfig, "global"); err != nil {
+		return nil, err
+	}
+	if policy.PerClient, err = parseRateLimit(limitConfig, "per_client"); err != nil {
+		return nil, err
+	}
+	if v, ok := limitConfig["client_key"]; ok {
+		key, _ := v.(string)
+		if key != ClientKeyIP && (!strings.HasPrefix(key, clientKeyHeaderPrefix) || key == clientKeyHeaderPrefix) {
+			return nil, fmt.Errorf(`rate_limit.client_key must be "ip" or "header:<name>", got %v`, v)
+		}
+		policy.ClientKey = key
+	}
+	if v, ok := limitConfig["max_clients"]; ok {
+		n, ok := intValue(v)
+		if !ok || n < 1 {
+			return nil, errors.New("rate_limit.max_clients must be a positive integer")
+		}
+		policy.MaxClients = int(n)
+	}
+	return policy, nil
+}
+
+func parseRateLimit(config map[string]interface{}, key string) (*RateLimit, error) {
+	v, ok := config[key]
+	if !ok {
+		return nil, nil
+	}
+	limitConfig, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("rate_limit.%s must be an object", key)
+	}
+	r, ok := numberValue(limitConfig["rate"])
+	if !ok || r <= 0 {
+		return nil, fmt.Errorf("rate_limit.%s.rate must be a positive number", key)
+	}
+	limit := &RateLimit{Rate: r, Burst: int(math.Ceil(r))}
+	if v, ok := limitConfig["burst"]; ok {
+		burst, ok := intValue(v)
+		if !ok || burst < 1 {
+			return nil, fmt.Errorf("rate_limit.%s.burst must be a positive integer", key)
+		}
+		limit.Burst = int(burst)
+	}
+	return limit, nil
+}
+
+// hitRateLimiter enforces the RateLimitPolicy of the running config. Buckets
+// survive config updates: a changed limit applies to the tokens left, and
+// per-client buckets are only dropped when clients are told apart differently.
+type hitRateLimiter struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	policy  *RateLimitPolicy // nil is unlimited
+	err     error            // set when the config's limits are invalid
+	global  *rate.Limiter
+	clients map[string]*rate.Limiter
+}
+
+func newHitRateLimiter() *hitRateLimiter {
+	return &hitRateLimiter{now: time.Now}
+}
+
+// Update switches to the limits of a new config, or to failing every request
+// with err when they are invalid.
+func (l *hitRateLimiter) Update(policy *RateLimitPolicy, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.err = err
+	if err != nil {
+		return
+	}
+	now := l.now()
+	if policy == nil || policy.Global == nil {
+		l.global = nil
+	} else {
+		l.global = updateLimiter(l.global, *policy.Global, now)
+	}
+
+	switch {
+	case policy == nil || policy.PerClient == nil:
+		l.clients = nil
+	case l.policy == nil || l.policy.ClientKey != policy.ClientKey || l.clients == nil:
+		l.clients = make(map[string]*rate.Limiter)
+	default:
+		for key, limiter := range l.clients {
+			l.clients[key] = updateLimiter(limiter, *policy.PerClient, now)
+		}
+	}
+	l.policy = policy
+}
+
+// updateLimiter returns limiter set to limit, keeping the tokens it has left,
+// or a full new one when limiter is nil.
+func updateLimiter(limiter *rate.Limiter, limit RateLimit, now time.Time) *rate.Limiter {
+	if limiter == nil {
+		return rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+	}
+	if limiter.Limit() != rate.Limit(limit.Rate) {
+		limiter.SetLimitAt(now, rate.Limit(limit.Rate))
+	}
+	if limiter.Burst() != limit.Burst {
+		limiter.SetBurstAt(now, limit.Burst)
+	}
+	return limiter
+}
+
+// Allow takes a token for req from every bucket it goes through, or none of
+// them when one is empty.
+func (l *hitRateLimiter) Allow(req dto.HitRequest) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return l.err
+	}
+	if l.policy == nil {
+		return nil
+	}
+
+	now := l.now()
+	var reservations []*rate.Reservation
+	if l.clients != nil {
+		reservations = append(reservations, l.client(l.clientKey(req), now).ReserveN(now, 1))
+	}
+	if l.global != nil {
+		reservations = append(reservations, l.global.ReserveN(now, 1))
+	}
+
+	var retryAfter time.Duration
+	for _, r := range reservations {
+		retryAfter = max(retryAfter, r.DelayFrom(now))
+	}
+	if retryAfter == 0 {
+		return nil
+	}
+	for _, r := range reservations {
+		r.CancelAt(now)
+	}
+	return &RateLimitError{RetryAfter: retryAfter}
+}
+
+func (l *hitRateLimiter) clientKey(req dto.HitRequest) string {
+	if name, ok := strings.CutPrefix(l.policy.ClientKey, clientKeyHeaderPrefix); ok {
+		if value := req.Headers.Get(name); value != "" {
+			return "header:" + value
+		}
+	}
+	return "ip:" + req.ClientIP
+}
+
+func (l *hitRateLimiter) client(key string, now time.Time) *rate.Limiter {
+	if limiter, ok := l.clients[key]; ok {
+		return limiter
+	}
+	if len(l.clients) >= l.policy.MaxClients {
+		l.forgetIdleClients(now)
+	}
+	limiter := rate.NewLimiter(rate.Limit(l.policy.PerClient.Rate), l.policy.PerClient.Burst)
+	l.clients[key] = limiter
+	return limiter
+}
+
+// forgetIdleClients drops the buckets that refilled completely, which behave
+// like new ones, or else the one closest to full.
+func (l *hitRateLimiter) forgetIdleClients(now time.Time) {
+	var fullest string
+	fullestTokens := math.Inf(-1)
+	for key, limiter := range l.clients {
+		tokens := limiter.TokensAt(now)
+		if tokens >= float64(limiter.Burst()) {
+			delete(l.clients, key)
+			continue
+		}
+		if tokens > fullestTokens {
+			fullest, fullestTokens = key, tokens
+		}
+	}
+	if len(l.clients) >= l.policy.MaxClients {
+		delete(l.clients, fullest)
+	}
+}
@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRateLimiter(t *testing.T, config map[string]interface{}) (*hitRateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Now()}
+	l := newHitRateLimiter()
+	l.now = clock.Now
+	l.Update(ParseRateLimitPolicy(map[string]interface{}{"rate_limit": config}))
+	assert.NoError(t, l.err)
+	return l, clock
+}
+
+func limit(rate, burst float64) map[string]interface{} {
+	return map[string]interface{}{"rate": rate, "burst": burst}
+}
+
+func fromIP(ip string) dto.HitRequest {
+	return dto.HitRequest{ClientIP: ip}
+}
+
+func TestHitRateLimiter_Global(t *testing.T) {
+	l, clock := newTestRateLimiter(t, map[string]interface{}{"global": limit(1, 2)})
+
+	assert.NoError(t, l.Allow(fromIP("10.0.0.1")))
+	assert.NoError(t, l.Allow(fromIP("10.0.0.2")))
+
+	err := l.Allow(fromIP("10.0.0.3"))
+	var limitErr *RateLimitError
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, time.Second, limitErr.RetryAfter)
+
+	clock.Advance(time.Second)
+	assert.NoError(t, l.Allow(fromIP("10.0.0.3")))
+}
+
+func TestHitRateLimiter_PerClient(t *testing.T) {
+	t.Run("By IP", func(t *testing.T) {
+		l, _ := newTestRateLimiter(t, map[string]interface{}{"per_client": limit(1, 1), "global": limit(10, 3)})
+
+		assert.NoError(t, l.Allow(fromIP("10.0.0.1")))
+		assert.Error(t, l.Allow(fromIP("10.0.0.1")))
+		assert.NoError(t, l.Allow(fromIP("10.0.0.2")))
+
+		// The rejected request did not use a global token
+		assert.NoError(t, l.Allow(fromIP("10.0.0.3")))
+		assert.Error(t, l.Allow(fromIP("10.0.0.4")))
+	})
+
+	t.Run("By Header", func(t *testing.T) {
+		l, _ := newTestRateLimiter(t, map[string]interface{}{"per_client": limit(1, 1), "client_key": "header:X-API-Key"})
+		withKey := func(key string) dto.HitRequest {
+			return dto.HitRequest{ClientIP: "10.0.0.1", Headers: http.Header{"X-Api-Key": {key}}}
+		}
+
+		assert.NoError(t, l.Allow(withKey("a")))
+		assert.Error(t, l.Allow(withKey("a")))
+		assert.NoError(t, l.Allow(withKey("b")), "clients behind the same IP are told apart")
+		assert.NoError(t, l.Allow(fromIP("10.0.0.1")), "requests without the header fall back to the IP")
+	})
+
+	t.Run("Forgets Idle Clients", func(t *testing.T) {
+		l, clock := newTestRateLimiter(t, map[string]interface{}{"per_client": limit(1, 1), "max_clients": float64(2)})
+
+		assert.NoError(t, l.Allow(fromIP("10.0.0.1")))
+		clock.Advance(time.Second)
+		assert.NoError(t, l.Allow(fromIP("10.0.0.2")))
+		assert.NoError(t, l.Allow(fromIP("10.0.0.3")))
+
+		assert.Len(t, l.clients, 2)
+		assert.NotContains(t, l.clients, "ip:10.0.0.1")
+	})
+}
+
+func TestHitRateLimiter_Update(t *testing.T) {
+	l, _ := newTestRateLimiter(t, map[string]interface{}{"global": limit(1, 2), "per_client": limit(1, 1)})
+	assert.NoError(t, l.Allow(fromIP("10.0.0.1")))
+
+	t.Run("Unaffected Buckets Keep Their Tokens", func(t *testing.T) {
+		l.Update(ParseRateLimitPolicy(map[string]interface{}{"rate_limit": map[string]interface{}{
+			"global": limit(1, 5), "per_client": limit(1, 1),
+		}}))
+
+		assert.Error(t, l.Allow(fromIP("10.0.0.1")), "the client bucket is still empty")
+		// The global bucket kept its last token instead of refilling to the new burst
+		assert.NoError(t, l.Allow(fromIP("10.0.0.2")))
+		assert.Error(t, l.Allow(fromIP("10.0.0.3")))
+	})
+
+	t.Run("Changing The Client Key Resets Clients", func(t *testing.T) {
+		l.Update(ParseRateLimitPolicy(map[string]interface{}{"rate_limit": map[string]interface{}{
+			"per_client": limit(1, 1), "client_key": "header:X-API-Key",
+		}}))
+		assert.NoError(t, l.Allow(fromIP("10.0.0.1")))
+	})
+
+	t.Run("Removing Limits", func(t *testing.T) {
+		l.Update(ParseRateLimitPolicy(map[string]interface{}{}))
+		for range 5 {
+			assert.NoError(t, l.Allow(fromIP("10.0.0.1")))
+		}
+	})
+
+	t.Run("Invalid Limits", func(t *testing.T) {
+		l.Update(ParseRateLimitPolicy(map[string]interface{}{"rate_limit": map[string]interface{}{"global": limit(0, 1)}}))
+		assert.EqualError(t, l.Allow(fromIP("10.0.0.1")), "rate_limit.global.rate must be a positive number")
+	})
+}
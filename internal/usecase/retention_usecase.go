@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"config-manager/internal/repository"
+	"time"
+)
+
+// RetentionPolicy decides which config versions survive compaction. A version is
+// kept when any rule matches; the latest, pinned and agent-applied versions are
+// always kept regardless of the policy.
+type RetentionPolicy struct {
+	KeepLast     int           // keep the newest N versions, 0 disables the rule
+	KeepFor      time.Duration // keep versions younger than this, 0 disables the rule
+	KeepTagged   bool          // keep every version that carries a tag
+	ActiveWithin time.Duration // only agents seen this recently keep their applied versions, 0 counts every agent
+}
+
+// Enabled reports whether the policy limits history at all.
+func (p RetentionPolicy) Enabled() bool {
+	return p.KeepLast > 0 || p.KeepFor > 0
+}
+
+type RetentionUsecase interface {
+	Compact() (int64, error)
+}
+
+type retentionUsecase struct {
+	configRepo repository.ConfigRepository
+	agentRepo  repository.AgentRepository
+	policy     RetentionPolicy
+}
+
+func NewRetentionUsecase(configRepo repository.ConfigRepository, agentRepo repository.AgentRepository, policy RetentionPolicy) RetentionUsecase {
+	return &retentionUsecase{
+		configRepo: configRepo,
+		agentRepo:  agentRepo,
+		policy:     policy,
+	}
+}
+
+// Compact deletes the config versions not protected by the policy and returns how many were removed.
+func (u *retentionUsecase) Compact() (int64, error) {
+	if !u.policy.Enabled() {
+		return 0, nil
+	}
+
+	versions, err := u.configRepo.ListVersions()
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, nil
+	}
+
+	var seenSince time.Time
+	if u.policy.ActiveWithin > 0 {
+		seenSince = time.Now().Add(-u.policy.ActiveWithin)
+	}
+	appliedVersions, err := u.agentRepo.ListAppliedVersions(seenSince)
+	if err != nil {
+		return 0, err
+	}
+	applied := make(map[string]struct{}, len(appliedVersions))
+	for _, v := range appliedVersions {
+		applied[v] = struct{}{}
+	}
+
+	now := time.Now()
+	var expired []uint
+	// versions are ordered newest first, so index 0 is the latest
+	for i, v := range versions {
+		if i == 0 || v.Pinned {
+			continue
+		}
+		if _, ok := applied[v.Version]; ok {
+			continue
+		}
+		if u.policy.KeepLast > 0 && i < u.policy.KeepLast {
+			continue
+		}
+		if u.policy.KeepFor > 0 && now.Sub(v.CreatedAt) < u.policy.KeepFor {
+			continue
+		}
+		if u.policy.KeepTagged && v.Tag != "" {
+			continue
+		}
+		expired = append(expired, v.ID)
+	}
+
+	return u.configRepo.DeleteByIDs(expired)
+}
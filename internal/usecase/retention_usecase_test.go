@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"config-manager/internal/domain"
+	"config-manager/internal/repository/mocks"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRetentionUsecase_Compact(t *testing.T) {
+	now := time.Now()
+	// newest first, as returned by ListVersions
+	versions := []domain.GlobalConfig{
+		{ID: 6, Version: "v6", CreatedAt: now},
+		{ID: 5, Version: "v5", CreatedAt: now.Add(-1 * time.Hour)},
+		{ID: 4, Version: "v4", CreatedAt: now.Add(-48 * time.Hour), Tag: "release-1"},
+		{ID: 3, Version: "v3", CreatedAt: now.Add(-72 * time.Hour), Pinned: true},
+		{ID: 2, Version: "v2", CreatedAt: now.Add(-96 * time.Hour)},
+		{ID: 1, Version: "v1", CreatedAt: now.Add(-120 * time.Hour)},
+	}
+
+	t.Run("Disabled Policy", func(t *testing.T) {
+		configRepo := new(mocks.MockConfigRepository)
+		agentRepo := new(mocks.MockAgentRepository)
+		uc := NewRetentionUsecase(configRepo, agentRepo, RetentionPolicy{})
+
+		removed, err := uc.Compact()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), removed)
+		configRepo.AssertNotCalled(t, "ListVersions")
+	})
+
+	t.Run("Keep Last Protects Latest Pinned And Applied", func(t *testing.T) {
+		configRepo := new(mocks.MockConfigRepository)
+		agentRepo := new(mocks.MockAgentRepository)
+		uc := NewRetentionUsecase(configRepo, agentRepo, RetentionPolicy{KeepLast: 1})
+
+		configRepo.On("ListVersions").Return(versions, nil).Once()
+		agentRepo.On("ListAppliedVersions", time.Time{}).Return([]string{"v1"}, nil).Once()
+		configRepo.On("DeleteByIDs", []uint{5, 4, 2}).Return(int64(3), nil).Once()
+
+		removed, err := uc.Compact()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), removed)
+		configRepo.AssertExpectations(t)
+		agentRepo.AssertExpectations(t)
+	})
+
+	t.Run("Keep For And Tagged", func(t *testing.T) {
+		configRepo := new(mocks.MockConfigRepository)
+		agentRepo := new(mocks.MockAgentRepository)
+		uc := NewRetentionUsecase(configRepo, agentRepo, RetentionPolicy{KeepFor: 24 * time.Hour, KeepTagged: true})
+
+		configRepo.On("ListVersions").Return(versions, nil).Once()
+		agentRepo.On("ListAppliedVersions", mock.Anything).Return([]string{}, nil).Once()
+		configRepo.On("DeleteByIDs", []uint{2, 1}).Return(int64(2), nil).Once()
+
+		removed, err := uc.Compact()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), removed)
+		configRepo.AssertExpectations(t)
+	})
+
+	t.Run("Only Recently Seen Agents Protect Versions", func(t *testing.T) {
+		configRepo := new(mocks.MockConfigRepository)
+		agentRepo := new(mocks.MockAgentRepository)
+		uc := NewRetentionUsecase(configRepo, agentRepo, RetentionPolicy{KeepLast: 1, ActiveWithin: 90 * time.Second})
+
+		configRepo.On("ListVersions").Return(versions, nil).Once()
+		agentRepo.On("ListAppliedVersions", mock.MatchedBy(func(seenSince time.Time) bool {
+			age := time.Since(seenSince)
+			return age >= 90*time.Second && age < 100*time.Second
+		})).Return([]string{"v2"}, nil).Once()
+		configRepo.On("DeleteByIDs", []uint{5, 4, 1}).Return(int64(3), nil).Once()
+
+		_, err := uc.Compact()
+		assert.NoError(t, err)
+		configRepo.AssertExpectations(t)
+		agentRepo.AssertExpectations(t)
+	})
+
+	t.Run("Empty History", func(t *testing.T) {
+		configRepo := new(mocks.MockConfigRepository)
+		agentRepo := new(mocks.MockAgentRepository)
+		uc := NewRetentionUsecase(configRepo, agentRepo, RetentionPolicy{KeepLast: 1})
+
+		configRepo.On("ListVersions").Return([]domain.GlobalConfig{}, nil).Once()
+
+		removed, err := uc.Compact()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), removed)
+		configRepo.AssertNotCalled(t, "DeleteByIDs", mock.Anything)
+	})
+
+	t.Run("List Error", func(t *testing.T) {
+		configRepo := new(mocks.MockConfigRepository)
+		agentRepo := new(mocks.MockAgentRepository)
+		uc := NewRetentionUsecase(configRepo, agentRepo, RetentionPolicy{KeepLast: 1})
+
+		configRepo.On("ListVersions").Return(nil, errors.New("db error")).Once()
+
+		_, err := uc.Compact()
+		assert.Error(t, err)
+	})
+
+	t.Run("Applied Versions Error", func(t *testing.T) {
+		configRepo := new(mocks.MockConfigRepository)
+		agentRepo := new(mocks.MockAgentRepository)
+		uc := NewRetentionUsecase(configRepo, agentRepo, RetentionPolicy{KeepLast: 1})
+
+		configRepo.On("ListVersions").Return(versions, nil).Once()
+		agentRepo.On("ListAppliedVersions", mock.Anything).Return(nil, errors.New("db error")).Once()
+
+		_, err := uc.Compact()
+		assert.Error(t, err)
+		configRepo.AssertNotCalled(t, "DeleteByIDs", mock.Anything)
+	})
+}
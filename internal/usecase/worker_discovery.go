@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WorkerDiscovery resolves the set of worker base URLs an agent manages.
+type WorkerDiscovery interface {
+	Workers(ctx context.Context) ([]string, error)
+}
+
+type staticDiscovery struct {
+	urls []string
+}
+
+// NewStaticDiscovery returns a fixed list of workers.
+func NewStaticDiscovery(urls []string) WorkerDiscovery {
+	return &staticDiscovery{urls: normalizeWorkerURLs(urls)}
+}
+
+func (d *staticDiscovery) Workers(ctx context.Context) ([]string, error) {
+	return d.urls, nil
+}
+
+// Resolver is the subset of *net.Resolver used by DNS discovery.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// DNSDiscoveryConfig describes how worker URLs are built from DNS records.
+type DNSDiscoveryConfig struct {
+	Name   string // host name for A/AAAA lookups, full SRV name (e.g. _http._tcp.worker) for SRV lookups
+	SRV    bool
+	Scheme string // default http
+	Port   int    // used for A/AAAA records, SRV records carry their own port
+}
+
+type dnsDiscovery struct {
+	resolver Resolver
+	cfg      DNSDiscoveryConfig
+}
+
+// NewDNSDiscovery resolves workers from A/AAAA or SRV records on every call.
+func NewDNSDiscovery(resolver Resolver, cfg DNSDiscoveryConfig) WorkerDiscovery {
+	if cfg.Scheme == "" {
+		cfg.Scheme = "http"
+	}
+	return &dnsDiscovery{resolver: resolver, cfg: cfg}
+}
+
+func (d *dnsDiscovery) Workers(ctx context.Context) ([]string, error) {
+	var urls []string
+	if d.cfg.SRV {
+		_, records, err := d.resolver.LookupSRV(ctx, "", "", d.cfg.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			host := strings.TrimSuffix(r.Target, ".")
+			urls = append(urls, d.cfg.Scheme+"://"+net.JoinHostPort(host, strconv.Itoa(int(r.Port))))
+		}
+	} else {
+		addrs, err := d.resolver.LookupHost(ctx, d.cfg.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			urls = append(urls, d.cfg.Scheme+"://"+net.JoinHostPort(addr, strconv.Itoa(d.cfg.Port)))
+		}
+	}
+	return normalizeWorkerURLs(urls), nil
+}
+
+type fileDiscovery struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	urls    []string
+}
+
+// NewFileDiscovery reads worker URLs from path, one per line, ignoring blank
+// lines and # comments. The file is re-read whenever it changes on disk.
+func NewFileDiscovery(path string) WorkerDiscovery {
+	return &fileDiscovery{path: path}
+}
+
+func (d *fileDiscovery) Workers(ctx context.Context) ([]string, error) {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.urls != nil && info.ModTime().Equal(d.modTime) && info.Size() == d.size {
+		return d.urls, nil
+	}
+
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line != "" {
+			urls = append(urls, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read worker targets file %s: %w", d.path, err)
+	}
+
+	d.urls = normalizeWorkerURLs(urls)
+	d.modTime = info.ModTime()
+	d.size = info.Size()
+	return d.urls, nil
+}
+
+// normalizeWorkerURLs trims, de-duplicates and sorts urls so a discovery result
+// can be compared with the previous one.
+func normalizeWorkerURLs(urls []string) []string {
+	seen := make(map[string]bool, len(urls))
+	res := make([]string, 0, len(urls))
+	for _, u := range urls {
+		u = strings.TrimRight(strings.TrimSpace(u), "/")
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		res = append(res, u)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResolver struct {
+	hosts []string
+	srv   []*net.SRV
+	err   error
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return r.hosts, r.err
+}
+
+func (r *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return name, r.srv, r.err
+}
+
+func TestStaticDiscovery(t *testing.T) {
+	urls, err := NewStaticDiscovery([]string{" http://worker-b:8082/", "http://worker-a:8082", "", "http://worker-b:8082"}).Workers(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://worker-a:8082", "http://worker-b:8082"}, urls)
+}
+
+func TestDNSDiscovery(t *testing.T) {
+	t.Run("A Records", func(t *testing.T) {
+		resolver := &fakeResolver{hosts: []string{"10.0.0.2", "10.0.0.1", "fd00::1"}}
+		d := NewDNSDiscovery(resolver, DNSDiscoveryConfig{Name: "worker", Port: 8082})
+
+		urls, err := d.Workers(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"http://10.0.0.1:8082", "http://10.0.0.2:8082", "http://[fd00::1]:8082"}, urls)
+	})
+
+	t.Run("SRV Records", func(t *testing.T) {
+		resolver := &fakeResolver{srv: []*net.SRV{
+			{Target: "worker-1.example.", Port: 9000},
+			{Target: "worker-0.example.", Port: 9001},
+		}}
+		d := NewDNSDiscovery(resolver, DNSDiscoveryConfig{Name: "_http._tcp.worker.example", SRV: true, Scheme: "https"})
+
+		urls, err := d.Workers(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"https://worker-0.example:9001", "https://worker-1.example:9000"}, urls)
+	})
+
+	t.Run("Lookup Error", func(t *testing.T) {
+		d := NewDNSDiscovery(&fakeResolver{err: errors.New("no such host")}, DNSDiscoveryConfig{Name: "worker"})
+
+		urls, err := d.Workers(context.Background())
+		assert.Error(t, err)
+		assert.Nil(t, urls)
+	})
+}
+
+func TestFileDiscovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workers.txt")
+
+	t.Run("Missing File", func(t *testing.T) {
+		_, err := NewFileDiscovery(path).Workers(context.Background())
+		assert.Error(t, err)
+	})
+
+	t.Run("Reloads On Change", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte("# workers\nhttp://worker-a:8082\n\nhttp://worker-b:8082 # canary\n"), 0o600))
+		d := NewFileDiscovery(path)
+
+		urls, err := d.Workers(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"http://worker-a:8082", "http://worker-b:8082"}, urls)
+
+		assert.NoError(t, os.WriteFile(path, []byte("http://worker-c:8082\n"), 0o600))
+		later := time.Now().Add(time.Second)
+		assert.NoError(t, os.Chtimes(path, later, later))
+
+		urls, err = d.Workers(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"http://worker-c:8082"}, urls)
+	})
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const maxPushDeadLetters = 20
+
+// WorkerPusher delivers configs to a worker in the background, retrying failed
+// pushes with exponential backoff until they succeed, a newer config replaces
+// them, or MaxAttempts is reached and the push is dead-lettered. Pushes the
+// worker rejects with ErrPushRejected are dead-lettered without retrying.
+type WorkerPusher interface {
+	// Submit schedules req, replacing any pending push of another version.
+	// Submitting the version already pending keeps its retry state.
+	Submit(req dto.ConfigRequest)
+	// Cancel drops the pending push and aborts an attempt in flight, e.g. when
+	// the worker is no longer managed or the agent shuts down.
+	Cancel()
+	Status() dto.PushStatus
+}
+
+// WorkerPusherConfig tunes retries; zero values fall back to the defaults.
+type WorkerPusherConfig struct {
+	InitialBackoff time.Duration // default 1s, doubled after every failure
+	MaxBackoff     time.Duration // default 1m
+	MaxAttempts    int           // default 10
+
+	OnSuccess    func(req dto.ConfigRequest)
+	OnDeadLetter func(entry dto.PushDeadLetter)
+}
+
+type workerPusher struct {
+	push func(ctx context.Context, req dto.ConfigRequest) error
+	cfg  WorkerPusherConfig
+	wake chan struct{}
+
+	mu          sync.Mutex
+	running     bool
+	sleeping    bool // backing off, Submit may wake the loop
+	pending     *dto.ConfigRequest
+	generation  uint64 // bumped whenever pending is replaced
+	attempts    int
+	lastError   string
+	nextRetryAt time.Time
+	deadLetters []dto.PushDeadLetter
+	cancelPush  context.CancelFunc // aborts the attempt in flight
+}
+
+// NewWorkerPusher returns a pusher calling push with a context that is cancelled
+// when the attempt is replaced or cancelled.
+func NewWorkerPusher(push func(ctx context.Context, req dto.ConfigRequest) error, cfg WorkerPusherConfig) WorkerPusher {
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Minute
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = cfg.InitialBackoff
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	return &workerPusher{
+		push: push,
+		cfg:  cfg,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+func (p *workerPusher) Submit(req dto.ConfigRequest) {
+	p.mu.Lock()
+	if p.pending != nil && p.pending.Version == req.Version {
+		p.mu.Unlock()
+		return
+	}
+	p.pending = &req
+	p.reset()
+	if !p.running {
+		p.running = true
+		go p.loop()
+	}
+	if p.sleeping {
+		// Cut short the backoff of the push being replaced
+		p.sleeping = false
+		p.wake <- struct{}{}
+	}
+	p.mu.Unlock()
+}
+
+func (p *workerPusher) Cancel() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending = nil
+	p.reset()
+	if p.sleeping {
+		p.sleeping = false
+		p.wake <- struct{}{}
+	}
+}
+
+// reset starts a new generation, aborting the attempt in flight. Callers must hold mu.
+func (p *workerPusher) reset() {
+	p.generation++
+	p.attempts = 0
+	p.lastError = ""
+	p.nextRetryAt = time.Time{}
+	if p.cancelPush != nil {
+		p.cancelPush()
+		p.cancelPush = nil
+	}
+}
+
+func (p *workerPusher) Status() dto.PushStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := dto.PushStatus{
+		Attempts:    p.attempts,
+		LastError:   p.lastError,
+		DeadLetters: append([]dto.PushDeadLetter{}, p.deadLetters...),
+	}
+	if p.pending != nil {
+		status.PendingVersion = p.pending.Version
+	}
+	if !p.nextRetryAt.IsZero() {
+		nextRetryAt := p.nextRetryAt
+		status.NextRetryAt = &nextRetryAt
+	}
+	return status
+}
+
+// loop drains pending pushes and exits once there is nothing left to deliver.
+func (p *workerPusher) loop() {
+	for {
+		p.mu.Lock()
+		if p.pending == nil {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		req, generation := *p.pending, p.generation
+		ctx, cancel := context.WithCancel(context.Background())
+		p.cancelPush = cancel
+		p.mu.Unlock()
+
+		err := p.push(ctx, req)
+		cancel()
+
+		p.mu.Lock()
+		if generation != p.generation {
+			// Replaced while pushing, the newer config wins
+			p.mu.Unlock()
+			continue
+		}
+		p.cancelPush = nil
+		if err == nil {
+			p.pending = nil
+			p.attempts = 0
+			p.lastError = ""
+			p.nextRetryAt = time.Time{}
+			p.mu.Unlock()
+			if p.cfg.OnSuccess != nil {
+				p.cfg.OnSuccess(req)
+			}
+			continue
+		}
+
+		p.attempts++
+		p.lastError = err.Error()
+		rejected := errors.Is(err, ErrPushRejected)
+		if p.attempts >= p.cfg.MaxAttempts || rejected {
+			entry := dto.PushDeadLetter{
+				Version:   req.Version,
+				Attempts:  p.attempts,
+				LastError: p.lastError,
+				Rejected:  rejected,
+				FailedAt:  time.Now().UTC(),
+			}
+			p.deadLetters = append(p.deadLetters, entry)
+			if len(p.deadLetters) > maxPushDeadLetters {
+				p.deadLetters = p.deadLetters[len(p.deadLetters)-maxPushDeadLetters:]
+			}
+			p.pending = nil
+			p.attempts = 0
+			p.nextRetryAt = time.Time{}
+			p.mu.Unlock()
+			if p.cfg.OnDeadLetter != nil {
+				p.cfg.OnDeadLetter(entry)
+			}
+			continue
+		}
+
+		delay := p.backoff(p.attempts)
+		p.nextRetryAt = time.Now().Add(delay)
+		p.sleeping = true
+		p.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-p.wake:
+			timer.Stop()
+		}
+
+		// A Submit racing the timer may have left a wake-up behind
+		p.mu.Lock()
+		p.sleeping = false
+		select {
+		case <-p.wake:
+		default:
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *workerPusher) backoff(attempts int) time.Duration {
+	return backoffDelay(p.cfg.InitialBackoff, p.cfg.MaxBackoff, attempts)
+}
+
+// backoffDelay doubles initial per failed attempt up to max and keeps between
+// 50% and 100% of it, so callers that failed together do not retry together.
+func backoffDelay(initial, max time.Duration, attempts int) time.Duration {
+	delay := initial
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
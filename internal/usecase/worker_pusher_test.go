@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWorker records pushes and fails the first failures of them.
+type fakeWorker struct {
+	mu       sync.Mutex
+	failures int
+	pushed   []string
+	block    chan struct{} // when set, pushes wait on it
+}
+
+func (w *fakeWorker) push(ctx context.Context, req dto.ConfigRequest) error {
+	if w.block != nil {
+		<-w.block
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pushed = append(w.pushed, req.Version)
+	if w.failures > 0 {
+		w.failures--
+		return errors.New("worker unavailable")
+	}
+	return nil
+}
+
+func (w *fakeWorker) calls() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string{}, w.pushed...)
+}
+
+func TestWorkerPusher(t *testing.T) {
+	fast := WorkerPusherConfig{InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, MaxAttempts: 3}
+
+	t.Run("Retries Until Success", func(t *testing.T) {
+		worker := &fakeWorker{failures: 2}
+		applied := make(chan string, 1)
+		cfg := fast
+		cfg.OnSuccess = func(req dto.ConfigRequest) { applied <- req.Version }
+		pusher := NewWorkerPusher(worker.push, cfg)
+
+		pusher.Submit(dto.ConfigRequest{Version: "v1"})
+
+		select {
+		case version := <-applied:
+			assert.Equal(t, "v1", version)
+		case <-time.After(time.Second):
+			t.Fatal("push did not succeed")
+		}
+		assert.Equal(t, []string{"v1", "v1", "v1"}, worker.calls())
+
+		status := pusher.Status()
+		assert.Empty(t, status.PendingVersion)
+		assert.Zero(t, status.Attempts)
+		assert.Empty(t, status.DeadLetters)
+	})
+
+	t.Run("Dead Letter After Max Attempts", func(t *testing.T) {
+		worker := &fakeWorker{failures: 100}
+		deadLetters := make(chan dto.PushDeadLetter, 1)
+		cfg := fast
+		cfg.OnSuccess = func(req dto.ConfigRequest) { t.Error("unexpected success") }
+		cfg.OnDeadLetter = func(entry dto.PushDeadLetter) { deadLetters <- entry }
+		pusher := NewWorkerPusher(worker.push, cfg)
+
+		pusher.Submit(dto.ConfigRequest{Version: "v1"})
+
+		select {
+		case entry := <-deadLetters:
+			assert.Equal(t, "v1", entry.Version)
+			assert.Equal(t, 3, entry.Attempts)
+			assert.Equal(t, "worker unavailable", entry.LastError)
+			assert.False(t, entry.Rejected)
+		case <-time.After(time.Second):
+			t.Fatal("push was not dead-lettered")
+		}
+		assert.Len(t, worker.calls(), 3)
+
+		status := pusher.Status()
+		assert.Empty(t, status.PendingVersion)
+		assert.Len(t, status.DeadLetters, 1)
+	})
+
+	t.Run("Rejected Push Is Not Retried", func(t *testing.T) {
+		var calls int
+		deadLetters := make(chan dto.PushDeadLetter, 1)
+		cfg := fast
+		cfg.OnDeadLetter = func(entry dto.PushDeadLetter) { deadLetters <- entry }
+		pusher := NewWorkerPusher(func(ctx context.Context, req dto.ConfigRequest) error {
+			calls++
+			return fmt.Errorf("%w, status: 422: egress denied", ErrPushRejected)
+		}, cfg)
+
+		pusher.Submit(dto.ConfigRequest{Version: "v1"})
+
+		select {
+		case entry := <-deadLetters:
+			assert.Equal(t, 1, entry.Attempts)
+			assert.Equal(t, "worker rejected config, status: 422: egress denied", entry.LastError)
+			assert.True(t, entry.Rejected)
+		case <-time.After(time.Second):
+			t.Fatal("push was not dead-lettered")
+		}
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Newer Version Replaces Pending", func(t *testing.T) {
+		worker := &fakeWorker{failures: 1}
+		applied := make(chan string, 2)
+		cfg := WorkerPusherConfig{InitialBackoff: time.Hour, MaxAttempts: 3}
+		cfg.OnSuccess = func(req dto.ConfigRequest) { applied <- req.Version }
+		pusher := NewWorkerPusher(worker.push, cfg)
+
+		pusher.Submit(dto.ConfigRequest{Version: "v1"})
+		assert.Eventually(t, func() bool {
+			return pusher.Status().Attempts == 1
+		}, time.Second, time.Millisecond)
+
+		// v1 is backing off for an hour, v2 must not wait for it
+		pusher.Submit(dto.ConfigRequest{Version: "v2"})
+
+		select {
+		case version := <-applied:
+			assert.Equal(t, "v2", version)
+		case <-time.After(time.Second):
+			t.Fatal("newer version was not pushed")
+		}
+		assert.Equal(t, []string{"v1", "v2"}, worker.calls())
+	})
+
+	t.Run("Same Version Keeps Retry State", func(t *testing.T) {
+		worker := &fakeWorker{failures: 1}
+		pusher := NewWorkerPusher(worker.push, WorkerPusherConfig{InitialBackoff: time.Hour, MaxAttempts: 3})
+
+		pusher.Submit(dto.ConfigRequest{Version: "v1"})
+		assert.Eventually(t, func() bool {
+			return pusher.Status().Attempts == 1
+		}, time.Second, time.Millisecond)
+
+		pusher.Submit(dto.ConfigRequest{Version: "v1"})
+
+		status := pusher.Status()
+		assert.Equal(t, "v1", status.PendingVersion)
+		assert.Equal(t, 1, status.Attempts)
+		assert.NotNil(t, status.NextRetryAt)
+		assert.Len(t, worker.calls(), 1)
+	})
+
+	t.Run("Replaced While Pushing", func(t *testing.T) {
+		worker := &fakeWorker{block: make(chan struct{})}
+		applied := make(chan string, 2)
+		pusher := NewWorkerPusher(worker.push, WorkerPusherConfig{
+			OnSuccess: func(req dto.ConfigRequest) { applied <- req.Version },
+		})
+
+		pusher.Submit(dto.ConfigRequest{Version: "v1"})
+		pusher.Submit(dto.ConfigRequest{Version: "v2"})
+		close(worker.block)
+
+		select {
+		case version := <-applied:
+			assert.Equal(t, "v2", version)
+		case <-time.After(time.Second):
+			t.Fatal("newer version was not pushed")
+		}
+	})
+}
+
+func TestWorkerPusher_Cancel(t *testing.T) {
+	worker := &fakeWorker{failures: 100}
+	pusher := NewWorkerPusher(worker.push, WorkerPusherConfig{InitialBackoff: time.Hour})
+
+	pusher.Submit(dto.ConfigRequest{Version: "v1"})
+	assert.Eventually(t, func() bool {
+		return pusher.Status().Attempts == 1
+	}, time.Second, time.Millisecond)
+
+	pusher.Cancel()
+
+	assert.Eventually(t, func() bool {
+		p := pusher.(*workerPusher)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return !p.running
+	}, time.Second, time.Millisecond)
+	assert.Empty(t, pusher.Status().PendingVersion)
+	assert.Len(t, worker.calls(), 1)
+}
+
+func TestWorkerPusher_CancelAbortsInFlightPush(t *testing.T) {
+	started := make(chan struct{})
+	aborted := make(chan error, 1)
+	pusher := NewWorkerPusher(func(ctx context.Context, req dto.ConfigRequest) error {
+		close(started)
+		<-ctx.Done()
+		aborted <- ctx.Err()
+		return ctx.Err()
+	}, WorkerPusherConfig{InitialBackoff: time.Hour})
+
+	pusher.Submit(dto.ConfigRequest{Version: "v1"})
+	<-started
+	pusher.Cancel()
+
+	select {
+	case err := <-aborted:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("in-flight push was not aborted")
+	}
+	assert.Empty(t, pusher.Status().PendingVersion)
+	assert.Zero(t, pusher.Status().Attempts)
+}
+
+func TestWorkerPusher_Backoff(t *testing.T) {
+	pusher := NewWorkerPusher(nil, WorkerPusherConfig{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+	}).(*workerPusher)
+
+	for attempts, expected := range map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		3:  4 * time.Second,
+		4:  8 * time.Second,
+		5:  10 * time.Second,
+		50: 10 * time.Second,
+	} {
+		for i := 0; i < 20; i++ {
+			delay := pusher.backoff(attempts)
+			assert.GreaterOrEqual(t, delay, expected/2)
+			assert.LessOrEqual(t, delay, expected)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"bytes"
+	"config-manager/internal/dto"
+	"config-manager/pkg/shared/tracing"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WorkerRegistrar announces a worker to its local agent.
+type WorkerRegistrar interface {
+	Register() error
+	Deregister() error
+}
+
+type workerRegistrar struct {
+	agentURL   string
+	authToken  string
+	req        dto.WorkerRegistrationRequest
+	httpClient *http.Client
+}
+
+func NewWorkerRegistrar(agentURL, authToken string, req dto.WorkerRegistrationRequest) WorkerRegistrar {
+	return &workerRegistrar{
+		agentURL:   agentURL,
+		authToken:  authToken,
+		req:        req,
+		httpClient: &http.Client{Timeout: 5 * time.Second, Transport: tracing.NewTransport(nil)},
+	}
+}
+
+func (r *workerRegistrar) Register() error {
+	reqBody, _ := json.Marshal(r.req)
+
+	httpReq, err := http.NewRequest(http.MethodPost, r.agentURL+"/v1/workers", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", r.authToken)
+
+	return r.do(httpReq, "register with agent")
+}
+
+func (r *workerRegistrar) Deregister() error {
+	httpReq, err := http.NewRequest(http.MethodDelete, r.agentURL+"/v1/workers?url="+url.QueryEscape(r.req.URL), nil)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Authorization", r.authToken)
+
+	return r.do(httpReq, "deregister from agent")
+}
+
+func (r *workerRegistrar) do(httpReq *http.Request, action string) error {
+	resp, err := r.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to %s, status: %d", action, resp.StatusCode)
+	}
+	return nil
+}
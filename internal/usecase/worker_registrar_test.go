@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerRegistrar(t *testing.T) {
+	req := dto.WorkerRegistrationRequest{URL: "http://worker-a:8082", Capabilities: []string{"http"}}
+
+	t.Run("Register", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/v1/workers", r.URL.Path)
+			assert.Equal(t, "secret-token", r.Header.Get("Authorization"))
+			var body dto.WorkerRegistrationRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, req, body)
+		}))
+		defer ts.Close()
+
+		assert.NoError(t, NewWorkerRegistrar(ts.URL, "secret-token", req).Register())
+	})
+
+	t.Run("Deregister", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodDelete, r.Method)
+			assert.Equal(t, "http://worker-a:8082", r.URL.Query().Get("url"))
+		}))
+		defer ts.Close()
+
+		assert.NoError(t, NewWorkerRegistrar(ts.URL, "secret-token", req).Deregister())
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer ts.Close()
+
+		registrar := NewWorkerRegistrar(ts.URL, "wrong", req)
+		assert.Error(t, registrar.Register())
+		assert.Error(t, registrar.Deregister())
+	})
+
+	t.Run("InvalidURL", func(t *testing.T) {
+		registrar := NewWorkerRegistrar("http://\x00invalid", "", req)
+		assert.Error(t, registrar.Register())
+		assert.Error(t, registrar.Deregister())
+	})
+}
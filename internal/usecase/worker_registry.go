@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidWorkerURL = errors.New("worker url must be an absolute http or https url")
+
+// WorkerRegistry keeps the workers that registered themselves with the agent.
+// Registrations expire unless renewed within the TTL, so crashed workers drop out.
+type WorkerRegistry interface {
+	WorkerDiscovery
+	// Register adds or renews a worker and reports whether it was not registered yet.
+	Register(req dto.WorkerRegistrationRequest) (bool, error)
+	// Deregister removes a worker and reports whether it was registered.
+	Deregister(workerURL string) bool
+	List() []dto.RegisteredWorker
+}
+
+type workerRegistry struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	workers map[string]*dto.RegisteredWorker
+}
+
+// NewWorkerRegistry returns an in-memory registry; a ttl <= 0 never expires registrations.
+func NewWorkerRegistry(ttl time.Duration) WorkerRegistry {
+	return &workerRegistry{
+		ttl:     ttl,
+		now:     time.Now,
+		workers: make(map[string]*dto.RegisteredWorker),
+	}
+}
+
+func (r *workerRegistry) Register(req dto.WorkerRegistrationRequest) (bool, error) {
+	workerURL, err := normalizeWorkerURL(req.URL)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+
+	now := r.now()
+	worker, ok := r.workers[workerURL]
+	if !ok {
+		worker = &dto.RegisteredWorker{URL: workerURL, RegisteredAt: now}
+		r.workers[workerURL] = worker
+	}
+	worker.Namespaces = req.Namespaces
+	worker.Capabilities = req.Capabilities
+	worker.LastSeenAt = now
+	return !ok, nil
+}
+
+func (r *workerRegistry) Deregister(workerURL string) bool {
+	workerURL = strings.TrimRight(strings.TrimSpace(workerURL), "/")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.workers[workerURL]
+	delete(r.workers, workerURL)
+	return ok
+}
+
+func (r *workerRegistry) List() []dto.RegisteredWorker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+
+	res := make([]dto.RegisteredWorker, 0, len(r.workers))
+	for _, w := range r.workers {
+		res = append(res, *w)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].URL < res[j].URL })
+	return res
+}
+
+func (r *workerRegistry) Workers(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune()
+
+	urls := make([]string, 0, len(r.workers))
+	for u := range r.workers {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
+
+// prune drops expired registrations. Callers must hold mu.
+func (r *workerRegistry) prune() {
+	if r.ttl <= 0 {
+		return
+	}
+	deadline := r.now().Add(-r.ttl)
+	for u, w := range r.workers {
+		if w.LastSeenAt.Before(deadline) {
+			delete(r.workers, u)
+		}
+	}
+}
+
+func normalizeWorkerURL(raw string) (string, error) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrInvalidWorkerURL
+	}
+	return raw, nil
+}
+
+type mergedDiscovery struct {
+	sources []WorkerDiscovery
+}
+
+// NewMergedDiscovery returns the union of the workers found by every source.
+// It fails when any source fails, so callers keep their current set.
+func NewMergedDiscovery(sources ...WorkerDiscovery) WorkerDiscovery {
+	return &mergedDiscovery{sources: sources}
+}
+
+func (d *mergedDiscovery) Workers(ctx context.Context) ([]string, error) {
+	var urls []string
+	for _, source := range d.sources {
+		found, err := source.Workers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, found...)
+	}
+	return normalizeWorkerURLs(urls), nil
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"config-manager/internal/dto"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerRegistry(t *testing.T) {
+	t.Run("Register And Renew", func(t *testing.T) {
+		registry := NewWorkerRegistry(time.Minute)
+
+		joined, err := registry.Register(dto.WorkerRegistrationRequest{URL: "http://worker-a:8082/", Namespaces: []string{"edge"}})
+		assert.NoError(t, err)
+		assert.True(t, joined)
+
+		joined, err = registry.Register(dto.WorkerRegistrationRequest{URL: "http://worker-a:8082", Capabilities: []string{"http"}})
+		assert.NoError(t, err)
+		assert.False(t, joined)
+
+		workers := registry.List()
+		assert.Len(t, workers, 1)
+		assert.Equal(t, "http://worker-a:8082", workers[0].URL)
+		assert.Nil(t, workers[0].Namespaces)
+		assert.Equal(t, []string{"http"}, workers[0].Capabilities)
+
+		urls, err := registry.Workers(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"http://worker-a:8082"}, urls)
+	})
+
+	t.Run("Invalid URL", func(t *testing.T) {
+		registry := NewWorkerRegistry(time.Minute)
+		for _, u := range []string{"", "worker-a:8082", "ftp://worker-a", "http://"} {
+			_, err := registry.Register(dto.WorkerRegistrationRequest{URL: u})
+			assert.ErrorIs(t, err, ErrInvalidWorkerURL, u)
+		}
+	})
+
+	t.Run("Deregister", func(t *testing.T) {
+		registry := NewWorkerRegistry(time.Minute)
+		_, _ = registry.Register(dto.WorkerRegistrationRequest{URL: "http://worker-a:8082"})
+
+		assert.True(t, registry.Deregister("http://worker-a:8082/"))
+		assert.False(t, registry.Deregister("http://worker-a:8082"))
+		assert.Empty(t, registry.List())
+	})
+
+	t.Run("Expired Registrations Are Dropped", func(t *testing.T) {
+		registry := NewWorkerRegistry(time.Minute).(*workerRegistry)
+		now := time.Now()
+		registry.now = func() time.Time { return now }
+		_, _ = registry.Register(dto.WorkerRegistrationRequest{URL: "http://worker-a:8082"})
+
+		now = now.Add(2 * time.Minute)
+		urls, err := registry.Workers(context.Background())
+		assert.NoError(t, err)
+		assert.Empty(t, urls)
+	})
+}
+
+func TestMergedDiscovery(t *testing.T) {
+	registry := NewWorkerRegistry(0)
+	_, _ = registry.Register(dto.WorkerRegistrationRequest{URL: "http://worker-b:8082"})
+	_, _ = registry.Register(dto.WorkerRegistrationRequest{URL: "http://worker-a:8082"})
+
+	d := NewMergedDiscovery(NewStaticDiscovery([]string{"http://worker-a:8082"}), registry)
+
+	urls, err := d.Workers(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://worker-a:8082", "http://worker-b:8082"}, urls)
+}
@@ -21,6 +21,9 @@ func main() {
 	rootCmd.AddCommand(server.ControllerCmd)
 	rootCmd.AddCommand(server.AgentCmd)
 	rootCmd.AddCommand(server.WorkerCmd)
+	rootCmd.AddCommand(server.MigrateCmd)
+	rootCmd.AddCommand(server.BackupCmd)
+	rootCmd.AddCommand(server.RestoreCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)
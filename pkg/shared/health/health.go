@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	// CheckTimeout bounds every check so a hung dependency cannot hang the probe.
+	CheckTimeout = 2 * time.Second
+)
+
+// Check reports whether one dependency is healthy.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type Response struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Register serves GET /healthz with the liveness checks and GET /readyz with the
+// readiness checks. Both answer 503 when any of their checks fails.
+func Register(e *echo.Echo, liveness, readiness []Check) {
+	e.GET("/healthz", Handler(liveness))
+	e.GET("/readyz", Handler(readiness))
+}
+
+// Handler runs checks concurrently and returns a per-check breakdown.
+func Handler(checks []Check) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res := Run(c.Request().Context(), checks)
+		code := http.StatusOK
+		if res.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		return c.JSON(code, res)
+	}
+}
+
+// Run executes checks concurrently, each bounded by CheckTimeout, and keeps
+// their order in the response.
+func Run(ctx context.Context, checks []Check) Response {
+	res := Response{Status: StatusOK, Checks: make([]CheckResult, len(checks))}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Run(checkCtx)
+			result := CheckResult{Name: check.Name, Status: StatusOK, DurationMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+			res.Checks[i] = result
+		}()
+	}
+	wg.Wait()
+
+	for _, check := range res.Checks {
+		if check.Status != StatusOK {
+			res.Status = StatusFail
+		}
+	}
+	return res
+}
+
+// HTTPCheck passes when GET url answers with a status below 500.
+func HTTPCheck(client *http.Client, url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
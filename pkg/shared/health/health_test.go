@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(e *echo.Echo, path string) (*httptest.ResponseRecorder, Response) {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var res Response
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	return rec, res
+}
+
+func TestRegister(t *testing.T) {
+	ok := Check{Name: "ok", Run: func(ctx context.Context) error { return nil }}
+	failing := Check{Name: "db", Run: func(ctx context.Context) error { return errors.New("connection refused") }}
+
+	e := echo.New()
+	Register(e, nil, []Check{ok, failing})
+
+	t.Run("Liveness Without Checks", func(t *testing.T) {
+		rec, res := serve(e, "/healthz")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, StatusOK, res.Status)
+		assert.Empty(t, res.Checks)
+	})
+
+	t.Run("Readiness Reports Failed Check", func(t *testing.T) {
+		rec, res := serve(e, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, StatusFail, res.Status)
+		if assert.Len(t, res.Checks, 2) {
+			assert.Equal(t, "ok", res.Checks[0].Name)
+			assert.Equal(t, StatusOK, res.Checks[0].Status)
+			assert.Equal(t, "db", res.Checks[1].Name)
+			assert.Equal(t, StatusFail, res.Checks[1].Status)
+			assert.Equal(t, "connection refused", res.Checks[1].Error)
+		}
+	})
+}
+
+func TestRun_Timeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	// Checks see the request's context, so a hung dependency is cut short
+	res := Run(ctx, []Check{{Name: "hung", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}})
+	assert.Equal(t, StatusFail, res.Status)
+	assert.Equal(t, context.Canceled.Error(), res.Checks[0].Error)
+}
+
+func TestHTTPCheck(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	check := HTTPCheck(server.Client(), server.URL)
+	assert.NoError(t, check(t.Context()))
+
+	// Client errors still prove the dependency is up
+	status = http.StatusUnauthorized
+	assert.NoError(t, check(t.Context()))
+
+	status = http.StatusServiceUnavailable
+	assert.EqualError(t, check(t.Context()), "unexpected status code 503")
+
+	server.Close()
+	assert.Error(t, check(t.Context()))
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	reg := NewRegistry()
+	e := echo.New()
+	e.Use(Middleware(reg))
+	Register(e, reg)
+	e.GET("/v1/config/:version", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+	e.POST("/v1/config", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/v1/config/v1", nil),
+		httptest.NewRequest(http.MethodGet, "/v1/config/v2", nil),
+		httptest.NewRequest(http.MethodPost, "/v1/config", nil),
+	} {
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Path parameters are collapsed into the route pattern
+	expected := `
+# HELP config_manager_http_requests_total HTTP requests served, by method, route and status code.
+# TYPE config_manager_http_requests_total counter
+config_manager_http_requests_total{code="200",method="GET",route="/v1/config/:version"} 2
+config_manager_http_requests_total{code="401",method="POST",route="/v1/config"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "config_manager_http_requests_total"))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "config_manager_http_request_duration_seconds_bucket")
+	assert.Contains(t, rec.Body.String(), "go_goroutines")
+}
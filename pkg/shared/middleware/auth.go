@@ -17,3 +17,20 @@ func StaticTokenAuth(headerName, token string) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// TokenAuth checks a specific header against any of the tokens returned by
+// tokens, which is called per request so tokens issued later are accepted.
+// Empty tokens never match.
+func TokenAuth(headerName string, tokens func() []string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			got := c.Request().Header.Get(headerName)
+			for _, token := range tokens() {
+				if token != "" && got == token {
+					return next(c)
+				}
+			}
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		}
+	}
+}
@@ -64,3 +64,27 @@ func TestStaticTokenAuth(t *testing.T) {
 		assert.Contains(t, rec.Body.String(), "unauthorized")
 	})
 }
+
+func TestTokenAuth(t *testing.T) {
+	e := echo.New()
+	tokens := []string{"admin-secret", ""}
+	h := TokenAuth("Authorization", func() []string { return tokens })(func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	})
+
+	do := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+		assert.NoError(t, h(e.NewContext(req, rec)))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("admin-secret"))
+	assert.Equal(t, http.StatusUnauthorized, do("wrong_token"))
+	assert.Equal(t, http.StatusUnauthorized, do(""), "an empty token must not match an unset one")
+
+	// Tokens are read per request.
+	tokens = append(tokens, "issued-later")
+	assert.Equal(t, http.StatusOK, do("issued-later"))
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderForwardedToLeader marks a request that a follower already forwarded,
+// so two replicas that disagree about the leader cannot bounce it forever.
+const HeaderForwardedToLeader = "X-Forwarded-To-Leader"
+
+// Leadership reports whether this replica may serve writes and where the leader is.
+type Leadership interface {
+	IsLeader() bool
+	LeaderURL() string
+}
+
+// ForwardToLeader proxies requests to the elected leader when this replica is a follower.
+func ForwardToLeader(leadership Leadership) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if leadership.IsLeader() {
+				return next(c)
+			}
+
+			leaderURL := leadership.LeaderURL()
+			if leaderURL == "" || c.Request().Header.Get(HeaderForwardedToLeader) != "" {
+				c.Response().Header().Set("Retry-After", "1")
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "no leader available"})
+			}
+
+			target, err := url.Parse(leaderURL)
+			if err != nil {
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "invalid leader url"})
+			}
+
+			proxy := httputil.NewSingleHostReverseProxy(target)
+			proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+				c.JSON(http.StatusBadGateway, map[string]string{"error": "failed to forward to leader: " + err.Error()})
+			}
+
+			c.Request().Header.Set(HeaderForwardedToLeader, "true")
+			proxy.ServeHTTP(c.Response(), c.Request())
+			return nil
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLeadership struct {
+	leader    bool
+	leaderURL string
+}
+
+func (f *fakeLeadership) IsLeader() bool    { return f.leader }
+func (f *fakeLeadership) LeaderURL() string { return f.leaderURL }
+
+func TestForwardToLeader(t *testing.T) {
+	leaderServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.Header.Get(HeaderForwardedToLeader))
+		assert.Equal(t, "/v1/config", r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("from leader"))
+	}))
+	defer leaderServer.Close()
+
+	newServer := func(l Leadership) *echo.Echo {
+		e := echo.New()
+		e.POST("/v1/config", func(c echo.Context) error {
+			return c.String(http.StatusOK, "local")
+		}, ForwardToLeader(l))
+		return e
+	}
+
+	t.Run("Leader Serves Locally", func(t *testing.T) {
+		e := newServer(&fakeLeadership{leader: true})
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/config", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "local", rec.Body.String())
+	})
+
+	t.Run("Follower Forwards", func(t *testing.T) {
+		e := newServer(&fakeLeadership{leaderURL: leaderServer.URL})
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/config", nil))
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, "from leader", rec.Body.String())
+	})
+
+	t.Run("No Leader", func(t *testing.T) {
+		e := newServer(&fakeLeadership{})
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/config", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("Already Forwarded", func(t *testing.T) {
+		e := newServer(&fakeLeadership{leaderURL: leaderServer.URL})
+		req := httptest.NewRequest(http.MethodPost, "/v1/config", nil)
+		req.Header.Set(HeaderForwardedToLeader, "true")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("Leader Unreachable", func(t *testing.T) {
+		e := newServer(&fakeLeadership{leaderURL: "http://localhost:1"})
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/config", nil))
+
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+	})
+}
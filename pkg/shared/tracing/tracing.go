@@ -0,0 +1,166 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+const tracerName = "config-manager"
+
+// Span attributes shared by every role, so one config version can be followed
+// from the controller to the workers.
+var (
+	AttrConfigVersion = attribute.Key("config.version")
+	AttrAgentID       = attribute.Key("agent.id")
+	AttrWorkerURL     = attribute.Key("worker.url")
+)
+
+type Config struct {
+	Exporter     string  // none, stdout, file or otlp
+	File         string  // spans are appended here by the file exporter
+	OTLPEndpoint string  // e.g. http://otel-collector:4318, empty uses the OTEL_EXPORTER_OTLP_* variables
+	SampleRatio  float64 // share of new traces recorded, remote parents decide for their children
+}
+
+// Setup installs the global tracer provider and the W3C trace-context propagator.
+// Incoming trace contexts are propagated even when the exporter is "none". The
+// returned function flushes pending spans.
+func Setup(ctx context.Context, service string, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var closeFile func() error
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		exporter = exp
+	case ExporterFile:
+		if cfg.File == "" {
+			return nil, errors.New("TRACING_FILE is required by the file exporter")
+		}
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		exporter, closeFile = exp, f.Close
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		exporter = exp
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeFile != nil {
+			err = errors.Join(err, closeFile())
+		}
+		return err
+	}, nil
+}
+
+// Tracer returns the tracer used for the spans started by config-manager itself.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Middleware starts a server span for every request, continuing the trace of the
+// caller. Health and metrics probes are not traced.
+func Middleware(service string) echo.MiddlewareFunc {
+	return otelecho.Middleware(service, otelecho.WithSkipper(func(c echo.Context) bool {
+		switch c.Path() {
+		case "/healthz", "/readyz", "/metrics":
+			return true
+		}
+		return false
+	}))
+}
+
+// NewTransport wraps base, http.DefaultTransport when nil, so outgoing requests
+// get a client span and carry the trace context of their request's context.
+// The client span gets the attributes of WithClientAttributes.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(clientAttributesTransport{base})
+}
+
+// SetAttributes adds attrs to the span of ctx, if any.
+func SetAttributes(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
+
+type clientAttributesKey struct{}
+
+// WithClientAttributes returns a copy of ctx whose requests sent through
+// NewTransport get attrs on their client span.
+func WithClientAttributes(ctx context.Context, attrs ...attribute.KeyValue) context.Context {
+	previous, _ := ctx.Value(clientAttributesKey{}).([]attribute.KeyValue)
+	return context.WithValue(ctx, clientAttributesKey{}, append(slices.Clip(previous), attrs...))
+}
+
+// clientAttributesTransport runs inside the otelhttp transport, where the
+// request's context holds the client span.
+type clientAttributesTransport struct {
+	base http.RoundTripper
+}
+
+func (t clientAttributesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if attrs, ok := req.Context().Value(clientAttributesKey{}).([]attribute.KeyValue); ok {
+		SetAttributes(req.Context(), attrs...)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	t.Run("None", func(t *testing.T) {
+		shutdown, err := Setup(t.Context(), "test", Config{Exporter: ExporterNone})
+		assert.NoError(t, err)
+		assert.NoError(t, shutdown(t.Context()))
+	})
+
+	t.Run("Unknown Exporter", func(t *testing.T) {
+		_, err := Setup(t.Context(), "test", Config{Exporter: "jaeger"})
+		assert.EqualError(t, err, `unknown tracing exporter "jaeger"`)
+	})
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.jsonl")
+		shutdown, err := Setup(t.Context(), "test", Config{Exporter: ExporterFile, File: path, SampleRatio: 1})
+		assert.NoError(t, err)
+
+		_, span := Tracer().Start(context.Background(), "agent.poll")
+		span.SetAttributes(AttrConfigVersion.String("v1"))
+		span.End()
+		assert.NoError(t, shutdown(t.Context()))
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"Name":"agent.poll"`)
+		assert.Contains(t, string(data), `"config.version"`)
+	})
+}
+
+func TestPropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	_, err := Setup(t.Context(), "test", Config{})
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.Use(Middleware("worker"))
+	e.POST("/v1/config", func(c echo.Context) error {
+		SetAttributes(c.Request().Context(), AttrConfigVersion.String("v1"))
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/healthz", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	ctx, parent := Tracer().Start(t.Context(), "agent.push")
+	ctx = WithClientAttributes(ctx, AttrWorkerURL.String(server.URL))
+	client := &http.Client{Transport: NewTransport(nil)}
+	for _, path := range []string{"/v1/config", "/healthz"} {
+		method := http.MethodPost
+		if path == "/healthz" {
+			method = http.MethodGet
+		}
+		req, _ := http.NewRequestWithContext(ctx, method, server.URL+path, nil)
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+	parent.End()
+
+	// The worker's server span continues the agent's trace; probes are not traced
+	var serverSpans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+		switch span.SpanKind().String() {
+		case "server":
+			serverSpans = append(serverSpans, span)
+		case "client":
+			assert.Contains(t, span.Attributes(), AttrWorkerURL.String(server.URL))
+		}
+	}
+	if assert.Len(t, serverSpans, 1) {
+		assert.Contains(t, serverSpans[0].Name(), "/v1/config")
+		assert.Contains(t, serverSpans[0].Attributes(), AttrConfigVersion.String("v1"))
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	t.Run("Creates File", func(t *testing.T) {
+		err := WriteFileAtomic(path, []byte("first"), 0o600)
+		assert.NoError(t, err)
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "first", string(data))
+
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("Replaces File Without Leftovers", func(t *testing.T) {
+		err := WriteFileAtomic(path, []byte("second"), 0o600)
+		assert.NoError(t, err)
+
+		data, _ := os.ReadFile(path)
+		assert.Equal(t, "second", string(data))
+
+		entries, _ := os.ReadDir(dir)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("Missing Directory", func(t *testing.T) {
+		err := WriteFileAtomic(filepath.Join(dir, "missing", "state.json"), []byte("x"), 0o600)
+		assert.Error(t, err)
+	})
+}